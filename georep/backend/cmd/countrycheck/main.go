@@ -0,0 +1,51 @@
+// countrycheck validates a country → region mapping file against a
+// topology: unknown or duplicate ISO codes, unknown continents and
+// references to undefined regions are reported. It exits non-zero when
+// any error is found, so it can run in CI before a mapping is deployed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"geo-repl-demo/internal/config"
+	"geo-repl-demo/internal/region"
+)
+
+func main() {
+	topoPath := flag.String("topology", "topology.yaml", "topology file")
+	mapPath := flag.String("countries", "", "country map file (default: the one referenced by the topology)")
+	flag.Parse()
+
+	topo, err := config.LoadTopology(*topoPath)
+	if err != nil {
+		log.Fatalf("load topology: %v", err)
+	}
+	path := *mapPath
+	if path == "" {
+		path = topo.CountryMap
+	}
+
+	m, err := region.LoadCountryMap(path)
+	if err != nil {
+		log.Fatalf("load country map: %v", err)
+	}
+	issues, err := region.ValidateCountryMap(topo, m)
+	if err != nil {
+		log.Fatalf("validate: %v", err)
+	}
+
+	errors := 0
+	for _, is := range issues {
+		fmt.Printf("%-7s %s\n", is.Severity, is.Message)
+		if is.Severity == "error" {
+			errors++
+		}
+	}
+	fmt.Printf("country map %q: %d issue(s), %d error(s)\n", m.Version, len(issues), errors)
+	if errors > 0 {
+		os.Exit(1)
+	}
+}
@@ -1,20 +1,36 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
+	_ "time/tzdata" // bölge saat dilimleri (zamanlanmış yayın) için gömülü IANA verisi
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 
+	"geo-repl-demo/internal/apikey"
 	"geo-repl-demo/internal/article"
+	"geo-repl-demo/internal/attachment"
+	"geo-repl-demo/internal/audit"
+	"geo-repl-demo/internal/auditlog"
 	"geo-repl-demo/internal/auth"
+	"geo-repl-demo/internal/blob"
+	"geo-repl-demo/internal/comment"
 	"geo-repl-demo/internal/config"
 	"geo-repl-demo/internal/db"
 	"geo-repl-demo/internal/geoip"
 	"geo-repl-demo/internal/middleware"
+	"geo-repl-demo/internal/preference"
+	"geo-repl-demo/internal/ratelimit"
+	"geo-repl-demo/internal/region"
 	"geo-repl-demo/internal/replication"
+	"geo-repl-demo/internal/topology"
 )
 
 func main() {
@@ -23,27 +39,65 @@ func main() {
 		log.Fatalf("❌ load config: %v", err)
 	}
 
-	// 🌍 GeoIP veritabanı yükle
-	if err := geoip.Init("/app/GeoLite2-Country.mmdb"); err != nil {
+	// 🌍 GeoIP sağlayıcısı (dosya değişince otomatik yeniden yüklenir)
+	geoCache, geoSource, err := geoip.Open(cfg.GeoIPProvider, cfg.GeoIPDB, cfg.GeoIPCache)
+	if geoCache == nil {
+		log.Fatalf("❌ geoip: %v", err)
+	}
+	if err != nil {
 		log.Printf("⚠️ GeoIP DB yüklenemedi: %v", err)
 	} else {
 		log.Println("🌍 GeoIP veritabanı başarıyla yüklendi")
 	}
+	go geoSource.Watch(context.Background(), 10*time.Second)
 
 	// 🔗 Veritabanı bağlantıları
-	masterDB := mustConnectMaster(cfg.MasterDSN)
+	log.Printf("🗺️ Topoloji yüklendi: master=%s, %d replika (%s)",
+		cfg.Topology.Master.Name, len(cfg.Topology.Replicas), cfg.TopologyFile)
+
+	// 🧭 Bölge kayıt defteri (bölge ID, alias, etiket, node ataması)
+	countries, err := region.LoadCountryMap(cfg.Topology.CountryMap)
+	if err != nil {
+		log.Fatalf("❌ country map: %v", err)
+	}
+	regions, err := region.New(cfg.Topology, countries)
+	if err != nil {
+		log.Fatalf("❌ region registry: %v", err)
+	}
+	for _, is := range regions.CountryIssues() {
+		log.Printf("⚠️ Ülke eşlemesi (%s): %s", is.Severity, is.Message)
+	}
+
+	masterDB := mustConnectMaster(cfg.Topology.Master)
 	defer masterDB.Close()
 
-	replicas := mustConnectReplicas(cfg.ReplicaDSNs())
+	replicas := mustConnectReplicas(cfg.Topology.Replicas)
 	defer replicas.Close()
 
 	// 🧱 Şema kontrolü (addDefaultArticles artık yok)
 	mustEnsureSchema(masterDB)
 
 	// 🧩 Repository & Servis
-	repo := article.NewRepository(masterDB, replicas)
+	repo := article.NewRepository(masterDB, replicas, regions)
 	replicator := replication.NewReplicator(masterDB, replicas)
-	svc := article.NewService(repo, replicator)
+	// 📎 Ek dosyaları: her node'un kendi blob store'u, yüklemeler master'ınkine
+	blobs, err := blob.Open(cfg.BlobStore, cfg.BlobRoot)
+	if err != nil {
+		log.Fatalf("❌ blob store: %v", err)
+	}
+	replicator.UseBlobs(blobs)
+	// 🧾 Denetim kayıtları master'a eklenir ve diğer veriler gibi bölgelere replike edilir
+	auditSink := auditlog.NewDBSink(masterDB, replicator)
+	svc := article.NewService(repo, replicator, auditSink, cfg.DeleteRetention)
+
+	attachmentSvc := attachment.NewService(attachment.NewRepository(masterDB, replicas, regions), svc, blobs, replicator, auditSink, cfg.MaxAttachmentBytes)
+	svc.OnPurge(attachmentSvc.PurgeBlobs)
+	log.Printf("📎 Blob store: %s (%s), en fazla %d bayt", cfg.BlobStore, cfg.BlobRoot, cfg.MaxAttachmentBytes)
+
+	// 📝 Markdown desteğinden önceki makalelerin HTML'i (ilk senkronizasyon replikalara taşır)
+	if err := svc.RenderMissing(context.Background()); err != nil {
+		log.Printf("⚠️ Makale HTML'i üretilemedi: %v", err)
+	}
 
 	log.Println("🔁 İlk replikasyon başlatılıyor...")
 	replicator.FullSync()
@@ -55,31 +109,130 @@ func main() {
 		}
 	}()
 
+	// 📰 Zamanlanmış yayınlar master'da yayına alınır
+	go svc.RunScheduler(context.Background(), 10*time.Second)
+
+	// 🧹 Saklama süresi dolan silinmiş makaleler master + tüm replikalardan kalıcı silinir
+	log.Printf("🗑️ Silinen makaleler %s boyunca geri alınabilir", cfg.DeleteRetention)
+	go svc.RunPurger(context.Background(), cfg.PurgeInterval)
+
+	// 💬 Yorumlar bölge node'una yazılır, birkaç saniyede bir master'da birleştirilip dağıtılır
+	commentSvc := comment.NewService(comment.NewRepository(masterDB, replicas, regions), svc, replicator, auditSink)
+	go commentSvc.RunMerger(context.Background(), 3*time.Second)
+
+	// 🩺 Replika sağlık kontrolü (en yakın sağlıklı node seçimi için)
+	go func() {
+		for range time.Tick(5 * time.Second) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			replicas.CheckHealth(ctx)
+			cancel()
+		}
+	}()
+
+	// ♻️ Topoloji hot-reload: dosya değişikliği veya SIGHUP
+	reloader := topology.NewReloader(cfg.TopologyFile, cfg.Topology, regions, replicas, replicator.Bootstrap)
+	// 🚦 İstek limitleri topoloji dosyasından gelir ve onunla birlikte yeniden yüklenir
+	limiter := ratelimit.New(cfg.Topology.RateLimits)
+	reloader.OnReload(func(t config.Topology) { limiter.Update(t.RateLimits) })
+	go reloader.Watch(context.Background(), 5*time.Second)
+	go func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		for range hup {
+			log.Println("♻️ SIGHUP alındı, topoloji yeniden yükleniyor")
+			if err := reloader.Reload(context.Background()); err != nil {
+				log.Printf("⚠️ Topoloji yeniden yüklenemedi: %v", err)
+			}
+		}
+	}()
+
 	// 🌐 HTTP Sunucu
 	r := gin.Default()
-	r.SetTrustedProxies(nil)
-	r.ForwardedByClientIP = true
-	r.Use(cors.Default())
-	r.Use(middleware.RegionMiddleware())
+	// Yalnızca yapılandırılmış proxy'lerin yönlendirme başlıklarına güven
+	proxies := make([]string, 0, len(cfg.TrustedProxies))
+	for _, p := range cfg.TrustedProxies {
+		proxies = append(proxies, p.String())
+	}
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		log.Fatalf("❌ TRUSTED_PROXIES geçersiz: %v", err)
+	}
+	r.ForwardedByClientIP = len(proxies) > 0
+	log.Printf("🛡️ Güvenilir proxy'ler: %v", proxies)
+	// Bearer token'ları ve API anahtarları için Authorization / X-API-Key başlıklarına da izin ver
+	corsCfg := cors.DefaultConfig()
+	corsCfg.AllowAllOrigins = true
+	corsCfg.AddAllowHeaders("Authorization", "X-API-Key")
+	r.Use(cors.New(corsCfg))
+
+	// 🔐 JWT'ler (her bölgede doğrulanabilir) ve imzalı bölge tercihi çerezleri
+	secret := []byte(cfg.SessionSecret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("❌ session secret: %v", err)
+		}
+		log.Println("⚠️ SESSION_SECRET tanımlı değil, rastgele anahtar üretildi (yeniden başlatınca token'lar geçersiz olur)")
+	}
+	signer := auth.NewSigner(auth.DeriveKey(secret, "region-preference"))
+	authn := auth.NewAuthenticator(auth.DeriveKey(secret, "jwt"), cfg.AdminTokens)
+	prefs := preference.NewCodec(signer, 30*24*time.Hour)
+	if cfg.DevMode {
+		log.Println("🧪 DEV_MODE açık: ?region= override'ı herkese açık")
+	}
+
+	// 🔑 Makine istemcileri için API anahtarları (replikada doğrulanır, anahtar başına kota)
+	keySvc := apikey.NewService(apikey.NewRepository(masterDB, replicas, regions), replicator, regions)
+	authn.UseKeys(keySvc)
 
-	authHandler := auth.NewHandler()
+	r.Use(authn.Middleware())
+	r.Use(keySvc.QuotaMiddleware())
+	r.Use(middleware.RegionMiddleware(regions, replicas, geoCache, middleware.RegionOptions{
+		Proxies:     middleware.NewProxyChain(cfg.TrustedProxies),
+		DevMode:     cfg.DevMode,
+		Preferences: prefs,
+		Audit:       auditSink,
+	}))
+	r.Use(audit.Middleware())
+	r.Use(limiter.Middleware())
+
+	authSvc := auth.NewService(auth.NewRepository(masterDB, replicas, regions), replicator, authn, regions, auditSink)
+	authHandler := auth.NewHandler(authSvc, mustOIDC(r, cfg.OIDC, secret))
 	articleHandler := article.NewHandler(svc)
 	auth.RegisterRoutes(r, authHandler)
 	article.RegisterRoutes(r, articleHandler)
+	comment.RegisterRoutes(r, comment.NewHandler(commentSvc))
+	attachment.RegisterRoutes(r, attachment.NewHandler(attachmentSvc))
+	topology.RegisterRoutes(r, topology.NewHandler(reloader, auditSink))
+	region.RegisterRoutes(r, region.NewHandler(regions))
+	preference.RegisterRoutes(r, preference.NewHandler(prefs, regions, auditSink))
+	geoip.RegisterRoutes(r, geoip.NewHandler(geoCache, geoSource))
+	apikey.RegisterRoutes(r, apikey.NewHandler(keySvc, auditSink))
+	auditlog.RegisterRoutes(r, auditlog.NewHandler(auditlog.NewStore(masterDB, replicas, regions)))
 
 	// 🌍 IP tabanlı bölge tespiti
 	r.GET("/api/region", func(c *gin.Context) {
-		clientIP := c.ClientIP()
+		clientIP := c.GetString(middleware.ClientAddrKey)
 		region := "unknown"
 		if rVal, ok := c.Get("region"); ok {
 			if val, ok := rVal.(string); ok {
 				region = val
 			}
 		}
-		c.JSON(200, gin.H{
+		resp := gin.H{
 			"region": region,
 			"ip":     clientIP,
-		})
+		}
+		if country := c.GetString(middleware.CountryKey); country != "" {
+			resp["country"] = country
+		}
+		if source := c.GetString(middleware.RegionSourceKey); source != "" {
+			resp["source"] = source
+		}
+		if node, ok := c.Get("node"); ok {
+			resp["node"] = node
+			resp["distance_km"] = c.GetFloat64("distance_km")
+		}
+		c.JSON(200, resp)
 	})
 
 	// ⚡ Gecikme ölçüm endpoint’i
@@ -110,9 +263,9 @@ func main() {
 // 🔧 Yardımcı Fonksiyonlar
 // ==========================================================
 
-func mustConnectMaster(dsn string) *db.Master {
+func mustConnectMaster(node config.Node) *db.Master {
 	for i := 0; i < 10; i++ {
-		m, err := db.NewMaster(dsn)
+		m, err := db.NewMaster(node)
 		if err == nil {
 			return m
 		}
@@ -123,9 +276,9 @@ func mustConnectMaster(dsn string) *db.Master {
 	return nil
 }
 
-func mustConnectReplicas(dsns []string) *db.ReplicaSet {
+func mustConnectReplicas(nodes []config.Node) *db.ReplicaSet {
 	for i := 0; i < 10; i++ {
-		r, err := db.NewReplicas(dsns)
+		r, err := db.NewReplicas(nodes)
 		if err == nil {
 			return r
 		}
@@ -136,6 +289,25 @@ func mustConnectReplicas(dsns []string) *db.ReplicaSet {
 	return nil
 }
 
+// mustOIDC kurulu değilse nil döner; mock modunda gömülü IdP'yi de bağlar
+func mustOIDC(r *gin.Engine, cfg config.OIDC, secret []byte) *auth.OIDC {
+	if !cfg.Enabled() {
+		return nil
+	}
+	var client *http.Client
+	if cfg.Mock {
+		idp, err := auth.NewMockIdP(cfg, auth.DefaultMockUsers)
+		if err != nil {
+			log.Fatalf("❌ mock IdP: %v", err)
+		}
+		r.Any(idp.Path()+"/*path", gin.WrapH(http.StripPrefix(idp.Path(), idp)))
+		client = idp.Client()
+		log.Printf("🧪 Mock OIDC IdP açık: %s", cfg.Issuer)
+	}
+	log.Printf("🔐 OIDC girişi: issuer=%s client=%s", cfg.Issuer, cfg.ClientID)
+	return auth.NewOIDC(cfg, auth.DeriveKey(secret, "oidc-flow"), client)
+}
+
 func mustEnsureSchema(master *db.Master) {
 	for i := 0; i < 10; i++ {
 		if err := db.EnsureSchema(master); err != nil {
@@ -0,0 +1,143 @@
+package apikey
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"geo-repl-demo/internal/audit"
+	"geo-repl-demo/internal/auth"
+	"geo-repl-demo/internal/model"
+)
+
+type Handler struct {
+	svc   *Service
+	audit audit.Sink
+}
+
+func NewHandler(svc *Service, sink audit.Sink) *Handler {
+	return &Handler{svc: svc, audit: sink}
+}
+
+func RegisterRoutes(r *gin.Engine, h *Handler) {
+	api := r.Group("/api")
+	{
+		api.GET("/keys", auth.Authenticated(), h.list)
+		api.POST("/keys", auth.Authenticated(), h.create)
+		api.POST("/keys/:id/rotate", auth.Authenticated(), h.rotate)
+		api.DELETE("/keys/:id", auth.Authenticated(), h.revoke)
+	}
+}
+
+// KeyResponse carries the plaintext key, which is shown only once.
+type KeyResponse struct {
+	model.APIKey
+	Key string `json:"key"`
+}
+
+type rotateRequest struct {
+	// GraceSeconds keeps the old secret valid; defaults to one hour.
+	GraceSeconds *int `json:"grace_seconds"`
+}
+
+func (h *Handler) list(c *gin.Context) {
+	p, _ := auth.FromContext(c)
+	keys, err := h.svc.List(c.Request.Context(), p)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+func (h *Handler) create(c *gin.Context) {
+	p, _ := auth.FromContext(c)
+	var req CreateInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	k, key, err := h.svc.Create(c.Request.Context(), p, req, c.GetString("region"))
+	h.record(c, p, "apikey.create", k.KeyID, err)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, KeyResponse{APIKey: k, Key: key})
+}
+
+func (h *Handler) rotate(c *gin.Context) {
+	p, _ := auth.FromContext(c)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req rotateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	var grace *time.Duration
+	if req.GraceSeconds != nil {
+		g := time.Duration(*req.GraceSeconds) * time.Second
+		grace = &g
+	}
+
+	k, key, err := h.svc.Rotate(c.Request.Context(), p, id, grace)
+	h.record(c, p, "apikey.rotate", c.Param("id"), err)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, KeyResponse{APIKey: k, Key: key})
+}
+
+func (h *Handler) revoke(c *gin.Context) {
+	p, _ := auth.FromContext(c)
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	k, err := h.svc.Revoke(c.Request.Context(), p, id)
+	h.record(c, p, "apikey.revoke", c.Param("id"), err)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, k)
+}
+
+// record audits a key management action.
+func (h *Handler) record(c *gin.Context, p auth.Principal, action, target string, err error) {
+	e := p.AuditEvent(c.Request.Context(), action, "api_key", target)
+	if err != nil {
+		e.Outcome = audit.Denied
+		if e.Detail == nil {
+			e.Detail = map[string]string{}
+		}
+		e.Detail["error"] = err.Error()
+	}
+	h.audit.Record(c.Request.Context(), e)
+}
+
+func writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrRevoked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotOwner), errors.Is(err, ErrKeyCaller), errors.Is(err, ErrNoUser), errors.Is(err, ErrScope):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": auth.CodeForbidden})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,104 @@
+// Package apikey manages API keys for machine clients.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+)
+
+// Prefix starts every API key, so keys can be told apart from JWTs.
+const Prefix = "grk_"
+
+// A key is "grk_<region>_<key id>_<secret>". The region lets any backend
+// validate the key against that region's replica; the key ID is the public
+// lookup handle and the secret is only ever stored as a hash.
+const (
+	keyIDBytes  = 8
+	secretBytes = 32
+)
+
+// token is a parsed API key.
+type token struct {
+	region string
+	keyID  string
+	secret string
+}
+
+func (t token) String() string {
+	return Prefix + t.region + "_" + t.keyID + "_" + t.secret
+}
+
+// newToken returns a fresh key for region. keyID is reused on rotation.
+func newToken(region, keyID string) (token, error) {
+	if keyID == "" {
+		id, err := randomHex(keyIDBytes)
+		if err != nil {
+			return token{}, err
+		}
+		keyID = id
+	}
+	secret, err := randomHex(secretBytes)
+	if err != nil {
+		return token{}, err
+	}
+	return token{region: region, keyID: keyID, secret: secret}, nil
+}
+
+// parseToken splits a key. The key ID and secret are hex, so the region is
+// everything before them even if it contains underscores.
+func parseToken(s string) (token, bool) {
+	rest, ok := strings.CutPrefix(s, Prefix)
+	if !ok {
+		return token{}, false
+	}
+	i := strings.LastIndexByte(rest, '_')
+	if i < 0 {
+		return token{}, false
+	}
+	secret := rest[i+1:]
+	rest = rest[:i]
+	j := strings.LastIndexByte(rest, '_')
+	if j <= 0 {
+		return token{}, false
+	}
+	t := token{region: rest[:j], keyID: rest[j+1:], secret: secret}
+	if !isHex(t.keyID, keyIDBytes) || !isHex(t.secret, secretBytes) {
+		return token{}, false
+	}
+	return t, true
+}
+
+// hashSecret hashes a key secret for storage. Secrets are 256 random bits,
+// so a fast hash is enough; a slow password hash would only add latency to
+// every request.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// secretMatches compares secret against a stored hash in constant time.
+func secretMatches(secret, hash string) bool {
+	if hash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(hash)) == 1
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func isHex(s string, n int) bool {
+	if len(s) != 2*n {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
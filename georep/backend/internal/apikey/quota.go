@@ -0,0 +1,94 @@
+package apikey
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"geo-repl-demo/internal/auth"
+)
+
+// quotaWindow is the period of per-key request quotas.
+const quotaWindow = time.Hour
+
+// quota counts requests per key in fixed hourly windows. Counters are per
+// backend process and are not replicated.
+type quota struct {
+	mu   sync.Mutex
+	keys map[string]*quotaState
+}
+
+type quotaState struct {
+	limit int
+	start time.Time
+	used  int
+}
+
+func newQuota() *quota {
+	return &quota{keys: map[string]*quotaState{}}
+}
+
+// setLimit records the key's current limit, as last read from the database.
+func (q *quota) setLimit(keyID string, limit int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	st, ok := q.keys[keyID]
+	if !ok {
+		st = &quotaState{}
+		q.keys[keyID] = st
+	}
+	st.limit = limit
+}
+
+// take counts one request. It returns the limit (0 = unlimited), the
+// remaining requests, the end of the window and whether the request fits.
+func (q *quota) take(keyID string, now time.Time) (limit, remaining int, reset time.Time, ok bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	st, found := q.keys[keyID]
+	if !found || st.limit <= 0 {
+		return 0, 0, time.Time{}, true
+	}
+	if now.Sub(st.start) >= quotaWindow {
+		st.start = now.Truncate(quotaWindow)
+		st.used = 0
+	}
+	reset = st.start.Add(quotaWindow)
+	if st.used >= st.limit {
+		return st.limit, 0, reset, false
+	}
+	st.used++
+	return st.limit, st.limit - st.used, reset, true
+}
+
+// QuotaMiddleware enforces per-key request quotas. It must run after the
+// authentication middleware; requests without an API key pass through.
+func (s *Service) QuotaMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := auth.FromContext(c)
+		if !ok || !p.IsAPIKey() {
+			c.Next()
+			return
+		}
+		now := time.Now()
+		limit, remaining, reset, ok := s.quota.take(p.KeyID, now)
+		if limit > 0 {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			c.Header("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		}
+		if !ok {
+			retry := int(reset.Sub(now).Seconds()) + 1
+			c.Header("Retry-After", strconv.Itoa(retry))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": "api key quota exceeded",
+				"code":  "quota_exceeded",
+			})
+			return
+		}
+		c.Next()
+	}
+}
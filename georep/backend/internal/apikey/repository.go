@@ -0,0 +1,154 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"geo-repl-demo/internal/db"
+	"geo-repl-demo/internal/model"
+	"geo-repl-demo/internal/region"
+)
+
+type Repository struct {
+	master   *db.Master
+	replicas *db.ReplicaSet
+	regions  *region.Registry
+}
+
+func NewRepository(master *db.Master, replicas *db.ReplicaSet, regions *region.Registry) *Repository {
+	return &Repository{master: master, replicas: replicas, regions: regions}
+}
+
+// owner is the user an API key acts for.
+type owner struct {
+	Username string
+	Role     string
+}
+
+// Insert creates a key on the master.
+func (r *Repository) Insert(ctx context.Context, k model.APIKey) (model.APIKey, error) {
+	var out model.APIKey
+	err := db.ScanAPIKey(r.master.Pool.QueryRow(ctx, `
+		INSERT INTO api_keys (key_id, name, owner_id, scopes, region, quota_per_hour, secret_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+db.APIKeyColumns,
+		k.KeyID, k.Name, k.OwnerID, k.Scopes, k.Region, k.QuotaPerHour, k.SecretHash), &out)
+	if err != nil {
+		return model.APIKey{}, fmt.Errorf("insert api key: %w", err)
+	}
+	return out, nil
+}
+
+// GetFromMaster reads a key by ID from the master.
+func (r *Repository) GetFromMaster(ctx context.Context, id int64) (model.APIKey, error) {
+	var k model.APIKey
+	err := db.ScanAPIKey(r.master.Pool.QueryRow(ctx,
+		`SELECT `+db.APIKeyColumns+` FROM api_keys WHERE id=$1`, id), &k)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.APIKey{}, ErrNotFound
+	}
+	return k, err
+}
+
+// ListFromMaster lists the keys of ownerID, or every key if ownerID is 0.
+func (r *Repository) ListFromMaster(ctx context.Context, ownerID int64) ([]model.APIKey, error) {
+	rows, err := r.master.Pool.Query(ctx, `
+		SELECT `+db.APIKeyColumns+` FROM api_keys
+		WHERE $1::bigint = 0 OR owner_id = $1
+		ORDER BY created_at DESC`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []model.APIKey{}
+	for rows.Next() {
+		var k model.APIKey
+		if err := db.ScanAPIKey(rows, &k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// Rotate replaces the secret hash; the old one stays valid until graceUntil.
+func (r *Repository) Rotate(ctx context.Context, id int64, hash string, graceUntil time.Time) (model.APIKey, error) {
+	var k model.APIKey
+	err := db.ScanAPIKey(r.master.Pool.QueryRow(ctx, `
+		UPDATE api_keys SET
+			previous_hash = secret_hash,
+			previous_expires_at = $3,
+			secret_hash = $2,
+			updated_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING `+db.APIKeyColumns, id, hash, graceUntil), &k)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.APIKey{}, ErrRevoked
+	}
+	return k, err
+}
+
+// Revoke marks a key revoked. Revoking twice keeps the first timestamp.
+func (r *Repository) Revoke(ctx context.Context, id int64) (model.APIKey, error) {
+	var k model.APIKey
+	err := db.ScanAPIKey(r.master.Pool.QueryRow(ctx, `
+		UPDATE api_keys SET
+			revoked_at = COALESCE(revoked_at, NOW()),
+			updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+db.APIKeyColumns, id), &k)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.APIKey{}, ErrNotFound
+	}
+	return k, err
+}
+
+// FindForAuth reads a key and its owner from the region's replica, falling
+// back to the master for keys that have not been replicated yet.
+func (r *Repository) FindForAuth(ctx context.Context, region, keyID string) (model.APIKey, owner, error) {
+	pool := r.poolForRegion(region)
+	k, o, err := r.findForAuth(ctx, pool, keyID)
+	if errors.Is(err, ErrNotFound) && pool != r.master.Pool {
+		return r.FindForAuthOnMaster(ctx, keyID)
+	}
+	return k, o, err
+}
+
+// FindForAuthOnMaster is FindForAuth without the replica.
+func (r *Repository) FindForAuthOnMaster(ctx context.Context, keyID string) (model.APIKey, owner, error) {
+	return r.findForAuth(ctx, r.master.Pool, keyID)
+}
+
+func (r *Repository) findForAuth(ctx context.Context, pool *pgxpool.Pool, keyID string) (model.APIKey, owner, error) {
+	var k model.APIKey
+	var o owner
+	err := pool.QueryRow(ctx, `
+		SELECT k.id, k.key_id, k.name, k.owner_id, k.scopes, k.region, k.quota_per_hour,
+			k.secret_hash, k.previous_hash, k.previous_expires_at, k.created_at, k.updated_at, k.revoked_at,
+			u.username, u.role
+		FROM api_keys k JOIN users u ON u.id = k.owner_id
+		WHERE k.key_id = $1`, keyID).Scan(
+		&k.ID, &k.KeyID, &k.Name, &k.OwnerID, &k.Scopes, &k.Region, &k.QuotaPerHour,
+		&k.SecretHash, &k.PreviousHash, &k.PreviousExpiresAt, &k.CreatedAt, &k.UpdatedAt, &k.RevokedAt,
+		&o.Username, &o.Role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.APIKey{}, owner{}, ErrNotFound
+	}
+	return k, o, err
+}
+
+func (r *Repository) poolForRegion(region string) *pgxpool.Pool {
+	node := r.regions.NodeFor(region)
+	if r.replicas != nil {
+		if rep, ok := r.replicas.Get(node.Name); ok && rep.Healthy() {
+			return rep.Pool
+		}
+	}
+	return r.master.Pool
+}
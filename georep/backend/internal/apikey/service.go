@@ -0,0 +1,302 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"geo-repl-demo/internal/auth"
+	"geo-repl-demo/internal/model"
+	"geo-repl-demo/internal/region"
+	"geo-repl-demo/internal/replication"
+)
+
+var (
+	ErrInvalidInput = errors.New("invalid input")
+	ErrNotFound     = errors.New("api key not found")
+	ErrNotOwner     = errors.New("only the owner or an admin may manage this key")
+	// ErrKeyCaller is returned when an API key tries to manage API keys.
+	ErrKeyCaller  = errors.New("api keys cannot manage api keys")
+	ErrNoUser     = errors.New("token is not bound to a user account")
+	ErrScope      = errors.New("scope exceeds the caller's role")
+	ErrRevoked    = errors.New("api key revoked")
+	ErrInvalidKey = errors.New("invalid api key")
+	ErrKeyRegion  = errors.New("api key does not belong to this region")
+)
+
+const (
+	maxNameLen   = 64
+	maxQuota     = 1_000_000
+	defaultGrace = time.Hour
+	maxGrace     = 7 * 24 * time.Hour
+)
+
+type CreateInput struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes" binding:"required"`
+	// Region defaults to the caller's home region.
+	Region       string `json:"region"`
+	QuotaPerHour int    `json:"quota_per_hour"`
+}
+
+// Service manages API keys and resolves them to principals. It implements
+// auth.KeyResolver.
+type Service struct {
+	repo       *Repository
+	replicator *replication.Replicator
+	regions    *region.Registry
+	quota      *quota
+
+	mu sync.Mutex
+	// changed holds the master's updated_at of keys rotated or revoked by
+	// this backend, so that stale replica copies are not trusted until the
+	// change has been replicated.
+	changed map[string]time.Time
+}
+
+func NewService(repo *Repository, replicator *replication.Replicator, regions *region.Registry) *Service {
+	return &Service{
+		repo:       repo,
+		replicator: replicator,
+		regions:    regions,
+		quota:      newQuota(),
+		changed:    map[string]time.Time{},
+	}
+}
+
+// Create issues a new key owned by the caller. The plaintext key is
+// returned only here.
+func (s *Service) Create(ctx context.Context, p auth.Principal, in CreateInput, routed string) (model.APIKey, string, error) {
+	if err := manager(p); err != nil {
+		return model.APIKey{}, "", err
+	}
+	name := strings.TrimSpace(in.Name)
+	if name == "" || len(name) > maxNameLen {
+		return model.APIKey{}, "", fmt.Errorf("%w: name must be 1-%d characters", ErrInvalidInput, maxNameLen)
+	}
+	if in.QuotaPerHour < 0 || in.QuotaPerHour > maxQuota {
+		return model.APIKey{}, "", fmt.Errorf("%w: quota_per_hour must be between 0 and %d", ErrInvalidInput, maxQuota)
+	}
+	scopes, err := normalizeScopes(p, in.Scopes)
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+
+	reg, ok := s.regions.Lookup(p.Region)
+	if !ok {
+		reg = s.regions.Resolve(routed)
+	}
+	if in.Region != "" {
+		if reg, ok = s.regions.Lookup(in.Region); !ok {
+			return model.APIKey{}, "", fmt.Errorf("%w: unknown region %q", ErrInvalidInput, in.Region)
+		}
+	}
+
+	tok, err := newToken(reg.ID, "")
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+	k, err := s.repo.Insert(ctx, model.APIKey{
+		KeyID:        tok.keyID,
+		Name:         name,
+		OwnerID:      p.UserID,
+		Scopes:       scopes,
+		Region:       reg.ID,
+		QuotaPerHour: in.QuotaPerHour,
+		SecretHash:   hashSecret(tok.secret),
+	})
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+	s.schedule(k)
+	return k, tok.String(), nil
+}
+
+// List returns the caller's keys; admins see every key.
+func (s *Service) List(ctx context.Context, p auth.Principal) ([]model.APIKey, error) {
+	if err := manager(p); err != nil {
+		return nil, err
+	}
+	owner := p.UserID
+	if p.IsAdmin() {
+		owner = 0
+	}
+	return s.repo.ListFromMaster(ctx, owner)
+}
+
+// Rotate issues a new secret for the key. The previous secret keeps working
+// for grace (nil means the default of one hour) so clients can roll over.
+func (s *Service) Rotate(ctx context.Context, p auth.Principal, id int64, grace *time.Duration) (model.APIKey, string, error) {
+	k, err := s.managed(ctx, p, id)
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+	if k.Revoked() {
+		return model.APIKey{}, "", ErrRevoked
+	}
+	g := defaultGrace
+	if grace != nil {
+		g = *grace
+	}
+	if g < 0 || g > maxGrace {
+		return model.APIKey{}, "", fmt.Errorf("%w: grace must be between 0 and %s", ErrInvalidInput, maxGrace)
+	}
+
+	tok, err := newToken(k.Region, k.KeyID)
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+	k, err = s.repo.Rotate(ctx, id, hashSecret(tok.secret), time.Now().UTC().Add(g))
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+	s.markChanged(k)
+	s.schedule(k)
+	return k, tok.String(), nil
+}
+
+// Revoke disables the key everywhere once replicated; on this backend it
+// takes effect immediately.
+func (s *Service) Revoke(ctx context.Context, p auth.Principal, id int64) (model.APIKey, error) {
+	if _, err := s.managed(ctx, p, id); err != nil {
+		return model.APIKey{}, err
+	}
+	k, err := s.repo.Revoke(ctx, id)
+	if err != nil {
+		return model.APIKey{}, err
+	}
+	s.markChanged(k)
+	s.schedule(k)
+	return k, nil
+}
+
+// IsKey implements auth.KeyResolver.
+func (s *Service) IsKey(token string) bool {
+	return strings.HasPrefix(token, Prefix)
+}
+
+// ResolveKey implements auth.KeyResolver. The key is looked up on the
+// replica of the region embedded in it, so any backend can validate it
+// without a round trip to the master.
+func (s *Service) ResolveKey(ctx context.Context, raw string) (auth.Principal, error) {
+	tok, ok := parseToken(raw)
+	if !ok {
+		return auth.Principal{}, ErrInvalidKey
+	}
+	k, o, err := s.repo.FindForAuth(ctx, tok.region, tok.keyID)
+	if err == nil && s.stale(k) {
+		k, o, err = s.repo.FindForAuthOnMaster(ctx, tok.keyID)
+	}
+	if errors.Is(err, ErrNotFound) {
+		return auth.Principal{}, ErrInvalidKey
+	}
+	if err != nil {
+		return auth.Principal{}, err
+	}
+
+	if k.Region != tok.region {
+		return auth.Principal{}, ErrKeyRegion
+	}
+	if k.Revoked() {
+		return auth.Principal{}, ErrRevoked
+	}
+	current := secretMatches(tok.secret, k.SecretHash)
+	previous := k.PreviousExpiresAt != nil && time.Now().UTC().Before(*k.PreviousExpiresAt) &&
+		secretMatches(tok.secret, k.PreviousHash)
+	if !current && !previous {
+		return auth.Principal{}, ErrInvalidKey
+	}
+
+	s.quota.setLimit(k.KeyID, k.QuotaPerHour)
+	return auth.Principal{
+		UserID:   k.OwnerID,
+		Username: o.Username,
+		Role:     o.Role,
+		Region:   k.Region,
+		KeyID:    k.KeyID,
+		Scopes:   k.Scopes,
+	}, nil
+}
+
+// managed loads a key the caller may manage.
+func (s *Service) managed(ctx context.Context, p auth.Principal, id int64) (model.APIKey, error) {
+	if err := manager(p); err != nil {
+		return model.APIKey{}, err
+	}
+	k, err := s.repo.GetFromMaster(ctx, id)
+	if err != nil {
+		return model.APIKey{}, err
+	}
+	if k.OwnerID != p.UserID && !p.IsAdmin() {
+		return model.APIKey{}, ErrNotOwner
+	}
+	return k, nil
+}
+
+func (s *Service) schedule(k model.APIKey) {
+	if s.replicator != nil {
+		go s.replicator.ScheduleAPIKey(k)
+	}
+}
+
+func (s *Service) markChanged(k model.APIKey) {
+	s.mu.Lock()
+	s.changed[k.KeyID] = k.UpdatedAt
+	s.mu.Unlock()
+}
+
+// stale reports whether k predates a change made through this backend.
+// Entries are dropped once a replica has caught up.
+func (s *Service) stale(k model.APIKey) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	at, ok := s.changed[k.KeyID]
+	if !ok {
+		return false
+	}
+	if k.UpdatedAt.Before(at) {
+		return true
+	}
+	delete(s.changed, k.KeyID)
+	return false
+}
+
+// manager rejects callers that may not manage keys at all.
+func manager(p auth.Principal) error {
+	if p.IsAPIKey() {
+		return ErrKeyCaller
+	}
+	if p.UserID == 0 {
+		return ErrNoUser
+	}
+	return nil
+}
+
+// normalizeScopes validates, de-duplicates and sorts scopes. A caller can
+// only grant scopes within their own role.
+func normalizeScopes(p auth.Principal, scopes []string) ([]string, error) {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, sc := range scopes {
+		sc = strings.ToLower(strings.TrimSpace(sc))
+		if !auth.ValidScope(sc) {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidInput, sc)
+		}
+		if !p.ScopeAllowed(sc) {
+			return nil, fmt.Errorf("%w: %s", ErrScope, sc)
+		}
+		if !seen[sc] {
+			seen[sc] = true
+			out = append(out, sc)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidInput)
+	}
+	sort.Strings(out)
+	return out, nil
+}
@@ -1,10 +1,16 @@
 package article
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"geo-repl-demo/internal/auth"
+	"geo-repl-demo/internal/locale"
 	"geo-repl-demo/internal/model"
 )
 
@@ -20,34 +26,224 @@ func RegisterRoutes(r *gin.Engine, h *Handler) {
 	api := r.Group("/api")
 	{
 		api.GET("/articles", h.list)
-		api.POST("/articles", h.create)
-		api.DELETE("/articles/:id", h.delete)
+		api.GET("/articles/facets", h.facets)
+		api.GET("/articles/:id", h.get)
+		api.GET("/articles/:id/revisions", h.revisions)
+		api.GET("/articles/trash", auth.Require(auth.PermArticleEditOwn), h.trash)
+		api.POST("/articles/:id/restore", auth.Require(auth.PermArticleEditOwn), h.restore)
+		api.GET("/articles/purges", auth.Require(auth.PermAdmin), h.purges)
+		api.GET("/articles/unpublished", auth.Require(auth.PermArticleEditOwn), h.unpublished)
+		api.POST("/articles/:id/publish", auth.Require(auth.PermArticleEditOwn), h.publish)
+		api.POST("/articles", auth.Require(auth.PermArticleCreate), h.create)
+		api.PUT("/articles/:id", auth.Require(auth.PermArticleEditOwn), h.update)
+		api.DELETE("/articles/:id", auth.Require(auth.PermArticleEditOwn), h.delete)
+		api.PUT("/articles/:id/translations/:locale", auth.Require(auth.PermArticleEditOwn), h.putTranslation)
+		api.DELETE("/articles/:id/translations/:locale", auth.Require(auth.PermArticleEditOwn), h.deleteTranslation)
+		api.GET("/categories", h.categories)
+		api.POST("/categories", auth.Require(auth.PermArticleEditAny), h.createCategory)
+		api.PUT("/categories/:slug", auth.Require(auth.PermArticleEditAny), h.updateCategory)
 		api.GET("/replication-status", h.status)
+		api.POST("/replication/sync", auth.Require(auth.PermAdmin), h.sync)
+		api.GET("/admin/articles/export", auth.Require(auth.PermAdmin), h.exportArticles)
+		api.POST("/admin/articles/import", auth.Require(auth.PermAdmin), h.importArticles)
 	}
 }
 
 func (h *Handler) list(c *gin.Context) {
-	// Önce query parameter'dan region'ı kontrol et (test için)
-	regionStr := c.Query("region")
-	
-	// Query parameter yoksa middleware'den gelen region'ı kullan
-	if regionStr == "" {
-		region, _ := c.Get("region")
-		if val, ok := region.(string); ok {
-			regionStr = val
-		} else {
-			regionStr = "eu" // Varsayılan
+	// Bölge yalnızca middleware'den gelir: ?region= override'ı orada
+	// dev modu / admin token'ı ile sınırlandırılır ve audit edilir
+	regionStr := c.GetString("region") // boşsa kayıt defterindeki varsayılan bölge
+
+	// ?as_of= ile kataloğun geçmişteki hali (revizyon geçmişinden)
+	asOf, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
+	// ?tag= / ?category= ile süzme (tekrarlanabilir ya da virgülle ayrılmış)
+	f, ok := parseFilter(c)
+	if !ok {
+		return
+	}
+	// ?lang= ya da Accept-Language ile dil seçimi
+	prefs, ok := preferredLocales(c)
+	if !ok {
+		return
+	}
+	if !asOf.IsZero() {
+		revs, err := h.svc.ListAsOf(c.Request.Context(), regionStr, c.GetString("country"), asOf, f)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
+		for i := range revs {
+			h.svc.Localize(&revs[i].Article, regionStr, prefs)
+		}
+		c.JSON(http.StatusOK, revs)
+		return
 	}
 
-	arts, err := h.svc.ListByRegion(c.Request.Context(), regionStr)
+	// Ülkesi bilinmeyen istemciler yalnızca izin listesi olmayan makaleleri görür
+	arts, err := h.svc.ListByRegion(c.Request.Context(), regionStr, c.GetString("country"), f)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	for i := range arts {
+		h.svc.Localize(&arts[i], regionStr, prefs)
+	}
 	c.JSON(http.StatusOK, arts)
 }
 
+// facets bölgenin replikasındaki etiket / kategori sayılarını döner;
+// list ile aynı ?tag= / ?category= süzgeçlerini alır
+func (h *Handler) facets(c *gin.Context) {
+	f, ok := parseFilter(c)
+	if !ok {
+		return
+	}
+	facets, err := h.svc.Facets(c.Request.Context(), c.GetString("region"), c.GetString("country"), f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, facets)
+}
+
+// parseFilter ?tag= ve ?category= parametrelerini slug'a çevirir.
+// Geçersizse 400 yazar ve false döner.
+func parseFilter(c *gin.Context) (model.ArticleFilter, bool) {
+	split := func(values []string) []string {
+		var out []string
+		for _, v := range values {
+			for _, part := range strings.Split(v, ",") {
+				if strings.TrimSpace(part) != "" {
+					out = append(out, part)
+				}
+			}
+		}
+		return out
+	}
+	tags, categories, err := normalizeTaxonomy(split(c.QueryArray("tag")), split(c.QueryArray("category")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return model.ArticleFilter{}, false
+	}
+	return model.ArticleFilter{Tags: tags, Categories: categories}, true
+}
+
+func (h *Handler) get(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	asOf, ok := parseAsOf(c)
+	if !ok {
+		return
+	}
+	prefs, ok := preferredLocales(c)
+	if !ok {
+		return
+	}
+	var a any
+	var art *model.Article
+	if asOf.IsZero() {
+		var got *model.Article
+		got, err = h.svc.Get(c.Request.Context(), c.GetString("region"), c.GetString("country"), id)
+		a, art = got, got
+	} else {
+		var rev *model.ArticleRevision
+		rev, err = h.svc.GetAsOf(c.Request.Context(), c.GetString("region"), c.GetString("country"), id, asOf)
+		if rev != nil {
+			art = &rev.Article
+		}
+		a = rev
+	}
+	if err == nil {
+		c.Header("Content-Language", h.svc.Localize(art, c.GetString("region"), prefs))
+	}
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrGeoBlocked):
+		// 451 Unavailable For Legal Reasons (lisans kısıtı)
+		c.JSON(http.StatusUnavailableForLegalReasons, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// revisions makalenin tüm sürümlerini yeniden eskiye döner (silme kaydı dahil)
+func (h *Handler) revisions(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	prefs, ok := preferredLocales(c)
+	if !ok {
+		return
+	}
+	revs, err := h.svc.Revisions(c.Request.Context(), c.GetString("region"), c.GetString("country"), id)
+	for i := range revs {
+		h.svc.Localize(&revs[i].Article, c.GetString("region"), prefs)
+	}
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrGeoBlocked):
+		c.JSON(http.StatusUnavailableForLegalReasons, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, revs)
+}
+
+// preferredLocales okuyucunun dil tercihlerini döner: ?lang= (virgülle
+// ayrılmış, sırayla) verilmişse o, yoksa Accept-Language. Yanıt dile göre
+// değiştiği için Vary başlığı eklenir. Geçersiz lang için 400 yazar.
+func preferredLocales(c *gin.Context) ([]string, bool) {
+	c.Header("Vary", "Accept-Language")
+	v := c.Query("lang")
+	if v == "" {
+		return locale.ParseAcceptLanguage(c.GetHeader("Accept-Language")), true
+	}
+	var prefs []string
+	for _, tag := range strings.Split(v, ",") {
+		loc, ok := locale.Normalize(tag)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("lang: invalid language tag %q", tag)})
+			return nil, false
+		}
+		prefs = append(prefs, loc)
+	}
+	return prefs, true
+}
+
+// parseAsOf ?as_of= parametresini (RFC 3339) okur; yoksa sıfır zaman döner.
+// Geçersizse 400 yazar ve false döner.
+func parseAsOf(c *gin.Context) (time.Time, bool) {
+	v := c.Query("as_of")
+	if v == "" {
+		return time.Time{}, true
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "as_of must be an RFC 3339 timestamp"})
+		return time.Time{}, false
+	}
+	return t, true
+}
+
 func (h *Handler) create(c *gin.Context) {
 	var in model.CreateArticleInput
 	if err := c.ShouldBindJSON(&in); err != nil {
@@ -55,14 +251,36 @@ func (h *Handler) create(c *gin.Context) {
 		return
 	}
 
-	a, err := h.svc.Create(c.Request.Context(), in)
+	p, _ := auth.FromContext(c)
+	a, err := h.svc.Create(c.Request.Context(), p, in)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 	c.JSON(http.StatusCreated, a)
 }
 
+func (h *Handler) update(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var in model.UpdateArticleInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p, _ := auth.FromContext(c)
+	a, err := h.svc.Update(c.Request.Context(), p, id, in)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
 func (h *Handler) delete(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -71,12 +289,246 @@ func (h *Handler) delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.svc.Delete(c.Request.Context(), id); err != nil {
+	p, _ := auth.FromContext(c)
+	a, err := h.svc.Delete(c.Request.Context(), p, id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted", "deleted_at": a.DeletedAt})
+}
+
+// putTranslation makalenin bir dildeki çevirisini ekler ya da değiştirir
+func (h *Handler) putTranslation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var in model.Translation
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p, _ := auth.FromContext(c)
+	a, err := h.svc.PutTranslation(c.Request.Context(), p, id, c.Param("locale"), in)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// deleteTranslation makalenin bir dildeki çevirisini siler
+func (h *Handler) deleteTranslation(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	p, _ := auth.FromContext(c)
+	a, err := h.svc.DeleteTranslation(c.Request.Context(), p, id, c.Param("locale"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// restore çöpteki makaleyi saklama süresi içinde geri alır
+func (h *Handler) restore(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	p, _ := auth.FromContext(c)
+	a, err := h.svc.Restore(c.Request.Context(), p, id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// publish taslak ya da zamanlanmış makaleyi hemen yayına alır
+func (h *Handler) publish(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	p, _ := auth.FromContext(c)
+	a, err := h.svc.Publish(c.Request.Context(), p, id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// unpublished taslak ve zamanlanmış makaleleri listeler (master'dan)
+func (h *Handler) unpublished(c *gin.Context) {
+	p, _ := auth.FromContext(c)
+	arts, err := h.svc.Unpublished(c.Request.Context(), p)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, arts)
+}
+
+// trash çöpteki makaleleri ve kalıcı silinecekleri zamanı listeler
+func (h *Handler) trash(c *gin.Context) {
+	p, _ := auth.FromContext(c)
+	arts, err := h.svc.Trash(c.Request.Context(), p)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, arts)
+}
+
+// purges kalıcı silmelerin replikalara yayılma durumunu gösterir (admin)
+func (h *Handler) purges(c *gin.Context) {
+	purges, err := h.svc.Purges(c.Request.Context())
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, purges)
+}
 
-	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+// categories tüm kategorileri bölgenin replikasından listeler
+func (h *Handler) categories(c *gin.Context) {
+	cats, err := h.svc.Categories(c.Request.Context(), c.GetString("region"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, cats)
+}
+
+// createCategory yeni kategori tanımlar (editör)
+func (h *Handler) createCategory(c *gin.Context) {
+	var in model.CategoryInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p, _ := auth.FromContext(c)
+	cat, err := h.svc.CreateCategory(c.Request.Context(), p, in)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, cat)
+}
+
+// updateCategory kategorinin adını / açıklamasını değiştirir (editör)
+func (h *Handler) updateCategory(c *gin.Context) {
+	var in model.CategoryInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p, _ := auth.FromContext(c)
+	cat, err := h.svc.UpdateCategory(c.Request.Context(), p, c.Param("slug"), in)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, cat)
+}
+
+// sync tüm replikalar için hemen bir tam senkronizasyon çalıştırır (admin)
+func (h *Handler) sync(c *gin.Context) {
+	p, _ := auth.FromContext(c)
+	h.svc.FullSync(c.Request.Context(), p)
+	c.JSON(http.StatusOK, gin.H{"status": "synced"})
+}
+
+// maxImportBytes içe aktarılan dosyanın boyut sınırı
+const maxImportBytes = 64 << 20
+
+// exportArticles master'daki makaleleri NDJSON ya da CSV olarak akıtır
+// (?format=, yoksa Accept başlığı; ?include_deleted=true çöptekileri de ekler).
+func (h *Handler) exportArticles(c *gin.Context) {
+	format := transferFormat(c, c.GetHeader("Accept"))
+	includeDeleted, _ := strconv.ParseBool(c.Query("include_deleted"))
+
+	contentType := "application/x-ndjson"
+	if format == FormatCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+	w, err := NewRecordWriter(format, c.Writer)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="articles-%s.%s"`, time.Now().UTC().Format("20060102-150405"), format))
+	c.Status(http.StatusOK)
+
+	// Yanıt akmaya başladı; hata artık durum koduyla bildirilemez, dosya kesik kalır
+	p, _ := auth.FromContext(c)
+	if _, err := h.svc.Export(c.Request.Context(), p, w, includeDeleted); err != nil {
+		_ = c.Error(err)
+	}
+}
+
+// importArticles NDJSON ya da CSV gövdesini (?format=, yoksa Content-Type)
+// satır satır içe aktarır. ?dry_run=true yalnızca doğrular. Hatalı satır
+// varsa hiçbir satır yazılmaz ve rapor 422 ile döner.
+func (h *Handler) importArticles(c *gin.Context) {
+	dryRun := false
+	if v := c.Query("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+			return
+		}
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	rr, err := NewRecordReader(transferFormat(c, c.GetHeader("Content-Type")), body)
+	if err == nil {
+		p, _ := auth.FromContext(c)
+		var report model.ImportReport
+		if report, err = h.svc.Import(c.Request.Context(), p, rr, dryRun); err == nil {
+			status := http.StatusOK
+			if report.Failed > 0 {
+				status = http.StatusUnprocessableEntity
+			}
+			c.JSON(status, report)
+			return
+		}
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("import is larger than %d bytes", maxImportBytes)})
+		return
+	}
+	writeError(c, err)
+}
+
+// transferFormat ?format= parametresini, yoksa verilen Accept / Content-Type
+// başlığını okur; varsayılan NDJSON'dır.
+func transferFormat(c *gin.Context, header string) string {
+	if f := strings.ToLower(c.Query("format")); f != "" {
+		return f
+	}
+	if strings.Contains(strings.ToLower(header), "csv") {
+		return FormatCSV
+	}
+	return FormatNDJSON
 }
 
 func (h *Handler) status(c *gin.Context) {
@@ -87,3 +539,24 @@ func (h *Handler) status(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, status)
 }
+
+// writeError servis hatalarını HTTP durum kodlarına çevirir
+func writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidCountry), errors.Is(err, ErrInvalidSchedule),
+		errors.Is(err, ErrInvalidTaxonomy), errors.Is(err, ErrUnknownCategory),
+		errors.Is(err, ErrInvalidLocale), errors.Is(err, ErrInvalidTranslation),
+		errors.Is(err, ErrInvalidFormat):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrCategoryNotFound), errors.Is(err, ErrTranslationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotDeleted), errors.Is(err, ErrAlreadyPublished), errors.Is(err, ErrCategoryExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrRetentionExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotOwner), errors.Is(err, ErrNoAuthor):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": auth.CodeForbidden})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
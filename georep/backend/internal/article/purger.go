@@ -0,0 +1,123 @@
+package article
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strconv"
+	"time"
+
+	"geo-repl-demo/internal/auth"
+	"geo-repl-demo/internal/db"
+	"geo-repl-demo/internal/model"
+)
+
+// purgeSettle, bir replikanın kalıcı silmeyi onaylaması için geçmesi gereken
+// süredir: öncesinde başlamış bir FullSync ya da gecikmeli Schedule satırı
+// yeniden yazabilir, bu yüzden silme bu süre boyunca her turda tekrarlanır.
+const purgeSettle = 30 * time.Second
+
+// PurgeHook kalıcı silinen bir makalenin veritabanı dışındaki verilerini
+// (ör. ek dosyalarının blob'ları) bir node'dan siler. Hata dönerse replika
+// onayı bir sonraki tura kalır.
+type PurgeHook func(ctx context.Context, node string, articleID int64) error
+
+// OnPurge kalıcı silmede çalışacak bir kanca ekler
+func (s *Service) OnPurge(h PurgeHook) {
+	s.purgeHooks = append(s.purgeHooks, h)
+}
+
+func (s *Service) runPurgeHooks(ctx context.Context, node string, articleID int64) error {
+	for _, h := range s.purgeHooks {
+		if err := h(ctx, node, articleID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunPurger saklama süresi dolan makaleleri periyodik olarak kalıcı siler
+func (s *Service) RunPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.Purge(ctx); err != nil {
+			log.Printf("⚠️ Kalıcı silme hatası: %v", err)
+		}
+	}
+}
+
+// Purge süresi dolan makaleleri master'dan siler, ardından bekleyen her
+// silmeyi replikalarda uygular ve tüm replikalar onaylayınca tamamlar.
+func (s *Service) Purge(ctx context.Context) error {
+	purged, err := s.repo.PurgeExpiredMaster(ctx, time.Now().Add(-s.retention))
+	if err != nil {
+		return err
+	}
+	for _, a := range purged {
+		log.Printf("🧹 Makale %d kalıcı olarak silindi (master)", a.ID)
+		if s.audit != nil {
+			e := auth.Principal{Username: db.SystemUsername}.AuditEvent(ctx, "article.purge", "article", strconv.FormatInt(a.ID, 10))
+			e.BeforeHash = articleHash(&a)
+			s.audit.Record(ctx, e)
+		}
+	}
+
+	pending, err := s.repo.PendingPurges(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range pending {
+		s.confirmPurge(ctx, p)
+	}
+	return nil
+}
+
+// confirmPurge silmeyi henüz onaylamamış her replikada uygular ve doğrular
+func (s *Service) confirmPurge(ctx context.Context, p model.ArticlePurge) {
+	settled := time.Since(p.PurgedAt) >= purgeSettle
+	confirmed := p.ConfirmedReplicas
+	complete := true
+	// Master'daki satırlar zaten silindi; kancalar tamamlanana kadar her turda tekrarlanır
+	if err := s.runPurgeHooks(ctx, s.repo.master.Node.Name, p.ArticleID); err != nil {
+		log.Printf("⚠️ Makale %d verileri master'dan silinemedi: %v", p.ArticleID, err)
+		complete = false
+	}
+	for _, rep := range s.repo.Replicas() {
+		name := rep.Node.Name
+		if slices.Contains(confirmed, name) {
+			continue
+		}
+		remaining, err := db.PurgeArticle(ctx, rep.Pool, p.ArticleID)
+		if err == nil {
+			err = s.runPurgeHooks(ctx, name, p.ArticleID)
+		}
+		switch {
+		case err != nil:
+			log.Printf("⚠️ Makale %d %s replikasından silinemedi: %v", p.ArticleID, name, err)
+			complete = false
+		case remaining || !settled:
+			complete = false
+		default:
+			confirmed = append(confirmed, name)
+		}
+	}
+	if err := s.repo.ConfirmPurge(ctx, p.ArticleID, confirmed, complete); err != nil {
+		log.Printf("⚠️ Kalıcı silme onayı kaydedilemedi (%d): %v", p.ArticleID, err)
+		return
+	}
+	if complete {
+		log.Printf("✅ Makale %d tüm replikalardan kalıcı olarak silindi", p.ArticleID)
+	}
+}
+
+// Purges son kalıcı silmeleri ve replika onaylarını döner (admin)
+func (s *Service) Purges(ctx context.Context) ([]model.ArticlePurge, error) {
+	return s.repo.RecentPurges(ctx, 100)
+}
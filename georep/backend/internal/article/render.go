@@ -0,0 +1,55 @@
+package article
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"geo-repl-demo/internal/markdown"
+	"geo-repl-demo/internal/model"
+)
+
+// summaryLength boş bırakılan özetin içerikten alınan en fazla uzunluğu (karakter)
+const summaryLength = 200
+
+// render content_long Markdown'ını temizlenmiş HTML'e ve içindekiler listesine
+// çevirir. Özet boşsa içeriğin düz metninden (başlıklar ve kod hariç), o da
+// yoksa başlıktan doldurulur.
+func render(title, content string, summary *string) (string, []model.Heading) {
+	doc := markdown.Render(content)
+	if *summary = strings.TrimSpace(*summary); *summary == "" {
+		*summary = markdown.Excerpt(doc.Text, summaryLength)
+	}
+	if *summary == "" {
+		*summary = title
+	}
+	toc := make([]model.Heading, len(doc.TOC))
+	for i, h := range doc.TOC {
+		toc[i] = model.Heading{Level: h.Level, ID: h.ID, Text: h.Text}
+	}
+	return doc.HTML, toc
+}
+
+// 🔹 Markdown desteğinden önce yazılmış makalelerin HTML'ini üret (açılışta).
+// Revizyon artmaz; replikalara tam senkronizasyonla ulaşır.
+func (s *Service) RenderMissing(ctx context.Context) error {
+	list, err := s.repo.ListUnrenderedFromMaster(ctx)
+	if err != nil {
+		return err
+	}
+	for _, a := range list {
+		summary := a.Summary
+		a.ContentHTML, a.TOC = render(a.Title, a.ContentLong, &summary)
+		for loc, t := range a.Translations {
+			t.ContentHTML, t.TOC = render(t.Title, t.ContentLong, &t.Summary)
+			a.Translations[loc] = t
+		}
+		if err := s.repo.SetRenderedMaster(ctx, a); err != nil {
+			return err
+		}
+	}
+	if len(list) > 0 {
+		log.Printf("📝 %d makalenin HTML'i üretildi", len(list))
+	}
+	return nil
+}
@@ -2,103 +2,475 @@ package article
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"geo-repl-demo/internal/db"
 	"geo-repl-demo/internal/model"
+	"geo-repl-demo/internal/region"
 )
 
 type Repository struct {
 	master   *db.Master
 	replicas *db.ReplicaSet
+	regions  *region.Registry
 }
 
-func NewRepository(master *db.Master, replicas *db.ReplicaSet) *Repository {
-	return &Repository{master: master, replicas: replicas}
+func NewRepository(master *db.Master, replicas *db.ReplicaSet, regions *region.Registry) *Repository {
+	return &Repository{master: master, replicas: replicas, regions: regions}
 }
 
 // =======================================================
 // 🔹 Master’a yazma işlemi (makale ekleme)
 // =======================================================
-func (r *Repository) InsertMaster(ctx context.Context, in model.CreateArticleInput, region string) (model.Article, error) {
-	var a model.Article
+// Yazar adı, kullanıcının görünen adından türetilir (istemciden alınmaz).
+// İlk revizyon aynı transaction içinde kaydedilir.
+func (r *Repository) InsertMaster(ctx context.Context, in model.CreateArticleInput, authorID int64, editor, region string) (model.Article, model.ArticleRevision, error) {
+	tx, err := r.master.Pool.Begin(ctx)
+	if err != nil {
+		return model.Article{}, model.ArticleRevision{}, fmt.Errorf("insert master: %w", err)
+	}
+	defer tx.Rollback(ctx)
 
-	err := r.master.Pool.QueryRow(ctx, `
-		INSERT INTO articles (title, summary, content_long, author, region)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, title, summary, content_long, author, region, created_at
-	`, in.Title, in.Summary, in.ContentLong, in.Author, region).
-		Scan(&a.ID, &a.Title, &a.Summary, &a.ContentLong, &a.Author, &a.Region, &a.CreatedAt)
+	a, rev, err := insertArticle(ctx, tx, in, authorID, nil, nil, authorID, editor, region)
+	if err != nil {
+		return model.Article{}, model.ArticleRevision{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return model.Article{}, model.ArticleRevision{}, fmt.Errorf("insert master: %w", err)
+	}
+	return a, rev, nil
+}
 
+// insertArticle makaleyi tx içinde ekler ve ilk revizyonunu kaydeder.
+// createdAt nil ise şimdiki zaman kullanılır; deletedAt verilirse makale
+// doğrudan çöpe eklenir.
+func insertArticle(ctx context.Context, tx pgx.Tx, in model.CreateArticleInput, authorID int64, createdAt, deletedAt *time.Time, editorID int64, editor, region string) (model.Article, model.ArticleRevision, error) {
+	var a model.Article
+	row := tx.QueryRow(ctx, `
+		INSERT INTO articles (title, summary, content_long, author, author_id, region, allowed_countries, denied_countries,
+			status, publish_at, region_publish_at, locale, translations, content_html, toc, created_at, deleted_at)
+		SELECT $1, $2, $3, COALESCE(NULLIF(u.display_name, ''), u.username), u.id, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14,
+			COALESCE($15::timestamp, NOW()), $16::timestamp
+		FROM users u WHERE u.id = $4
+		RETURNING `+db.ArticleColumns,
+		in.Title, in.Summary, in.ContentLong, authorID, region, in.AllowedCountries, in.DeniedCountries,
+		in.Status, in.PublishAt, in.RegionPublishAt, in.Locale, db.Translations(in.Translations),
+		in.ContentHTML, db.TOC(in.TOC), createdAt, deletedAt)
+	err := db.ScanArticle(row, &a)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Article{}, model.ArticleRevision{}, fmt.Errorf("insert master: author %d not found", authorID)
+	}
+
+	if err != nil {
+		return model.Article{}, model.ArticleRevision{}, fmt.Errorf("insert master: %w", err)
+	}
+	if err := setTaxonomy(ctx, tx, &a, in.Tags, in.Categories); err != nil {
+		return model.Article{}, model.ArticleRevision{}, err
+	}
+	rev, err := recordRevision(ctx, tx, a.ID, editorID, editor)
 	if err != nil {
-		return model.Article{}, fmt.Errorf("insert master: %w", err)
+		return model.Article{}, model.ArticleRevision{}, err
+	}
+	return a, rev, nil
+}
+
+// =======================================================
+// 🔹 Master’da güncelleme ve sahiplik kontrolü için okuma
+// =======================================================
+func (r *Repository) GetFromMaster(ctx context.Context, id int64) (model.Article, error) {
+	var a model.Article
+	row := r.master.Pool.QueryRow(ctx, `SELECT `+db.ArticleColumns+` FROM articles WHERE id=$1`, id)
+	if err := db.ScanArticle(row, &a); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Article{}, ErrNotFound
+		}
+		return model.Article{}, err
 	}
 	return a, nil
 }
 
+// UpdateMaster her düzenlemede revizyonu artırır ve yeni hali geçmişe ekler.
+func (r *Repository) UpdateMaster(ctx context.Context, id int64, in model.ArticleInput, allowed, denied []string, editorID int64, editor string) (model.Article, model.ArticleRevision, error) {
+	var a model.Article
+
+	tx, err := r.master.Pool.Begin(ctx)
+	if err != nil {
+		return model.Article{}, model.ArticleRevision{}, fmt.Errorf("update master: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	row := tx.QueryRow(ctx, `
+		UPDATE articles
+		SET title=$2, summary=$3, content_long=$4, allowed_countries=$5, denied_countries=$6,
+			status=$7, publish_at=$8, region_publish_at=$9, locale=$10, translations=$11,
+			content_html=$12, toc=$13, revision=revision+1, updated_at=NOW()
+		WHERE id=$1 AND deleted_at IS NULL
+		RETURNING `+db.ArticleColumns,
+		id, in.Title, in.Summary, in.ContentLong, allowed, denied,
+		in.Status, in.PublishAt, in.RegionPublishAt, in.Locale, db.Translations(in.Translations),
+		in.ContentHTML, db.TOC(in.TOC))
+	if err := db.ScanArticle(row, &a); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Article{}, model.ArticleRevision{}, ErrNotFound
+		}
+		return model.Article{}, model.ArticleRevision{}, fmt.Errorf("update master: %w", err)
+	}
+	if err := setTaxonomy(ctx, tx, &a, in.Tags, in.Categories); err != nil {
+		return model.Article{}, model.ArticleRevision{}, err
+	}
+	rev, err := recordRevision(ctx, tx, id, editorID, editor)
+	if err != nil {
+		return model.Article{}, model.ArticleRevision{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return model.Article{}, model.ArticleRevision{}, fmt.Errorf("update master: %w", err)
+	}
+	return a, rev, nil
+}
+
+// recordRevision makalenin tx içindeki güncel halini geçmiş tablosuna ekler.
+// Çöpteki makalenin revizyonu "deleted" olarak yazılır.
+func recordRevision(ctx context.Context, tx pgx.Tx, id, editorID int64, editor string) (model.ArticleRevision, error) {
+	var rev model.ArticleRevision
+	row := tx.QueryRow(ctx, `
+		INSERT INTO article_revisions (article_id, revision, title, summary, content_long, author, author_id,
+			region, created_at, allowed_countries, denied_countries, editor, editor_id, deleted,
+			status, publish_at, region_publish_at, tags, categories, locale, translations, content_html, toc)
+		SELECT id, revision, title, COALESCE(summary, ''), COALESCE(content_long, ''), author,
+			COALESCE(author_id, 0), region, created_at, allowed_countries, denied_countries, $3, $2,
+			deleted_at IS NOT NULL, status, publish_at, region_publish_at,
+			ARRAY(SELECT tag FROM article_tags WHERE article_id = articles.id ORDER BY tag),
+			ARRAY(SELECT category FROM article_categories WHERE article_id = articles.id ORDER BY category),
+			locale, translations, content_html, toc
+		FROM articles WHERE id=$1
+		RETURNING `+db.RevisionColumns,
+		id, editorID, editor)
+	if err := db.ScanRevision(row, &rev); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return rev, ErrNotFound
+		}
+		return rev, fmt.Errorf("record revision: %w", err)
+	}
+	return rev, nil
+}
+
+// setTaxonomy makalenin etiket ve kategorilerini tx içinde değiştirir.
+// Kategoriler önceden tanımlanmış olmalıdır; etiketler ilk kullanımda oluşur.
+func setTaxonomy(ctx context.Context, tx pgx.Tx, a *model.Article, tags, categories []string) error {
+	if len(categories) > 0 {
+		var known []string
+		if err := tx.QueryRow(ctx, `
+			SELECT COALESCE(array_agg(slug), '{}') FROM categories WHERE slug = ANY($1)
+		`, categories).Scan(&known); err != nil {
+			return err
+		}
+		if missing := missingSlugs(categories, known); len(missing) > 0 {
+			return fmt.Errorf("%w: %s", ErrUnknownCategory, strings.Join(missing, ", "))
+		}
+	}
+	if err := db.SetArticleTaxonomy(ctx, tx, a.ID, tags, categories); err != nil {
+		return err
+	}
+	// RETURNING eski bağlantıları gördü; güncel listeler (servisçe sıralanmış) girdinin kendisidir
+	a.Tags, a.Categories = orNone(tags), orNone(categories)
+	return nil
+}
+
 // =======================================================
 // 🔹 Replikaya kopyalama (Replication)
 // =======================================================
-func (r *Repository) CopyToReplica(ctx context.Context, idx int, a model.Article) error {
-	if idx < 0 || r.replicas == nil || idx >= len(r.replicas.Pools) {
-		return fmt.Errorf("invalid replica index %d", idx)
-	}
-	pool := r.replicas.Pools[idx]
-
-	_, err := pool.Exec(ctx, `
-		CREATE TABLE IF NOT EXISTS articles (
-			id SERIAL PRIMARY KEY,
-			title TEXT NOT NULL,
-			summary TEXT NOT NULL,
-			content_long TEXT NOT NULL,
-			author TEXT NOT NULL,
-			region TEXT NOT NULL,
-			created_at TIMESTAMP DEFAULT NOW()
-		);
-
-		INSERT INTO articles (id, title, summary, content_long, author, region, created_at)
-		VALUES ($1,$2,$3,$4,$5,$6,$7)
-		ON CONFLICT (id) DO UPDATE
-		SET title=EXCLUDED.title,
-			summary=EXCLUDED.summary,
-			content_long=EXCLUDED.content_long,
-			author=EXCLUDED.author,
-			region=EXCLUDED.region,
-			created_at=EXCLUDED.created_at;
-	`, a.ID, a.Title, a.Summary, a.ContentLong, a.Author, a.Region, a.CreatedAt)
+func (r *Repository) CopyToReplica(ctx context.Context, name string, a model.Article) error {
+	pool, err := r.replicaPool(name)
+	if err != nil {
+		return err
+	}
 
-	return err
+	if err := db.EnsureReplicaSchema(ctx, pool); err != nil {
+		return err
+	}
+	return db.UpsertArticle(ctx, pool, a)
 }
 
 // =======================================================
 // 🔹 Makale silme işlemleri
 // =======================================================
-func (r *Repository) DeleteFromMaster(ctx context.Context, id int64) error {
-	_, err := r.master.Pool.Exec(ctx, `DELETE FROM articles WHERE id=$1`, id)
+// SoftDeleteMaster makaleyi çöpe taşır (deleted_at); replikalara durum
+// değişikliği olarak gider. Silme geçmişe yeni bir revizyon olarak yazılır.
+func (r *Repository) SoftDeleteMaster(ctx context.Context, id, editorID int64, editor string) (model.Article, model.ArticleRevision, error) {
+	return r.transition(ctx, id, editorID, editor, `
+		UPDATE articles SET deleted_at=NOW(), revision=revision+1, updated_at=NOW()
+		WHERE id=$1 AND deleted_at IS NULL
+		RETURNING `+db.ArticleColumns)
+}
+
+// RestoreMaster çöpteki makaleyi geri alır; saklama süresi dolmuşsa
+// (deleted_at, cutoff'tan eskiyse) ErrNotFound döner.
+func (r *Repository) RestoreMaster(ctx context.Context, id int64, cutoff time.Time, editorID int64, editor string) (model.Article, model.ArticleRevision, error) {
+	return r.transition(ctx, id, editorID, editor, `
+		UPDATE articles SET deleted_at=NULL, revision=revision+1, updated_at=NOW()
+		WHERE id=$1 AND deleted_at IS NOT NULL AND deleted_at >= $2::timestamptz
+		RETURNING `+db.ArticleColumns, cutoff)
+}
+
+// PublishMaster zamanlanmış ya da taslak makaleyi yayına alır. now true ise
+// (elle yayın) yayın zamanı şimdi olur; aksi halde zamanlama korunur.
+func (r *Repository) PublishMaster(ctx context.Context, id int64, now bool, editorID int64, editor string) (model.Article, model.ArticleRevision, error) {
+	return r.transition(ctx, id, editorID, editor, `
+		UPDATE articles SET status='published', revision=revision+1, updated_at=NOW(),
+			publish_at=CASE WHEN $2 THEN NOW() ELSE publish_at END,
+			region_publish_at=CASE WHEN $2 THEN '{}'::jsonb ELSE region_publish_at END
+		WHERE id=$1 AND deleted_at IS NULL AND status <> 'published'
+		RETURNING `+db.ArticleColumns, now)
+}
+
+// PutTranslationMaster makalenin bir çevirisini ekler ya da değiştirir;
+// diğer çeviriler korunur (jsonb birleştirmesi tek satırda, yarışsız).
+func (r *Repository) PutTranslationMaster(ctx context.Context, id int64, loc string, t model.Translation, editorID int64, editor string) (model.Article, model.ArticleRevision, error) {
+	return r.transition(ctx, id, editorID, editor, `
+		UPDATE articles SET translations = translations || jsonb_build_object($2::text, $3::jsonb),
+			revision=revision+1, updated_at=NOW()
+		WHERE id=$1 AND deleted_at IS NULL
+		RETURNING `+db.ArticleColumns, loc, t)
+}
+
+// DeleteTranslationMaster makalenin bir çevirisini siler.
+func (r *Repository) DeleteTranslationMaster(ctx context.Context, id int64, loc string, editorID int64, editor string) (model.Article, model.ArticleRevision, error) {
+	return r.transition(ctx, id, editorID, editor, `
+		UPDATE articles SET translations = translations - $2::text, revision=revision+1, updated_at=NOW()
+		WHERE id=$1 AND deleted_at IS NULL AND translations ? $2::text
+		RETURNING `+db.ArticleColumns, loc)
+}
+
+// DueScheduledMaster tüm bölgelerdeki yayın zamanı gelmiş zamanlanmış makaleleri döner.
+func (r *Repository) DueScheduledMaster(ctx context.Context) ([]int64, error) {
+	rows, err := r.master.Pool.Query(ctx, `
+		SELECT id FROM articles
+		WHERE status = 'scheduled' AND deleted_at IS NULL AND publish_at <= NOW()
+			AND NOT EXISTS (
+				SELECT 1 FROM jsonb_each_text(region_publish_at) e
+				WHERE e.value::timestamptz > NOW()
+			)
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// transition makalenin durumunu tek bir UPDATE ile değiştirir ve yeni hali
+// aynı transaction içinde geçmişe ekler. Satır eşleşmezse ErrNotFound döner.
+func (r *Repository) transition(ctx context.Context, id, editorID int64, editor, query string, args ...any) (model.Article, model.ArticleRevision, error) {
+	var a model.Article
+
+	tx, err := r.master.Pool.Begin(ctx)
+	if err != nil {
+		return model.Article{}, model.ArticleRevision{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	row := tx.QueryRow(ctx, query, append([]any{id}, args...)...)
+	if err := db.ScanArticle(row, &a); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Article{}, model.ArticleRevision{}, ErrNotFound
+		}
+		return model.Article{}, model.ArticleRevision{}, err
+	}
+	rev, err := recordRevision(ctx, tx, id, editorID, editor)
+	if err != nil {
+		return model.Article{}, model.ArticleRevision{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return model.Article{}, model.ArticleRevision{}, err
+	}
+	return a, rev, nil
+}
+
+// ListDeletedFromMaster çöpteki makaleleri döner (authorID 0 ise hepsini).
+func (r *Repository) ListDeletedFromMaster(ctx context.Context, authorID int64) ([]model.Article, error) {
+	return r.listFromMaster(ctx, `deleted_at IS NOT NULL`, `deleted_at DESC`, authorID)
+}
+
+// ListUnpublishedFromMaster taslak ve zamanlanmış makaleleri döner (authorID 0 ise hepsini).
+func (r *Repository) ListUnpublishedFromMaster(ctx context.Context, authorID int64) ([]model.Article, error) {
+	return r.listFromMaster(ctx, `deleted_at IS NULL AND status <> 'published'`, `publish_at NULLS FIRST, id DESC`, authorID)
+}
+
+func (r *Repository) listFromMaster(ctx context.Context, where, order string, authorID int64) ([]model.Article, error) {
+	rows, err := r.master.Pool.Query(ctx, `
+		SELECT `+db.ArticleColumns+`
+		FROM articles
+		WHERE `+where+` AND ($1 = 0 OR author_id = $1)
+		ORDER BY `+order, authorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := []model.Article{}
+	for rows.Next() {
+		var a model.Article
+		if err := db.ScanArticle(rows, &a); err != nil {
+			return nil, err
+		}
+		res = append(res, a)
+	}
+	return res, rows.Err()
+}
+
+// ListUnrenderedFromMaster HTML'i henüz üretilmemiş (Markdown desteğinden
+// önce yazılmış) makaleleri döner.
+func (r *Repository) ListUnrenderedFromMaster(ctx context.Context) ([]model.Article, error) {
+	return r.listFromMaster(ctx, `(content_html = '' AND COALESCE(content_long, '') <> ''
+		OR EXISTS (SELECT 1 FROM jsonb_each(translations) t WHERE NOT t.value ? 'content_html'))`, `id`, 0)
+}
+
+// SetRenderedMaster üretilen HTML'i yazar. İçerik değişmediği için revizyon
+// artmaz; arada düzenlenen makale (revizyonu değişmiş) atlanır.
+func (r *Repository) SetRenderedMaster(ctx context.Context, a model.Article) error {
+	_, err := r.master.Pool.Exec(ctx, `
+		UPDATE articles SET content_html=$3, toc=$4, translations=$5
+		WHERE id=$1 AND revision=$2
+	`, a.ID, a.Revision, a.ContentHTML, db.TOC(a.TOC), db.Translations(a.Translations))
 	return err
 }
 
-func (r *Repository) DeleteFromReplica(ctx context.Context, idx int, id int64) error {
-	if idx < 0 || r.replicas == nil || idx >= len(r.replicas.Pools) {
-		return fmt.Errorf("invalid replica index %d", idx)
+// =======================================================
+// 🔹 Kalıcı silme (purge) – saklama süresi dolan makaleler
+// =======================================================
+
+// PurgeExpiredMaster deleted_at'i cutoff'tan eski makaleleri (geçmişleriyle
+// birlikte) master'dan kalıcı olarak siler ve article_purges'a kaydeder.
+func (r *Repository) PurgeExpiredMaster(ctx context.Context, cutoff time.Time) ([]model.Article, error) {
+	tx, err := r.master.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		DELETE FROM articles
+		WHERE deleted_at IS NOT NULL AND deleted_at < $1::timestamptz
+		RETURNING `+db.ArticleColumns, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	var purged []model.Article
+	for rows.Next() {
+		var a model.Article
+		if err := db.ScanArticle(rows, &a); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		purged = append(purged, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, a := range purged {
+		if _, err := tx.Exec(ctx, `DELETE FROM article_revisions WHERE article_id=$1`, a.ID); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM article_tags WHERE article_id=$1`, a.ID); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM article_categories WHERE article_id=$1`, a.ID); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM comments WHERE article_id=$1`, a.ID); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM attachment_copies WHERE attachment_id IN (SELECT id FROM attachments WHERE article_id=$1)
+		`, a.ID); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM attachments WHERE article_id=$1`, a.ID); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO article_purges (article_id) VALUES ($1)
+			ON CONFLICT (article_id) DO UPDATE SET purged_at=NOW(), confirmed_at=NULL, confirmed_replicas='{}'
+		`, a.ID); err != nil {
+			return nil, err
+		}
+	}
+	return purged, tx.Commit(ctx)
+}
+
+// PendingPurges henüz tüm replikalarca onaylanmamış kalıcı silmeleri döner.
+func (r *Repository) PendingPurges(ctx context.Context) ([]model.ArticlePurge, error) {
+	return r.purges(ctx, `WHERE confirmed_at IS NULL ORDER BY purged_at`)
+}
+
+// RecentPurges son kalıcı silmeleri (yeniden eskiye) döner.
+func (r *Repository) RecentPurges(ctx context.Context, limit int) ([]model.ArticlePurge, error) {
+	return r.purges(ctx, `ORDER BY purged_at DESC LIMIT $1`, limit)
+}
+
+func (r *Repository) purges(ctx context.Context, tail string, args ...any) ([]model.ArticlePurge, error) {
+	rows, err := r.master.Pool.Query(ctx, `
+		SELECT article_id, purged_at, confirmed_at, confirmed_replicas
+		FROM article_purges `+tail, args...)
+	if err != nil {
+		return nil, err
 	}
-	pool := r.replicas.Pools[idx]
-	_, err := pool.Exec(ctx, `DELETE FROM articles WHERE id=$1`, id)
+	defer rows.Close()
+
+	res := []model.ArticlePurge{}
+	for rows.Next() {
+		var p model.ArticlePurge
+		if err := rows.Scan(&p.ArticleID, &p.PurgedAt, &p.ConfirmedAt, &p.ConfirmedReplicas); err != nil {
+			return nil, err
+		}
+		res = append(res, p)
+	}
+	return res, rows.Err()
+}
+
+// ConfirmPurge bir replikanın kalıcı silmeyi onayladığını kaydeder; complete
+// ise silme tamamlanmış sayılır.
+func (r *Repository) ConfirmPurge(ctx context.Context, id int64, replicas []string, complete bool) error {
+	_, err := r.master.Pool.Exec(ctx, `
+		UPDATE article_purges
+		SET confirmed_replicas=$2, confirmed_at=CASE WHEN $3 THEN NOW() ELSE NULL END
+		WHERE article_id=$1
+	`, id, replicas, complete)
 	return err
 }
 
 // =======================================================
 // 🔹 Bölgeye göre okuma (ReaderPage için)
 // =======================================================
-func (r *Repository) ListByRegion(ctx context.Context, region string) ([]model.Article, error) {
+// Yalnızca bu bölgede yayın zamanı gelmiş makaleler döner (replikanın saatine göre).
+// f verilirse etiket / kategoriye göre süzülür.
+func (r *Repository) ListByRegion(ctx context.Context, region string, f model.ArticleFilter) ([]model.Article, error) {
 	pool := r.poolForRegion(region)
 
 	rows, err := pool.Query(ctx, `
-		SELECT id, title, summary, content_long, author, region, created_at
+		SELECT `+db.ArticleColumns+`
 		FROM articles
+		WHERE deleted_at IS NULL AND `+db.PublishedSQL("$1", "NOW()")+`
+			AND `+db.TaxonomySQL("$2", "$3")+`
 		ORDER BY created_at DESC
-	`)
+	`, r.regions.Resolve(region).ID, orNone(f.Tags), orNone(f.Categories))
 	if err != nil {
 		return nil, err
 	}
@@ -107,7 +479,7 @@ func (r *Repository) ListByRegion(ctx context.Context, region string) ([]model.A
 	var res []model.Article
 	for rows.Next() {
 		var a model.Article
-		if err := rows.Scan(&a.ID, &a.Title, &a.Summary, &a.ContentLong, &a.Author, &a.Region, &a.CreatedAt); err != nil {
+		if err := db.ScanArticle(rows, &a); err != nil {
 			return nil, err
 		}
 		res = append(res, a)
@@ -115,61 +487,360 @@ func (r *Repository) ListByRegion(ctx context.Context, region string) ([]model.A
 	return res, nil
 }
 
+// GetByID tek makaleyi bölgenin replikasından okur.
+func (r *Repository) GetByID(ctx context.Context, region string, id int64) (model.Article, error) {
+	var a model.Article
+	row := r.poolForRegion(region).QueryRow(ctx, `
+		SELECT `+db.ArticleColumns+`
+		FROM articles
+		WHERE id=$1 AND deleted_at IS NULL AND `+db.PublishedSQL("$2", "NOW()")+`
+	`, id, r.regions.Resolve(region).ID)
+	if err := db.ScanArticle(row, &a); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return model.Article{}, ErrNotFound
+		}
+		return model.Article{}, err
+	}
+	return a, nil
+}
+
 // =======================================================
-// 🔹 Replika seçimi (Geo yönlendirme)
+// 🔹 Revizyon geçmişi ve zamanda geriye okuma (as_of)
 // =======================================================
-func (r *Repository) poolForRegion(region string) *pgxpool.Pool {
-	if r.replicas == nil || len(r.replicas.Pools) == 0 {
-		return r.master.Pool
+
+// Revisions bir makalenin tüm revizyonlarını (yeniden eskiye) bölgenin replikasından okur.
+func (r *Repository) Revisions(ctx context.Context, region string, id int64) ([]model.ArticleRevision, error) {
+	rows, err := r.poolForRegion(region).Query(ctx, `
+		SELECT `+db.RevisionColumns+`
+		FROM article_revisions
+		WHERE article_id=$1
+		ORDER BY revision DESC
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	return collectRevisions(rows)
+}
+
+// ListAsOf her makalenin asOf anındaki son revizyonunu döner; o an silinmiş,
+// henüz oluşturulmamış ya da bölgede yayınlanmamış makaleler dahil edilmez.
+// Etiket / kategori süzgeci revizyonun o anki etiketlerine uygulanır.
+// Yayın kontrolü en geç şimdiye göre yapılır: gelecekteki bir asOf
+// ambargosu dolmamış zamanlanmış makaleleri açmaz.
+func (r *Repository) ListAsOf(ctx context.Context, region string, asOf time.Time, f model.ArticleFilter) ([]model.ArticleRevision, error) {
+	rows, err := r.poolForRegion(region).Query(ctx, `
+		SELECT `+db.RevisionColumns+` FROM (
+			SELECT DISTINCT ON (article_id) *
+			FROM article_revisions
+			WHERE recorded_at <= $1
+			ORDER BY article_id, revision DESC
+		) latest
+		WHERE NOT deleted AND `+db.PublishedSQL("$2", "LEAST($1, NOW())")+`
+			AND (cardinality($3::text[]) = 0 OR tags && $3)
+			AND (cardinality($4::text[]) = 0 OR categories && $4)
+	`, asOf, r.regions.Resolve(region).ID, orNone(f.Tags), orNone(f.Categories))
+	if err != nil {
+		return nil, err
+	}
+	revs, err := collectRevisions(rows)
+	if err != nil {
+		return nil, err
+	}
+	// Güncel listeyle aynı sıralama
+	sort.SliceStable(revs, func(i, j int) bool { return revs[i].CreatedAt.After(revs[j].CreatedAt) })
+	return revs, nil
+}
+
+// GetAsOf makalenin asOf anındaki halini döner.
+func (r *Repository) GetAsOf(ctx context.Context, region string, id int64, asOf time.Time) (model.ArticleRevision, error) {
+	var rev model.ArticleRevision
+	row := r.poolForRegion(region).QueryRow(ctx, `
+		SELECT `+db.RevisionColumns+`
+		FROM article_revisions
+		WHERE article_id=$1 AND recorded_at <= $2
+		ORDER BY revision DESC
+		LIMIT 1
+	`, id, asOf)
+	if err := db.ScanRevision(row, &rev); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return rev, ErrNotFound
+		}
+		return rev, err
+	}
+	// ListAsOf gibi: gelecekteki asOf ile ambargo delinmez
+	at := asOf
+	if now := time.Now(); at.After(now) {
+		at = now
+	}
+	if rev.Deleted || !rev.PublishedIn(r.regions.Resolve(region).ID, at) {
+		return rev, ErrNotFound
+	}
+	return rev, nil
+}
+
+func collectRevisions(rows pgx.Rows) ([]model.ArticleRevision, error) {
+	defer rows.Close()
+	revs := []model.ArticleRevision{}
+	for rows.Next() {
+		var rev model.ArticleRevision
+		if err := db.ScanRevision(rows, &rev); err != nil {
+			return nil, err
+		}
+		revs = append(revs, rev)
+	}
+	return revs, rows.Err()
+}
+
+// =======================================================
+// 🔹 Etiket / kategori sayıları (facets) ve kategoriler
+// =======================================================
+
+// Facets bölgenin replikasında görünen makalelerin etiket ve kategori
+// sayılarını döner. Her grup diğer grubun süzgeciyle sayılır; böylece bir
+// etiket seçiliyken diğer etiketlerin sayıları da görünür kalır.
+func (r *Repository) Facets(ctx context.Context, region, country string, f model.ArticleFilter) (model.ArticleFacets, error) {
+	pool := r.poolForRegion(region)
+	visible := `
+		WITH visible AS (
+			SELECT id FROM articles
+			WHERE deleted_at IS NULL AND ` + db.PublishedSQL("$1", "NOW()") + `
+				AND ` + db.AvailableSQL("$2") + ` AND ` + db.TaxonomySQL("$3", "$4") + `
+		)`
+	regionID := r.regions.Resolve(region).ID
+
+	var res model.ArticleFacets
+	var err error
+	res.Tags, err = collectFacets(pool.Query(ctx, visible+`
+		SELECT t.tag, '', COUNT(*)
+		FROM article_tags t JOIN visible v ON v.id = t.article_id
+		GROUP BY t.tag
+		ORDER BY COUNT(*) DESC, t.tag
+	`, regionID, country, []string{}, orNone(f.Categories)))
+	if err != nil {
+		return res, err
+	}
+	res.Categories, err = collectFacets(pool.Query(ctx, visible+`
+		SELECT ac.category, COALESCE(c.name, ac.category), COUNT(*)
+		FROM article_categories ac
+		JOIN visible v ON v.id = ac.article_id
+		LEFT JOIN categories c ON c.slug = ac.category
+		GROUP BY ac.category, c.name
+		ORDER BY COUNT(*) DESC, ac.category
+	`, regionID, country, orNone(f.Tags), []string{}))
+	return res, err
+}
+
+func collectFacets(rows pgx.Rows, err error) ([]model.Facet, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	facets := []model.Facet{}
+	for rows.Next() {
+		var f model.Facet
+		if err := rows.Scan(&f.Slug, &f.Name, &f.Count); err != nil {
+			return nil, err
+		}
+		facets = append(facets, f)
 	}
+	return facets, rows.Err()
+}
 
-	switch region {
-	case "us":
-		if len(r.replicas.Pools) > 0 {
-			return r.replicas.Pools[0]
+// Categories tüm kategorileri bölgenin replikasından okur.
+func (r *Repository) Categories(ctx context.Context, region string) ([]model.Category, error) {
+	rows, err := r.poolForRegion(region).Query(ctx, `
+		SELECT `+db.CategoryColumns+` FROM categories ORDER BY name, slug`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []model.Category{}
+	for rows.Next() {
+		var c model.Category
+		if err := db.ScanCategory(rows, &c); err != nil {
+			return nil, err
 		}
-	case "asia":
-		if len(r.replicas.Pools) > 1 {
-			return r.replicas.Pools[1]
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+// GetCategoryFromMaster denetim kaydının "önce" hali için kategoriyi master'dan okur.
+func (r *Repository) GetCategoryFromMaster(ctx context.Context, slug string) (model.Category, error) {
+	var c model.Category
+	row := r.master.Pool.QueryRow(ctx, `SELECT `+db.CategoryColumns+` FROM categories WHERE slug=$1`, slug)
+	if err := db.ScanCategory(row, &c); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c, ErrCategoryNotFound
 		}
-	case "sa":
-		if len(r.replicas.Pools) > 2 {
-			return r.replicas.Pools[2]
+		return c, err
+	}
+	return c, nil
+}
+
+// InsertCategoryMaster yeni kategoriyi master'a yazar.
+func (r *Repository) InsertCategoryMaster(ctx context.Context, in model.CategoryInput) (model.Category, error) {
+	var c model.Category
+	row := r.master.Pool.QueryRow(ctx, `
+		INSERT INTO categories (slug, name, description) VALUES ($1, $2, $3)
+		ON CONFLICT (slug) DO NOTHING
+		RETURNING `+db.CategoryColumns, in.Slug, in.Name, in.Description)
+	if err := db.ScanCategory(row, &c); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c, ErrCategoryExists
 		}
-	case "africa":
-		if len(r.replicas.Pools) > 3 {
-			return r.replicas.Pools[3]
+		return c, err
+	}
+	return c, nil
+}
+
+// UpdateCategoryMaster kategorinin adını ve açıklamasını değiştirir (slug sabittir).
+func (r *Repository) UpdateCategoryMaster(ctx context.Context, in model.CategoryInput) (model.Category, error) {
+	var c model.Category
+	row := r.master.Pool.QueryRow(ctx, `
+		UPDATE categories SET name=$2, description=$3, updated_at=NOW()
+		WHERE slug=$1
+		RETURNING `+db.CategoryColumns, in.Slug, in.Name, in.Description)
+	if err := db.ScanCategory(row, &c); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return c, ErrCategoryNotFound
 		}
-	case "eu":
-		fallthrough
-	default:
-		return r.master.Pool // ✅ EU = Master
+		return c, err
 	}
+	return c, nil
+}
+
+// =======================================================
+// 🔹 Toplu içe / dışa aktarma (master)
+// =======================================================
 
-	return r.master.Pool
+// Importer içe aktarılan makaleleri tek bir master transaction'ında ekler.
+// Her satır kendi savepoint'inde yazılır: hatalı satır geri alınır, diğerleri
+// transaction'da kalır. Commit çağrılmazsa hiçbiri kalıcı olmaz.
+type Importer struct {
+	tx       pgx.Tx
+	editorID int64
+	editor   string
+	region   string
+	authors  map[string]int64 // kullanıcı adı → id (0: yok)
+}
+
+// BeginImport içe aktarma transaction'ını açar.
+func (r *Repository) BeginImport(ctx context.Context, editorID int64, editor, region string) (*Importer, error) {
+	tx, err := r.master.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("import: %w", err)
+	}
+	return &Importer{tx: tx, editorID: editorID, editor: editor, region: region, authors: map[string]int64{}}, nil
+}
+
+// AuthorID kullanıcı adını master'daki kullanıcı id'sine çevirir (yoksa 0).
+func (im *Importer) AuthorID(ctx context.Context, username string) (int64, error) {
+	if id, ok := im.authors[username]; ok {
+		return id, nil
+	}
+	var id int64
+	err := im.tx.QueryRow(ctx, `SELECT id FROM users WHERE username=$1`, username).Scan(&id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, err
+	}
+	im.authors[username] = id
+	return id, nil
+}
+
+// Insert bir satırı savepoint içinde ekler; hata olursa yalnızca o satır geri alınır.
+// deletedAt verilen satır çöpe eklenir.
+func (im *Importer) Insert(ctx context.Context, in model.CreateArticleInput, authorID int64, createdAt, deletedAt *time.Time) (model.Article, model.ArticleRevision, error) {
+	sp, err := im.tx.Begin(ctx)
+	if err != nil {
+		return model.Article{}, model.ArticleRevision{}, err
+	}
+	a, rev, err := insertArticle(ctx, sp, in, authorID, createdAt, deletedAt, im.editorID, im.editor, im.region)
+	if err != nil {
+		_ = sp.Rollback(ctx)
+		return model.Article{}, model.ArticleRevision{}, err
+	}
+	return a, rev, sp.Commit(ctx)
+}
+
+// Commit eklenen tüm satırları kalıcı yapar.
+func (im *Importer) Commit(ctx context.Context) error {
+	return im.tx.Commit(ctx)
+}
+
+// Rollback hiçbir satırı yazmadan transaction'ı kapatır (commit'ten sonra etkisizdir).
+func (im *Importer) Rollback(ctx context.Context) error {
+	return im.tx.Rollback(ctx)
+}
+
+// ExportFromMaster makaleleri id sırasıyla master'dan okur ve her birini
+// yazarının kullanıcı adıyla fn'e verir; satırlar belleğe toplanmaz.
+func (r *Repository) ExportFromMaster(ctx context.Context, includeDeleted bool, fn func(a model.Article, username string) error) error {
+	rows, err := r.master.Pool.Query(ctx, `
+		SELECT `+db.ArticleColumns+`, COALESCE((SELECT username FROM users WHERE id = articles.author_id), '')
+		FROM articles
+		WHERE $1 OR deleted_at IS NULL
+		ORDER BY id`, includeDeleted)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a model.Article
+		var username string
+		if err := db.ScanArticleWith(rows, &a, &username); err != nil {
+			return err
+		}
+		if err := fn(a, username); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
 }
 
 // =======================================================
-// 🔹 Yardımcı: Replika sayısı & index eşleştirme
+// 🔹 Replika seçimi (Geo yönlendirme)
 // =======================================================
-func (r *Repository) NumReplicas() int {
-	if r.replicas == nil {
-		return 0
+func (r *Repository) poolForRegion(region string) *pgxpool.Pool {
+	node := r.regions.NodeFor(region)
+	if r.replicas != nil {
+		// Henüz bootstrap edilmemiş, sağlıksız ya da kaldırılmış replikalar okuma almaz
+		if rep, ok := r.replicas.Get(node.Name); ok && rep.Healthy() {
+			return rep.Pool
+		}
+	}
+	return r.master.Pool // Replikası olmayan bölgeler master’dan okur
+}
+
+func (r *Repository) replicaPool(name string) (*pgxpool.Pool, error) {
+	if r.replicas != nil {
+		for _, rep := range r.replicas.All() {
+			if rep.Node.Name == name {
+				return rep.Pool, nil
+			}
+		}
 	}
-	return len(r.replicas.Pools)
+	return nil, fmt.Errorf("unknown replica %q", name)
 }
 
-func (r *Repository) RegionToIndex(region string) int {
-	switch region {
-	case "us":
-		return 0
-	case "asia":
-		return 1
-	case "sa":
-		return 2
-	case "africa":
-		return 3
-	default:
-		return -1
+// =======================================================
+// 🔹 Yardımcı: Güncel replika listesi
+// =======================================================
+func (r *Repository) Replicas() []*db.Replica {
+	if r.replicas == nil {
+		return nil
 	}
+	return r.replicas.All()
+}
+
+// BlobLag node'un blob store'unda henüz olmayan ek sayısını ve en eskisinin
+// bekleme süresini döner (master'daki kopya kayıtlarından).
+func (r *Repository) BlobLag(ctx context.Context, node string) (int, time.Duration, error) {
+	return db.BlobLag(ctx, r.master.Pool, node)
+}
+
+// MasterRegion yazmaların yapıldığı master bölgesini döner.
+func (r *Repository) MasterRegion() string {
+	return r.regions.Master().Region
 }
@@ -0,0 +1,190 @@
+package article
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"geo-repl-demo/internal/auth"
+	"geo-repl-demo/internal/db"
+	"geo-repl-demo/internal/model"
+)
+
+var (
+	// ErrInvalidSchedule geçersiz durum ya da yayın zamanlaması
+	ErrInvalidSchedule = errors.New("invalid publish schedule")
+	// ErrAlreadyPublished yayındaki makale yeniden yayına alınmak istenirse döner
+	ErrAlreadyPublished = errors.New("article is already published")
+)
+
+// Bölge yerel saati biçimleri (publish_local_time)
+var localTimeLayouts = []string{"2006-01-02T15:04", "2006-01-02T15:04:05"}
+
+// resolveSchedule istemcinin durum/zamanlama girdisini doğrular ve
+// kanonik bölge ID'leriyle UTC zamanlara çevirir. cur, güncellenen makaledir
+// (oluşturmada nil); durum verilmezse makalenin mevcut zamanlaması korunur.
+func (s *Service) resolveSchedule(in model.PublishInput, cur *model.Article, now time.Time) (model.PublishInput, error) {
+	status := strings.ToLower(strings.TrimSpace(in.Status))
+	hasTimes := in.PublishAt != nil || len(in.RegionPublishAt) > 0 || in.PublishLocalTime != ""
+	if status == "" {
+		switch {
+		case hasTimes:
+			status = model.StatusScheduled
+		case cur != nil:
+			return model.PublishInput{Status: cur.Status, PublishAt: cur.PublishAt, RegionPublishAt: orEmpty(cur.RegionPublishAt)}, nil
+		default:
+			status = model.StatusPublished
+		}
+	}
+
+	out := model.PublishInput{Status: status, RegionPublishAt: map[string]time.Time{}}
+	switch status {
+	case model.StatusDraft:
+		return out, nil
+	case model.StatusPublished:
+		// Zaten yayındaysa ilk yayın zamanı korunur
+		at := now.UTC()
+		if cur != nil && cur.Status == model.StatusPublished && cur.PublishAt != nil {
+			at = *cur.PublishAt
+		}
+		out.PublishAt = &at
+		return out, nil
+	case model.StatusScheduled:
+	default:
+		return out, fmt.Errorf("%w: unknown status %q", ErrInvalidSchedule, in.Status)
+	}
+
+	if in.PublishLocalTime != "" {
+		for _, reg := range s.repo.regions.Regions() {
+			t, err := parseLocalTime(in.PublishLocalTime, reg.Location())
+			if err != nil {
+				return out, fmt.Errorf("%w: publish_local_time must look like 2006-01-02T09:00", ErrInvalidSchedule)
+			}
+			out.RegionPublishAt[reg.ID] = t.UTC()
+		}
+	}
+	for name, t := range in.RegionPublishAt {
+		reg, ok := s.repo.regions.Lookup(name)
+		if !ok {
+			return out, fmt.Errorf("%w: unknown region %q", ErrInvalidSchedule, name)
+		}
+		out.RegionPublishAt[reg.ID] = t.UTC()
+	}
+
+	// publish_at, bölge zamanı verilmeyen bölgeler için geçerlidir; verilmezse en geç bölge zamanı
+	switch {
+	case in.PublishAt != nil:
+		at := in.PublishAt.UTC()
+		out.PublishAt = &at
+	case len(out.RegionPublishAt) > 0:
+		var latest time.Time
+		for _, t := range out.RegionPublishAt {
+			if t.After(latest) {
+				latest = t
+			}
+		}
+		out.PublishAt = &latest
+	default:
+		return out, fmt.Errorf("%w: scheduled articles need publish_at, region_publish_at or publish_local_time", ErrInvalidSchedule)
+	}
+	return out, nil
+}
+
+func parseLocalTime(v string, loc *time.Location) (time.Time, error) {
+	var err error
+	for _, layout := range localTimeLayouts {
+		var t time.Time
+		if t, err = time.ParseInLocation(layout, v, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+func orEmpty(m map[string]time.Time) map[string]time.Time {
+	if m == nil {
+		return map[string]time.Time{}
+	}
+	return m
+}
+
+// 🔹 Taslak ya da zamanlanmış makaleyi hemen yayına al (yalnızca yazarı veya editör)
+func (s *Service) Publish(ctx context.Context, p auth.Principal, id int64) (*model.Article, error) {
+	before, err := s.authorize(ctx, p, id)
+	if err == nil && before.Status == model.StatusPublished {
+		err = ErrAlreadyPublished
+	}
+	if err != nil {
+		s.record(ctx, p, "article.publish", id, before, nil, err)
+		return nil, err
+	}
+	a, rev, err := s.repo.PublishMaster(ctx, id, true, p.UserID, p.Username)
+	if err != nil {
+		s.record(ctx, p, "article.publish", id, before, before, err)
+		return nil, err
+	}
+	s.record(ctx, p, "article.publish", id, before, &a, nil)
+	s.replicate(a, rev)
+	return &a, nil
+}
+
+// 🔹 Taslaklar ve zamanlanmış makaleler – yazarlar kendi makalelerini, editörler hepsini görür
+func (s *Service) Unpublished(ctx context.Context, p auth.Principal) ([]model.Article, error) {
+	var owner int64
+	if !p.Can(auth.PermArticleEditAny) {
+		if p.UserID == 0 {
+			return nil, ErrNoAuthor
+		}
+		owner = p.UserID
+	}
+	return s.repo.ListUnpublishedFromMaster(ctx, owner)
+}
+
+// RunScheduler zamanlanmış makaleleri periyodik olarak yayına alır.
+// Replikalar bölge zamanı gelen makaleyi kendi saatleriyle zaten gösterir;
+// zamanlayıcı, tüm bölgelerdeki zamanı geçen makaleyi "published" yapar.
+func (s *Service) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := s.PublishDue(ctx); err != nil {
+			log.Printf("⚠️ Zamanlanmış yayın hatası: %v", err)
+		}
+	}
+}
+
+// PublishDue yayın zamanı her bölgede geçmiş makaleleri yayına alır
+func (s *Service) PublishDue(ctx context.Context) error {
+	ids, err := s.repo.DueScheduledMaster(ctx)
+	if err != nil {
+		return err
+	}
+	system := auth.Principal{Username: db.SystemUsername}
+	for _, id := range ids {
+		a, rev, err := s.repo.PublishMaster(ctx, id, false, 0, db.SystemUsername)
+		if errors.Is(err, ErrNotFound) {
+			continue // bu arada yayınlandı, silindi ya da yeniden zamanlandı
+		}
+		if err != nil {
+			return err
+		}
+		log.Printf("📰 Makale %d zamanlandığı gibi yayına alındı", id)
+		if s.audit != nil {
+			e := system.AuditEvent(ctx, "article.publish", "article", strconv.FormatInt(id, 10))
+			e.AfterHash = articleHash(&a)
+			s.audit.Record(ctx, e)
+		}
+		s.replicate(a, rev)
+	}
+	return nil
+}
@@ -2,162 +2,519 @@ package article
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"geo-repl-demo/internal/audit"
+	"geo-repl-demo/internal/auth"
+	"geo-repl-demo/internal/geo"
 	"geo-repl-demo/internal/model"
+	"geo-repl-demo/internal/region"
 	"geo-repl-demo/internal/replication"
 )
 
+var (
+	// ErrNotFound makale yoksa döner
+	ErrNotFound = errors.New("article not found")
+	// ErrGeoBlocked makale istemcinin ülkesinde lisanslı değilse döner (HTTP 451)
+	ErrGeoBlocked = errors.New("article is not available in your country")
+	// ErrInvalidCountry geçersiz ISO ülke kodu
+	ErrInvalidCountry = errors.New("invalid country code")
+	// ErrNotOwner makale başkasına aitse ve çağıranın editör yetkisi yoksa döner
+	ErrNotOwner = errors.New("only the author or an editor may change this article")
+	// ErrNoAuthor token bir kullanıcıya bağlı değilse (statik admin token'ı) döner
+	ErrNoAuthor = errors.New("token is not bound to a user account")
+	// ErrNotDeleted çöpte olmayan makale geri alınmak istenirse döner
+	ErrNotDeleted = errors.New("article is not deleted")
+	// ErrRetentionExpired saklama süresi dolmuş (kalıcı silinecek) makale için döner (HTTP 410)
+	ErrRetentionExpired = errors.New("article can no longer be restored, its retention period has expired")
+)
+
 // Service iş katmanı (Repository + Replicator’ı birleştiriyor)
 type Service struct {
 	repo       *Repository
 	replicator *replication.Replicator
+	audit      audit.Sink
+	retention  time.Duration // silinen makale bu süre boyunca geri alınabilir
 
 	mu               sync.Mutex
-	lastReplicaWrite []time.Time // her replikaya son yazma/silme zamanı (syncing göstermek için)
+	lastReplicaWrite map[string]time.Time // replika adı → son yazma/silme zamanı (syncing göstermek için)
+
+	purgeHooks []PurgeHook
 }
 
 // Yeni servis oluşturur
-func NewService(repo *Repository, replicator *replication.Replicator) *Service {
+func NewService(repo *Repository, replicator *replication.Replicator, sink audit.Sink, retention time.Duration) *Service {
 	return &Service{
 		repo:       repo,
 		replicator: replicator,
+		audit:      sink,
+		retention:  retention,
 	}
 }
 
-// 🔹 Makaleleri bölgeye göre getir
-func (s *Service) ListByRegion(ctx context.Context, region string) ([]model.Article, error) {
-	return s.repo.ListByRegion(ctx, region)
+// 🔹 Makaleleri bölgeye göre getir (istemcinin ülkesinde kısıtlı olanlar hariç)
+func (s *Service) ListByRegion(ctx context.Context, region, country string, f model.ArticleFilter) ([]model.Article, error) {
+	arts, err := s.repo.ListByRegion(ctx, region, f)
+	if err != nil {
+		return nil, err
+	}
+	visible := arts[:0]
+	for _, a := range arts {
+		if a.AvailableIn(country) {
+			visible = append(visible, a)
+		}
+	}
+	return visible, nil
 }
 
-// 🔹 Yeni makale ekle (master’a)
-func (s *Service) Create(ctx context.Context, in model.CreateArticleInput) (*model.Article, error) {
-	// Her zaman EU master’a yazıyoruz
-	a, err := s.repo.InsertMaster(ctx, in, "eu")
+// 🔹 Tek makale – ülke kısıtı varsa ErrGeoBlocked
+func (s *Service) Get(ctx context.Context, region, country string, id int64) (*model.Article, error) {
+	a, err := s.repo.GetByID(ctx, region, id)
 	if err != nil {
 		return nil, err
 	}
+	if !a.AvailableIn(country) {
+		return nil, ErrGeoBlocked
+	}
+	return &a, nil
+}
 
-	// Replikasyon başlat (eventual consistency)
-	if s.replicator != nil {
-		go s.replicator.Schedule(a)
+// 🔹 Revizyon geçmişi – bölgede yayınlanmamış (taslak / zamanı gelmemiş) ve
+// istemcinin ülkesinde yayınlanamayan revizyonlar gizlenir
+func (s *Service) Revisions(ctx context.Context, region, country string, id int64) ([]model.ArticleRevision, error) {
+	revs, err := s.repo.Revisions(ctx, region, id)
+	if err != nil {
+		return nil, err
+	}
+	regionID, now := s.repo.regions.Resolve(region).ID, time.Now()
+	published := revs[:0]
+	for _, rev := range revs {
+		if rev.PublishedIn(regionID, now) {
+			published = append(published, rev)
+		}
 	}
+	if len(published) == 0 {
+		return nil, ErrNotFound
+	}
+	visible := published[:0]
+	for _, rev := range published {
+		if rev.AvailableIn(country) {
+			visible = append(visible, rev)
+		}
+	}
+	if len(visible) == 0 {
+		return nil, ErrGeoBlocked
+	}
+	return visible, nil
+}
 
-	// Replikasyon durumu için kısa süre "syncing" göster
-	s.markReplicasSyncing()
+// 🔹 Kataloğun asOf anındaki hali (olay incelemesi için)
+func (s *Service) ListAsOf(ctx context.Context, region, country string, asOf time.Time, f model.ArticleFilter) ([]model.ArticleRevision, error) {
+	revs, err := s.repo.ListAsOf(ctx, region, asOf, f)
+	if err != nil {
+		return nil, err
+	}
+	visible := revs[:0]
+	for _, rev := range revs {
+		if rev.AvailableIn(country) {
+			visible = append(visible, rev)
+		}
+	}
+	return visible, nil
+}
+
+// 🔹 Tek makalenin asOf anındaki hali
+func (s *Service) GetAsOf(ctx context.Context, region, country string, id int64, asOf time.Time) (*model.ArticleRevision, error) {
+	rev, err := s.repo.GetAsOf(ctx, region, id, asOf)
+	if err != nil {
+		return nil, err
+	}
+	if !rev.AvailableIn(country) {
+		return nil, ErrGeoBlocked
+	}
+	return &rev, nil
+}
+
+// 🔹 Yeni makale ekle (master’a) – yazar, doğrulanmış kullanıcıdır
+func (s *Service) Create(ctx context.Context, p auth.Principal, in model.CreateArticleInput) (*model.Article, error) {
+	if p.UserID == 0 {
+		s.record(ctx, p, "article.create", 0, nil, nil, ErrNoAuthor)
+		return nil, ErrNoAuthor
+	}
+	if err := s.prepareCreate(&in, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// Her zaman master’a (topolojideki master bölgesi) yazıyoruz
+	a, rev, err := s.repo.InsertMaster(ctx, in, p.UserID, p.Username, s.repo.MasterRegion())
+	if err != nil {
+		s.record(ctx, p, "article.create", 0, nil, nil, err)
+		return nil, err
+	}
+	s.record(ctx, p, "article.create", a.ID, nil, &a, nil)
+
+	// Replikasyon başlat (eventual consistency)
+	s.replicate(a, rev)
 
 	return &a, nil
 }
 
-// 🔹 Makale sil – master + tüm replikalardan
-func (s *Service) Delete(ctx context.Context, id int64) error {
-	// Önce master’dan sil
-	if err := s.repo.DeleteFromMaster(ctx, id); err != nil {
+// prepareCreate yeni makale girdisini doğrular ve kanonik hale getirir
+// (oluşturma ve toplu içe aktarma ortak kullanır).
+func (s *Service) prepareCreate(in *model.CreateArticleInput, now time.Time) error {
+	var err error
+	if in.AllowedCountries, err = normalizeCountries(in.AllowedCountries); err != nil {
+		return err
+	}
+	if in.DeniedCountries, err = normalizeCountries(in.DeniedCountries); err != nil {
+		return err
+	}
+	if in.PublishInput, err = s.resolveSchedule(in.PublishInput, nil, now); err != nil {
 		return err
 	}
+	if in.Tags, in.Categories, err = normalizeTaxonomy(in.Tags, in.Categories); err != nil {
+		return err
+	}
+	if in.Locale == "" {
+		in.Locale = s.defaultLocale()
+	}
+	if in.Locale, err = normalizeLocale(in.Locale); err != nil {
+		return err
+	}
+	if in.Translations, err = normalizeTranslations(in.Locale, in.Translations); err != nil {
+		return err
+	}
+	// Markdown yazarken bir kez HTML'e çevrilir; replikalar hazır HTML'i sunar
+	in.ContentHTML, in.TOC = render(in.Title, in.ContentLong, &in.Summary)
+	return nil
+}
 
-	// Tüm replikalardan da sil
-	n := s.repo.NumReplicas()
-	for i := 0; i < n; i++ {
-		_ = s.repo.DeleteFromReplica(ctx, i, id)
+// 🔹 Makale güncelle – yalnızca yazarı veya editör
+func (s *Service) Update(ctx context.Context, p auth.Principal, id int64, in model.UpdateArticleInput) (*model.Article, error) {
+	before, err := s.authorize(ctx, p, id)
+	if err != nil {
+		s.record(ctx, p, "article.update", id, before, nil, err)
+		return nil, err
+	}
+	// Ülke listeleri verilmezse mevcutları korunur (lisans kısıtı sessizce kalkmaz)
+	allowed, err := countriesOrCurrent(in.AllowedCountries, before.AllowedCountries)
+	if err != nil {
+		return nil, err
+	}
+	denied, err := countriesOrCurrent(in.DeniedCountries, before.DeniedCountries)
+	if err != nil {
+		return nil, err
 	}
+	if in.PublishInput, err = s.resolveSchedule(in.PublishInput, before, time.Now()); err != nil {
+		return nil, err
+	}
+	if in.Tags, in.Categories, err = normalizeTaxonomy(in.Tags, in.Categories); err != nil {
+		return nil, err
+	}
+	// Dil ve çeviriler verilmezse mevcutları korunur
+	if in.Locale == "" {
+		in.Locale = before.Locale
+	}
+	if in.Locale, err = normalizeLocale(in.Locale); err != nil {
+		return nil, err
+	}
+	if in.Translations == nil {
+		in.Translations = before.Translations
+	}
+	if in.Translations, err = normalizeTranslations(in.Locale, in.Translations); err != nil {
+		return nil, err
+	}
+	in.ContentHTML, in.TOC = render(in.Title, in.ContentLong, &in.Summary)
 
-	// Silme işlemi de bir “replikasyon olayı” – kısa süre syncing gösterelim
-	s.markReplicasSyncing()
+	a, rev, err := s.repo.UpdateMaster(ctx, id, in.ArticleInput, allowed, denied, p.UserID, p.Username)
+	if err != nil {
+		s.record(ctx, p, "article.update", id, before, nil, err)
+		return nil, err
+	}
+	s.record(ctx, p, "article.update", id, before, &a, nil)
+	s.replicate(a, rev)
+	return &a, nil
+}
 
-	return nil
+// 🔹 Makale sil – çöpe taşır, saklama süresi boyunca geri alınabilir (yalnızca yazarı veya editör)
+func (s *Service) Delete(ctx context.Context, p auth.Principal, id int64) (*model.Article, error) {
+	before, err := s.authorize(ctx, p, id)
+	if err != nil {
+		s.record(ctx, p, "article.delete", id, before, nil, err)
+		return nil, err
+	}
+
+	// Master’da deleted_at işaretlenir; replikalara durum değişikliği olarak gider
+	a, rev, err := s.repo.SoftDeleteMaster(ctx, id, p.UserID, p.Username)
+	if err != nil {
+		s.record(ctx, p, "article.delete", id, before, before, err)
+		return nil, err
+	}
+	s.record(ctx, p, "article.delete", id, before, &a, nil)
+
+	// Silme de bir durum değişikliği olarak replike edilir
+	s.replicate(a, rev)
+
+	return &a, nil
 }
 
-// 🔹 Replikasyon durumu (US/ASIA/SA/TR/AFRICA + syncing/ok)
+// 🔹 Çöpteki makaleyi geri al – saklama süresi dolmadıysa
+func (s *Service) Restore(ctx context.Context, p auth.Principal, id int64) (*model.Article, error) {
+	before, err := s.authorize(ctx, p, id)
+	if err == nil {
+		switch {
+		case before.DeletedAt == nil:
+			err = ErrNotDeleted
+		case time.Since(*before.DeletedAt) > s.retention:
+			err = ErrRetentionExpired
+		}
+	}
+	if err != nil {
+		s.record(ctx, p, "article.restore", id, before, nil, err)
+		return nil, err
+	}
+
+	a, rev, err := s.repo.RestoreMaster(ctx, id, time.Now().Add(-s.retention), p.UserID, p.Username)
+	if errors.Is(err, ErrNotFound) {
+		// Bu arada başka biri geri aldı ya da süre doldu
+		err = ErrRetentionExpired
+	}
+	if err != nil {
+		s.record(ctx, p, "article.restore", id, before, before, err)
+		return nil, err
+	}
+	s.record(ctx, p, "article.restore", id, before, &a, nil)
+	s.replicate(a, rev)
+	return &a, nil
+}
+
+// TrashedArticle çöpteki bir makale ve kalıcı silineceği zamandır.
+type TrashedArticle struct {
+	model.Article
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// 🔹 Çöp kutusu – yazarlar kendi makalelerini, editörler hepsini görür
+func (s *Service) Trash(ctx context.Context, p auth.Principal) ([]TrashedArticle, error) {
+	var owner int64
+	if !p.Can(auth.PermArticleEditAny) {
+		if p.UserID == 0 {
+			return nil, ErrNoAuthor
+		}
+		owner = p.UserID
+	}
+	arts, err := s.repo.ListDeletedFromMaster(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]TrashedArticle, 0, len(arts))
+	for _, a := range arts {
+		out = append(out, TrashedArticle{Article: a, PurgeAt: a.DeletedAt.Add(s.retention)})
+	}
+	return out, nil
+}
+
+// 🔹 Elle tam senkronizasyon (admin)
+func (s *Service) FullSync(ctx context.Context, p auth.Principal) {
+	if s.audit != nil {
+		s.audit.Record(ctx, p.AuditEvent(ctx, "replication.sync", "replication", ""))
+	}
+	if s.replicator != nil {
+		s.replicator.FullSync()
+	}
+	s.markReplicasSyncing()
+}
+
+// 🔹 Replikasyon durumu (topolojideki etiketler + bootstrapping/syncing/ok)
 func (s *Service) ReplicationStatus(ctx context.Context) ([]model.ReplicationStatus, error) {
-	count := s.repo.NumReplicas()
-	regionLabels := []string{"US", "ASIA", "SA", "TR", "AFRICA"} // EU yok, çünkü master
+	replicas := s.repo.Replicas()
 
-	statuses := make([]model.ReplicationStatus, 0, count)
+	statuses := make([]model.ReplicationStatus, 0, len(replicas))
 
 	// lastReplicaWrite için thread-safe snapshot al
 	s.mu.Lock()
-	snapshot := make([]time.Time, len(s.lastReplicaWrite))
-	copy(snapshot, s.lastReplicaWrite)
+	snapshot := make(map[string]time.Time, len(s.lastReplicaWrite))
+	for k, v := range s.lastReplicaWrite {
+		snapshot[k] = v
+	}
 	s.mu.Unlock()
 
 	now := time.Now()
 
-	for i := 0; i < count; i++ {
-		label := fmt.Sprintf("Replica %d", i+1)
-		if i < len(regionLabels) {
-			label = regionLabels[i]
-		}
-
+	for _, rep := range replicas {
 		status := "ok"
-		if i < len(snapshot) && !snapshot[i].IsZero() {
+		if !rep.Ready() {
+			// Topolojiye yeni eklendi, henüz okuma almıyor
+			status = "bootstrapping"
+		} else if last, ok := snapshot[rep.Node.Name]; ok {
 			// Son yazma/silme olayı üzerinden 3 sn’den az geçmişse "syncing"
-			if now.Sub(snapshot[i]) < 3*time.Second {
+			if now.Sub(last) < 3*time.Second {
 				status = "syncing"
 			}
 		}
 
-		statuses = append(statuses, model.ReplicationStatus{
-			Replica: label,
+		st := model.ReplicationStatus{
+			Replica: rep.Node.Label,
 			Status:  status,
 			LastAt:  now,
-		})
+		}
+		// Blob gecikmesi satırlardan ayrı raporlanır: içerik kopyası daha uzun sürebilir
+		pending, lag, err := s.repo.BlobLag(ctx, rep.Node.Name)
+		if err != nil {
+			return nil, err
+		}
+		st.BlobPending = pending
+		st.BlobLagSeconds = lag.Seconds()
+		statuses = append(statuses, st)
 	}
 
 	return statuses, nil
 }
 
+// Gecikme modeli: istemci bölgesinin node'una yakındır; master'a giden istek
+// node'lar arasındaki büyük daire mesafesini fiberde gidip gelir
+const (
+	fiberKmPerMs = 200.0 // ışığın fiberdeki hızı (tek yön)
+	routeStretch = 1.5   // gerçek rotalar büyük daireden uzundur
+)
+
 // ⏱ Master’a göre gecikme kazancı ölçümü (frontend için)
 // NOT: Handler sadece dönen stringi gösteriyor.
+// Değerler topolojideki node koordinatlarından hesaplanır; bilinmeyen bölge
+// yönlendirmede olduğu gibi varsayılan bölgeye düşer.
 func (s *Service) MeasureLatency(region string) (string, string) {
-	rand.Seed(time.Now().UnixNano())
-	r := strings.ToLower(region)
-
-	var latencyRegion, latencyMaster int
-
-	switch r {
-	case "eu":
-		// EU: master ile aynı – kazanç yok, ikisi de aynı değer
-		base := rand.Intn(20) + 25 // 25–44 ms
-		latencyRegion = base
-		latencyMaster = base
-	case "us":
-		// US: master (EU) uzak, replikaya yakın
-		latencyRegion = rand.Intn(40) + 40  // 40–79 ms (US replikası)
-		latencyMaster = rand.Intn(80) + 160 // 160–239 ms (EU master)
-	case "asia":
-		// ASIA: master daha uzak
-		latencyRegion = rand.Intn(50) + 50  // 50–99 ms
-		latencyMaster = rand.Intn(90) + 180 // 180–269 ms
-	case "sa":
-		// South America
-		latencyRegion = rand.Intn(45) + 45  // 45–89 ms
-		latencyMaster = rand.Intn(90) + 170 // 170–259 ms
-	case "africa", "tr":
-		// TR ve AFRICA – master’a görece uzak, kendi replikası daha yakın
-		latencyRegion = rand.Intn(35) + 35  // 35–69 ms
-		latencyMaster = rand.Intn(80) + 150 // 150–229 ms
-	default:
-		// Bilinmeyen bölge -> hafif fark
-		latencyRegion = rand.Intn(60) + 60  // 60–119 ms
-		latencyMaster = rand.Intn(40) + 120 // 120–159 ms
-	}
-
-	diff := latencyMaster - latencyRegion
-	if diff < 0 {
-		diff = 0
+	regions := s.repo.regions
+	reg := regions.Resolve(region) // alias → kanonik bölge
+	node := regions.NodeFor(reg.ID)
+	master := regions.Master()
+
+	// İstemci → kendi bölgesinin node'u; master aynı node ise kazanç yok
+	base := rand.Intn(20) + 25 // 25–44 ms
+	latencyRegion, latencyMaster := base, base
+	if node.Name != master.Name {
+		km := geo.DistanceKm(node.Point(), master.Point())
+		rtt := int(2 * routeStretch * km / fiberKmPerMs)
+		latencyMaster += rtt + rand.Intn(rtt/10+1) // %10'a kadar jitter
 	}
 
 	result := fmt.Sprintf(
 		"⏱ Master’a göre gecikme kazancı: %d ms (%s=%d ms, Master=%d ms)",
-		diff, strings.ToUpper(r), latencyRegion, latencyMaster,
+		latencyMaster-latencyRegion, strings.ToUpper(reg.ID), latencyRegion, latencyMaster,
 	)
 
-	return r, result
+	return reg.ID, result
+}
+
+// 🔹 Düzenleme yetkisi olan makale (ek dosyası yükleme gibi makaleye bağlı yazmalar için)
+// Çöpteki makale ErrNotFound döner.
+func (s *Service) Editable(ctx context.Context, p auth.Principal, id int64) (*model.Article, error) {
+	a, err := s.authorize(ctx, p, id)
+	if err != nil {
+		return nil, err
+	}
+	if a.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	return a, nil
+}
+
+// ------------------------------------------------------
+//  Yardımcı: Sahiplik kontrolü (master’daki güncel kayıt üzerinden)
+// ------------------------------------------------------
+// Dönen makale, denetim kaydının "önce" hali için kullanılır.
+func (s *Service) authorize(ctx context.Context, p auth.Principal, id int64) (*model.Article, error) {
+	a, err := s.repo.GetFromMaster(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.Can(auth.PermArticleEditAny) {
+		return &a, nil
+	}
+	if p.UserID == 0 || a.AuthorID != p.UserID {
+		return &a, ErrNotOwner
+	}
+	return &a, nil
+}
+
+// ------------------------------------------------------
+//  Yardımcı: Denetim kaydı (önce/sonra hash'leri ile)
+// ------------------------------------------------------
+func (s *Service) record(ctx context.Context, p auth.Principal, action string, id int64, before, after *model.Article, err error) {
+	if s.audit == nil {
+		return
+	}
+	target := ""
+	if id != 0 {
+		target = strconv.FormatInt(id, 10)
+	}
+	e := p.AuditEvent(ctx, action, "article", target)
+	e.BeforeHash = articleHash(before)
+	e.AfterHash = articleHash(after)
+	if err != nil {
+		// Yetki hataları "denied", diğerleri "failed"
+		e.Outcome = audit.Failed
+		if errors.Is(err, ErrNotOwner) || errors.Is(err, ErrNoAuthor) {
+			e.Outcome = audit.Denied
+		}
+		if e.Detail == nil {
+			e.Detail = map[string]string{}
+		}
+		e.Detail["error"] = err.Error()
+	}
+	s.audit.Record(ctx, e)
+}
+
+func articleHash(a *model.Article) string {
+	if a == nil {
+		return ""
+	}
+	return audit.Hash(a)
+}
+
+// ------------------------------------------------------
+//  Yardımcı: Ülke listesini doğrula (büyük harf, tekrarsız)
+// ------------------------------------------------------
+func normalizeCountries(codes []string) ([]string, error) {
+	out := make([]string, 0, len(codes))
+	seen := map[string]bool{}
+	for _, code := range codes {
+		c, ok := region.LookupCountry(code)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCountry, code)
+		}
+		if !seen[c.Code] {
+			seen[c.Code] = true
+			out = append(out, c.Code)
+		}
+	}
+	return out, nil
+}
+
+// countriesOrCurrent güncellemedeki ülke listesini doğrular; nil (alan
+// gönderilmemiş) mevcut listeyi korur, [] temizler.
+func countriesOrCurrent(codes *[]string, current []string) ([]string, error) {
+	if codes == nil {
+		return append([]string{}, current...), nil
+	}
+	return normalizeCountries(*codes)
+}
+
+// ------------------------------------------------------
+//  Yardımcı: Makaleyi ve yeni revizyonunu replikalara gönder
+// ------------------------------------------------------
+func (s *Service) replicate(a model.Article, rev model.ArticleRevision) {
+	if s.replicator != nil {
+		go s.replicator.Schedule(a)
+		go s.replicator.ScheduleRevision(rev)
+	}
+	// Replikasyon durumu için kısa süre "syncing" göster
+	s.markReplicasSyncing()
 }
 
 // ------------------------------------------------------
@@ -167,13 +524,12 @@ func (s *Service) markReplicasSyncing() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	count := s.repo.NumReplicas()
-	if len(s.lastReplicaWrite) < count {
-		s.lastReplicaWrite = make([]time.Time, count)
+	if s.lastReplicaWrite == nil {
+		s.lastReplicaWrite = map[string]time.Time{}
 	}
 
 	now := time.Now()
-	for i := 0; i < count; i++ {
-		s.lastReplicaWrite[i] = now
+	for _, rep := range s.repo.Replicas() {
+		s.lastReplicaWrite[rep.Node.Name] = now
 	}
 }
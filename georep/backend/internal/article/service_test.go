@@ -0,0 +1,85 @@
+package article
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"geo-repl-demo/internal/config"
+	"geo-repl-demo/internal/model"
+	"geo-repl-demo/internal/region"
+)
+
+func TestUpdateKeepsOmittedCountries(t *testing.T) {
+	current := []string{"TR", "DE"}
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{`{"title":"t","content_long":"c"}`, []string{"TR", "DE"}},
+		{`{"title":"t","content_long":"c","allowed_countries":null}`, []string{"TR", "DE"}},
+		{`{"title":"t","content_long":"c","allowed_countries":[]}`, []string{}},
+		{`{"title":"t","content_long":"c","allowed_countries":["us","US"]}`, []string{"US"}},
+	}
+	for _, tt := range tests {
+		var in model.UpdateArticleInput
+		if err := json.Unmarshal([]byte(tt.body), &in); err != nil {
+			t.Fatal(err)
+		}
+		got, err := countriesOrCurrent(in.AllowedCountries, current)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.body, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: countries = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+	if _, err := countriesOrCurrent(&[]string{"XX"}, current); err == nil {
+		t.Error("unknown country accepted")
+	}
+}
+
+func TestMeasureLatencyFollowsTopology(t *testing.T) {
+	reg, err := region.New(config.Topology{
+		Master: config.Node{Name: "master", Region: "eu", Lat: 50.11, Lon: 8.68},
+		Replicas: []config.Node{
+			{Name: "replica1", Region: "us", Lat: 39.04, Lon: -77.49},
+			{Name: "replica2", Region: "asia", Lat: 1.35, Lon: 103.82},
+			{Name: "replica3", Region: "tr", Lat: 41.01, Lon: 28.98},
+			{Name: "replica4", Region: "sa", Lat: -23.55, Lon: -46.63},
+			{Name: "replica5", Region: "africa", Lat: -33.92, Lon: 18.42},
+		},
+		DefaultRegion: "us",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Service{repo: NewRepository(nil, nil, reg)}
+
+	// Kazanç master ile bölge node'u arasındaki mesafeden gelir:
+	// Frankfurt → Virginia ≈ 6500 km → 2 × 1.5 × 6500 / 200 ≈ 97 ms (+%10'a kadar jitter)
+	tests := []struct {
+		region   string
+		want     string
+		min, max int
+	}{
+		{"eu", "eu", 0, 0},
+		{"US", "us", 90, 110},
+		{"tr", "tr", 27, 31},
+		{"mars", "us", 90, 110},
+	}
+	for _, tt := range tests {
+		got, result := s.MeasureLatency(tt.region)
+		if got != tt.want {
+			t.Errorf("%s: region = %q, want %q", tt.region, got, tt.want)
+		}
+		var gain int
+		if _, err := fmt.Sscanf(result, "⏱ Master’a göre gecikme kazancı: %d ms", &gain); err != nil {
+			t.Fatalf("%s: %q: %v", tt.region, result, err)
+		}
+		if gain < tt.min || gain > tt.max {
+			t.Errorf("%s: gain = %d ms, want %d–%d (%s)", tt.region, gain, tt.min, tt.max, result)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package article
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"geo-repl-demo/internal/audit"
+	"geo-repl-demo/internal/auth"
+	"geo-repl-demo/internal/model"
+)
+
+var (
+	// ErrInvalidTaxonomy geçersiz etiket ya da kategori adı
+	ErrInvalidTaxonomy = errors.New("invalid tag or category")
+	// ErrUnknownCategory makaleye tanımlanmamış bir kategori verilirse döner
+	ErrUnknownCategory = errors.New("unknown category")
+	// ErrCategoryExists aynı slug ile ikinci kategori oluşturulmak istenirse döner
+	ErrCategoryExists = errors.New("category already exists")
+	// ErrCategoryNotFound kategori yoksa döner
+	ErrCategoryNotFound = errors.New("category not found")
+)
+
+// Etiket / kategori sınırları
+const (
+	maxSlugLen        = 40
+	maxTags           = 10
+	maxCategories     = 3
+	maxCategoryName   = 64
+	maxCategoryDetail = 500
+)
+
+// 🔹 Bölgedeki etiket ve kategori sayıları (istemcinin ülkesinde kısıtlı makaleler hariç)
+func (s *Service) Facets(ctx context.Context, region, country string, f model.ArticleFilter) (model.ArticleFacets, error) {
+	return s.repo.Facets(ctx, region, country, f)
+}
+
+// 🔹 Kategoriler (bölgenin replikasından)
+func (s *Service) Categories(ctx context.Context, region string) ([]model.Category, error) {
+	return s.repo.Categories(ctx, region)
+}
+
+// 🔹 Yeni kategori (editör) – slug verilmezse addan türetilir
+func (s *Service) CreateCategory(ctx context.Context, p auth.Principal, in model.CategoryInput) (*model.Category, error) {
+	if in.Slug == "" {
+		in.Slug = in.Name
+	}
+	in, err := normalizeCategory(in)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.repo.InsertCategoryMaster(ctx, in)
+	if err != nil {
+		s.recordCategory(ctx, p, "category.create", in.Slug, nil, nil, err)
+		return nil, err
+	}
+	s.recordCategory(ctx, p, "category.create", c.Slug, nil, &c, nil)
+	s.replicateCategory(c)
+	return &c, nil
+}
+
+// 🔹 Kategoriyi yeniden adlandır (editör) – slug ve makale bağlantıları değişmez
+func (s *Service) UpdateCategory(ctx context.Context, p auth.Principal, slug string, in model.CategoryInput) (*model.Category, error) {
+	in.Slug = slug
+	in, err := normalizeCategory(in)
+	if err != nil {
+		return nil, err
+	}
+	before, err := s.repo.GetCategoryFromMaster(ctx, in.Slug)
+	if err != nil {
+		s.recordCategory(ctx, p, "category.update", in.Slug, nil, nil, err)
+		return nil, err
+	}
+	c, err := s.repo.UpdateCategoryMaster(ctx, in)
+	if err != nil {
+		s.recordCategory(ctx, p, "category.update", in.Slug, &before, nil, err)
+		return nil, err
+	}
+	s.recordCategory(ctx, p, "category.update", c.Slug, &before, &c, nil)
+	s.replicateCategory(c)
+	return &c, nil
+}
+
+func (s *Service) replicateCategory(c model.Category) {
+	if s.replicator != nil {
+		go s.replicator.ScheduleCategory(c)
+	}
+	s.markReplicasSyncing()
+}
+
+func (s *Service) recordCategory(ctx context.Context, p auth.Principal, action, slug string, before, after *model.Category, err error) {
+	if s.audit == nil {
+		return
+	}
+	e := p.AuditEvent(ctx, action, "category", slug)
+	if before != nil {
+		e.BeforeHash = audit.Hash(before)
+	}
+	if after != nil {
+		e.AfterHash = audit.Hash(after)
+	}
+	if err != nil {
+		e.Outcome = audit.Failed
+		e.Detail = map[string]string{"error": err.Error()}
+	}
+	s.audit.Record(ctx, e)
+}
+
+// ------------------------------------------------------
+//  Yardımcı: Etiket / kategori doğrulama
+// ------------------------------------------------------
+
+// normalizeTaxonomy makale girdisindeki etiket ve kategorileri slug'a
+// çevirir; sonuç tekrarsız ve sıralıdır.
+func normalizeTaxonomy(tags, categories []string) ([]string, []string, error) {
+	tags, err := normalizeSlugs(tags)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tags) > maxTags {
+		return nil, nil, fmt.Errorf("%w: at most %d tags", ErrInvalidTaxonomy, maxTags)
+	}
+	categories, err = normalizeSlugs(categories)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(categories) > maxCategories {
+		return nil, nil, fmt.Errorf("%w: at most %d categories", ErrInvalidTaxonomy, maxCategories)
+	}
+	return tags, categories, nil
+}
+
+func normalizeCategory(in model.CategoryInput) (model.CategoryInput, error) {
+	slug, err := slugify(in.Slug)
+	if err != nil {
+		return in, err
+	}
+	in.Slug = slug
+	in.Name = strings.TrimSpace(in.Name)
+	in.Description = strings.TrimSpace(in.Description)
+	if in.Name == "" || utf8.RuneCountInString(in.Name) > maxCategoryName {
+		return in, fmt.Errorf("%w: name must be 1-%d characters", ErrInvalidTaxonomy, maxCategoryName)
+	}
+	if utf8.RuneCountInString(in.Description) > maxCategoryDetail {
+		return in, fmt.Errorf("%w: description must be at most %d characters", ErrInvalidTaxonomy, maxCategoryDetail)
+	}
+	return in, nil
+}
+
+func normalizeSlugs(values []string) ([]string, error) {
+	out := make([]string, 0, len(values))
+	seen := map[string]bool{}
+	for _, v := range values {
+		slug, err := slugify(v)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[slug] {
+			seen[slug] = true
+			out = append(out, slug)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// slugify "Geo Replication" → "geo-replication". Harfler (Türkçe dahil) ve
+// rakamlar korunur; boşluk ve alt çizgi tireye dönüşür, diğerleri atılır.
+func slugify(v string) (string, error) {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(v)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		case r == '-' || r == '_' || unicode.IsSpace(r):
+			dash = true
+		}
+	}
+	slug := b.String()
+	if slug == "" || utf8.RuneCountInString(slug) > maxSlugLen {
+		return "", fmt.Errorf("%w: %q must have 1-%d letters or digits", ErrInvalidTaxonomy, v, maxSlugLen)
+	}
+	return slug, nil
+}
+
+// missingSlugs want içinde olup have içinde olmayanları döner.
+func missingSlugs(want, have []string) []string {
+	known := map[string]bool{}
+	for _, h := range have {
+		known[h] = true
+	}
+	var missing []string
+	for _, w := range want {
+		if !known[w] {
+			missing = append(missing, w)
+		}
+	}
+	return missing
+}
+
+// orNone nil listeyi boş listeye çevirir (SQL'de NULL yerine '{}' gitsin)
+func orNone(slugs []string) []string {
+	if slugs == nil {
+		return []string{}
+	}
+	return slugs
+}
@@ -0,0 +1,538 @@
+package article
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"geo-repl-demo/internal/auth"
+	"geo-repl-demo/internal/model"
+)
+
+var (
+	// ErrInvalidFormat desteklenmeyen dosya biçimi ya da okunamayan CSV başlığı
+	ErrInvalidFormat = errors.New("invalid import format")
+	// ErrInvalidRecord içe aktarılan satır geçersizse döner (satır raporuna yazılır)
+	ErrInvalidRecord = errors.New("invalid record")
+	// ErrImportRejected en az bir satır hatalı olduğu için hiçbir satır yazılmadığında denetim kaydına düşer
+	ErrImportRejected = errors.New("import rejected, no rows were written")
+)
+
+// Toplu aktarma biçimleri
+const (
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// maxImportRows tek içe aktarmadaki satır sınırı (hepsi tek transaction'da)
+const maxImportRows = 10000
+
+// csvColumns dışa aktarılan CSV'nin sütunları; içe aktarmada sıra serbesttir,
+// title ve content_long zorunludur. Listeler "|" ile ayrılır, haritalar JSON'dur.
+var csvColumns = []string{
+	"id", "title", "summary", "content_long", "author", "region", "locale", "status",
+	"publish_at", "region_publish_at", "allowed_countries", "denied_countries",
+	"tags", "categories", "translations", "created_at", "updated_at", "deleted_at",
+}
+
+// RecordReader içe aktarılan dosyadan satır okur. Satıra özgü hatalar
+// (*RowError) okumayı durdurmaz; diğer hatalar dosyanın tamamını geçersiz kılar.
+// Dosya bitince io.EOF döner.
+type RecordReader interface {
+	Read() (model.ArticleRecord, error)
+}
+
+// RecordWriter dışa aktarılan satırları yazar.
+type RecordWriter interface {
+	Write(model.ArticleRecord) error
+	Flush() error
+}
+
+// RowError tek bir satırın okunamadığını bildirir.
+type RowError struct {
+	Err error
+}
+
+func (e *RowError) Error() string { return e.Err.Error() }
+func (e *RowError) Unwrap() error { return e.Err }
+
+// NewRecordReader biçime göre satır okuyucu döner.
+func NewRecordReader(format string, r io.Reader) (RecordReader, error) {
+	switch format {
+	case FormatNDJSON:
+		return &ndjsonReader{r: bufio.NewReader(r)}, nil
+	case FormatCSV:
+		return newCSVReader(r)
+	default:
+		return nil, fmt.Errorf("%w: %q, use ndjson or csv", ErrInvalidFormat, format)
+	}
+}
+
+// NewRecordWriter biçime göre satır yazıcı döner.
+func NewRecordWriter(format string, w io.Writer) (RecordWriter, error) {
+	switch format {
+	case FormatNDJSON:
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		return ndjsonWriter{enc: enc}, nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvColumns); err != nil {
+			return nil, err
+		}
+		return csvWriter{w: cw}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q, use ndjson or csv", ErrInvalidFormat, format)
+	}
+}
+
+// ------------------------------------------------------
+//  NDJSON: satır başına bir JSON nesnesi, boş satırlar atlanır
+// ------------------------------------------------------
+
+type ndjsonReader struct {
+	r *bufio.Reader
+}
+
+func (n *ndjsonReader) Read() (model.ArticleRecord, error) {
+	for {
+		line, err := n.r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err != nil {
+				return model.ArticleRecord{}, err
+			}
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return model.ArticleRecord{}, err
+		}
+		var rec model.ArticleRecord
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&rec); err != nil {
+			return model.ArticleRecord{}, &RowError{Err: err}
+		}
+		return rec, nil
+	}
+}
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (n ndjsonWriter) Write(rec model.ArticleRecord) error { return n.enc.Encode(rec) }
+func (n ndjsonWriter) Flush() error                        { return nil }
+
+// ------------------------------------------------------
+//  CSV: ilk satır başlık
+// ------------------------------------------------------
+
+type csvReader struct {
+	r    *csv.Reader
+	cols []string
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: csv header: %v", ErrInvalidFormat, err)
+	}
+	known := map[string]bool{}
+	for _, col := range csvColumns {
+		known[col] = true
+	}
+	seen := map[string]bool{}
+	for i, col := range header {
+		col = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(col, "\ufeff")))
+		if !known[col] || seen[col] {
+			return nil, fmt.Errorf("%w: unknown or repeated csv column %q", ErrInvalidFormat, col)
+		}
+		seen[col] = true
+		header[i] = col
+	}
+	if !seen["title"] || !seen["content_long"] {
+		return nil, fmt.Errorf("%w: csv needs title and content_long columns", ErrInvalidFormat)
+	}
+	return &csvReader{r: cr, cols: header}, nil
+}
+
+func (c *csvReader) Read() (model.ArticleRecord, error) {
+	fields, err := c.r.Read()
+	if err != nil {
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			return model.ArticleRecord{}, &RowError{Err: err}
+		}
+		return model.ArticleRecord{}, err
+	}
+	var rec model.ArticleRecord
+	for i, v := range fields {
+		if err := setCSVField(&rec, c.cols[i], csvUnescape(v)); err != nil {
+			return model.ArticleRecord{}, &RowError{Err: fmt.Errorf("%s: %w", c.cols[i], err)}
+		}
+	}
+	return rec, nil
+}
+
+func setCSVField(rec *model.ArticleRecord, col, v string) error {
+	var err error
+	switch col {
+	case "id":
+		if v != "" {
+			rec.ID, err = strconv.ParseInt(v, 10, 64)
+		}
+	case "title":
+		rec.Title = v
+	case "summary":
+		rec.Summary = v
+	case "content_long":
+		rec.ContentLong = v
+	case "author":
+		rec.Author = v
+	case "region":
+		rec.Region = v
+	case "locale":
+		rec.Locale = v
+	case "status":
+		rec.Status = v
+	case "publish_at":
+		rec.PublishAt, err = csvTime(v)
+	case "region_publish_at":
+		err = csvJSON(v, &rec.RegionPublishAt)
+	case "allowed_countries":
+		rec.AllowedCountries = csvList(v)
+	case "denied_countries":
+		rec.DeniedCountries = csvList(v)
+	case "tags":
+		rec.Tags = csvList(v)
+	case "categories":
+		rec.Categories = csvList(v)
+	case "translations":
+		err = csvJSON(v, &rec.Translations)
+	case "created_at":
+		rec.CreatedAt, err = csvTime(v)
+	case "updated_at":
+		rec.UpdatedAt, err = csvTime(v)
+	case "deleted_at":
+		rec.DeletedAt, err = csvTime(v)
+	}
+	return err
+}
+
+func csvList(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, "|") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func csvTime(v string) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, errors.New("must be an RFC 3339 timestamp")
+	}
+	return &t, nil
+}
+
+func csvJSON(v string, dst any) error {
+	if strings.TrimSpace(v) == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(v), dst)
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c csvWriter) Write(rec model.ArticleRecord) error {
+	row := make([]string, 0, len(csvColumns))
+	for _, col := range csvColumns {
+		row = append(row, csvEscape(csvField(rec, col)))
+	}
+	return c.w.Write(row)
+}
+
+func (c csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func csvField(rec model.ArticleRecord, col string) string {
+	switch col {
+	case "id":
+		return strconv.FormatInt(rec.ID, 10)
+	case "title":
+		return rec.Title
+	case "summary":
+		return rec.Summary
+	case "content_long":
+		return rec.ContentLong
+	case "author":
+		return rec.Author
+	case "region":
+		return rec.Region
+	case "locale":
+		return rec.Locale
+	case "status":
+		return rec.Status
+	case "publish_at":
+		return csvTimeString(rec.PublishAt)
+	case "region_publish_at":
+		return csvJSONString(rec.RegionPublishAt, len(rec.RegionPublishAt))
+	case "allowed_countries":
+		return strings.Join(rec.AllowedCountries, "|")
+	case "denied_countries":
+		return strings.Join(rec.DeniedCountries, "|")
+	case "tags":
+		return strings.Join(rec.Tags, "|")
+	case "categories":
+		return strings.Join(rec.Categories, "|")
+	case "translations":
+		return csvJSONString(rec.Translations, len(rec.Translations))
+	case "created_at":
+		return csvTimeString(rec.CreatedAt)
+	case "updated_at":
+		return csvTimeString(rec.UpdatedAt)
+	case "deleted_at":
+		return csvTimeString(rec.DeletedAt)
+	}
+	return ""
+}
+
+// csvFormulaStart tablo programlarının hücreyi formül olarak yorumladığı ilk karakterler
+const csvFormulaStart = "=+-@\t\r"
+
+// csvEscape formül gibi başlayan hücrenin başına ' ekler (CSV injection).
+// Zaten ' ile kaçırılmış gibi görünen değer de bir ' daha alır; böylece
+// csvUnescape her değeri aynen geri verir.
+func csvEscape(v string) string {
+	if rest := strings.TrimLeft(v, "'"); rest != "" && strings.ContainsRune(csvFormulaStart, rune(rest[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+// csvUnescape csvEscape'in başa eklediği tırnağı kaldırır; diğer değerlere dokunmaz.
+func csvUnescape(v string) string {
+	if strings.HasPrefix(v, "'") {
+		if rest := strings.TrimLeft(v, "'"); rest != "" && strings.ContainsRune(csvFormulaStart, rune(rest[0])) {
+			return v[1:]
+		}
+	}
+	return v
+}
+
+func csvTimeString(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func csvJSONString(v any, n int) string {
+	if n == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// ------------------------------------------------------
+//  İçe / dışa aktarma servisi
+// ------------------------------------------------------
+
+// 🔹 Toplu içe aktarma (admin): tüm satırlar master'da tek transaction'da
+// yazılır; tek bir satır bile hatalıysa (ya da dryRun ise) hiçbiri yazılmaz.
+// Satırlar tek tek doğrulanır ve eklenir, dosya belleğe alınmaz. Başarılı
+// içe aktarma normal replikasyonla bölgelere dağıtılır.
+func (s *Service) Import(ctx context.Context, p auth.Principal, rr RecordReader, dryRun bool) (model.ImportReport, error) {
+	report := model.ImportReport{DryRun: dryRun, Rows: []model.ImportRow{}}
+
+	im, err := s.repo.BeginImport(ctx, p.UserID, p.Username, s.repo.MasterRegion())
+	if err != nil {
+		return report, err
+	}
+	defer im.Rollback(ctx)
+
+	var arts []model.Article
+	var revs []model.ArticleRevision
+	now := time.Now()
+	for {
+		rec, err := rr.Read()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *RowError
+		if err != nil && !errors.As(err, &rowErr) {
+			return report, err
+		}
+
+		report.Total++
+		row := model.ImportRow{Row: report.Total, SourceID: rec.ID}
+		if report.Total > maxImportRows {
+			row.Error = fmt.Sprintf("too many rows, at most %d per import", maxImportRows)
+			report.Rows = append(report.Rows, row)
+			report.Failed++
+			break
+		}
+		if err == nil {
+			var a model.Article
+			var rev model.ArticleRevision
+			if a, rev, err = s.importRecord(ctx, p, im, rec, now); err == nil {
+				arts, revs = append(arts, a), append(revs, rev)
+			}
+		}
+		if err != nil {
+			row.Error = err.Error()
+			report.Failed++
+		}
+		report.Rows = append(report.Rows, row)
+	}
+	report.Imported = len(arts)
+
+	if report.Failed > 0 {
+		report.Imported = 0
+		if !dryRun {
+			s.record(ctx, p, "article.import", 0, nil, nil, ErrImportRejected)
+		}
+		return report, nil
+	}
+	if dryRun {
+		return report, nil
+	}
+	if err := im.Commit(ctx); err != nil {
+		return report, fmt.Errorf("import: %w", err)
+	}
+	report.Committed = true
+
+	// Kimlikler ancak commit'ten sonra kalıcıdır
+	for i := range arts {
+		report.Rows[i].ArticleID = arts[i].ID
+		s.record(ctx, p, "article.import", arts[i].ID, nil, &arts[i], nil)
+	}
+	if s.replicator != nil {
+		s.replicator.ScheduleArticles(arts, revs)
+	}
+	s.markReplicasSyncing()
+	return report, nil
+}
+
+// importRecord tek satırı doğrular ve içe aktarma transaction'ına ekler.
+func (s *Service) importRecord(ctx context.Context, p auth.Principal, im *Importer, rec model.ArticleRecord, now time.Time) (model.Article, model.ArticleRevision, error) {
+	in := model.CreateArticleInput{
+		ArticleInput: model.ArticleInput{
+			Title:        strings.TrimSpace(rec.Title),
+			Summary:      rec.Summary,
+			ContentLong:  rec.ContentLong,
+			Tags:         rec.Tags,
+			Categories:   rec.Categories,
+			Locale:       rec.Locale,
+			Translations: rec.Translations,
+			PublishInput: model.PublishInput{
+				Status:          rec.Status,
+				PublishAt:       rec.PublishAt,
+				RegionPublishAt: rec.RegionPublishAt,
+			},
+		},
+		AllowedCountries: rec.AllowedCountries,
+		DeniedCountries:  rec.DeniedCountries,
+	}
+	if in.Title == "" || strings.TrimSpace(in.ContentLong) == "" {
+		return model.Article{}, model.ArticleRevision{}, fmt.Errorf("%w: title and content_long are required", ErrInvalidRecord)
+	}
+	// Silinmiş satır çöpe eklenir; çöp süresi deleted_at'ten sayılır
+	if d := rec.DeletedAt; d != nil {
+		if d.After(now) {
+			return model.Article{}, model.ArticleRevision{}, fmt.Errorf("%w: deleted_at is in the future", ErrInvalidRecord)
+		}
+		if rec.CreatedAt != nil && d.Before(*rec.CreatedAt) {
+			return model.Article{}, model.ArticleRevision{}, fmt.Errorf("%w: deleted_at is before created_at", ErrInvalidRecord)
+		}
+	}
+
+	authorID := p.UserID
+	if rec.Author != "" {
+		id, err := im.AuthorID(ctx, rec.Author)
+		if err != nil {
+			return model.Article{}, model.ArticleRevision{}, err
+		}
+		if id == 0 {
+			return model.Article{}, model.ArticleRevision{}, fmt.Errorf("%w: unknown author %q", ErrInvalidRecord, rec.Author)
+		}
+		authorID = id
+	}
+	if authorID == 0 {
+		return model.Article{}, model.ArticleRevision{}, fmt.Errorf("%w: author is required when importing with a token that is not bound to a user", ErrInvalidRecord)
+	}
+
+	if err := s.prepareCreate(&in, now); err != nil {
+		return model.Article{}, model.ArticleRevision{}, err
+	}
+	// Yayındaki makalenin asıl yayın zamanı korunur
+	if in.Status == model.StatusPublished && rec.PublishAt != nil {
+		at := rec.PublishAt.UTC()
+		in.PublishAt = &at
+	}
+	return im.Insert(ctx, in, authorID, rec.CreatedAt, rec.DeletedAt)
+}
+
+// 🔹 Toplu dışa aktarma (admin): master'daki makaleler id sırasıyla akıtılır.
+// Çevirilerin üretilmiş HTML'i yazılmaz; içe aktarmada yeniden üretilir.
+func (s *Service) Export(ctx context.Context, p auth.Principal, w RecordWriter, includeDeleted bool) (int, error) {
+	n := 0
+	err := s.repo.ExportFromMaster(ctx, includeDeleted, func(a model.Article, username string) error {
+		n++
+		return w.Write(exportRecord(a, username))
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	s.record(ctx, p, "article.export", 0, nil, nil, err)
+	return n, err
+}
+
+func exportRecord(a model.Article, username string) model.ArticleRecord {
+	created, updated := a.CreatedAt, a.UpdatedAt
+	rec := model.ArticleRecord{
+		ID:               a.ID,
+		Title:            a.Title,
+		Summary:          a.Summary,
+		ContentLong:      a.ContentLong,
+		Author:           username,
+		Region:           a.Region,
+		Locale:           a.Locale,
+		AllowedCountries: a.AllowedCountries,
+		DeniedCountries:  a.DeniedCountries,
+		Tags:             a.Tags,
+		Categories:       a.Categories,
+		Status:           a.Status,
+		PublishAt:        a.PublishAt,
+		RegionPublishAt:  a.RegionPublishAt,
+		CreatedAt:        &created,
+		UpdatedAt:        &updated,
+		DeletedAt:        a.DeletedAt,
+	}
+	if len(a.Translations) > 0 {
+		rec.Translations = make(map[string]model.Translation, len(a.Translations))
+		for loc, t := range a.Translations {
+			rec.Translations[loc] = model.Translation{Title: t.Title, Summary: t.Summary, ContentLong: t.ContentLong}
+		}
+	}
+	return rec
+}
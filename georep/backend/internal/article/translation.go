@@ -0,0 +1,150 @@
+package article
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"geo-repl-demo/internal/auth"
+	"geo-repl-demo/internal/locale"
+	"geo-repl-demo/internal/model"
+)
+
+var (
+	// ErrInvalidLocale geçersiz dil etiketi (ör. "tr", "en", "pt-BR" beklenir)
+	ErrInvalidLocale = errors.New("invalid locale")
+	// ErrInvalidTranslation eksik alanlı ya da makalenin kendi diliyle çakışan çeviri
+	ErrInvalidTranslation = errors.New("invalid translation")
+	// ErrTranslationNotFound makalede o dilde çeviri yoksa döner
+	ErrTranslationNotFound = errors.New("translation not found")
+)
+
+// maxTranslations makale başına çeviri sınırı
+const maxTranslations = 20
+
+// 🔹 Okuyucunun diline göre metni seç: önce tercihler (lang / Accept-Language),
+// sonra bölgenin varsayılan dili, en son makalenin kendi dili. Çeviriler
+// yanıttan çıkarılır, yerine mevcut diller listelenir. Sunulan dili döner.
+func (s *Service) Localize(a *model.Article, region string, prefs []string) string {
+	available := make([]string, 0, len(a.Translations)+1)
+	available = append(available, a.Locale)
+	for loc := range a.Translations {
+		available = append(available, loc)
+	}
+	sort.Strings(available[1:])
+
+	want, ok := locale.Negotiate(prefs, available)
+	if !ok {
+		want, ok = locale.Negotiate([]string{s.repo.regions.Resolve(region).Locale}, available)
+	}
+	if ok && want != a.Locale {
+		t := a.Translations[want]
+		a.Title, a.Summary, a.ContentLong, a.Locale = t.Title, t.Summary, t.ContentLong, want
+		a.ContentHTML, a.TOC = t.ContentHTML, t.TOC
+	}
+	a.Translations = nil
+	a.Locales = available
+	return a.Locale
+}
+
+// 🔹 Çeviri ekle / değiştir (yazarı veya editör) – makaleyle birlikte replike olur
+func (s *Service) PutTranslation(ctx context.Context, p auth.Principal, id int64, loc string, t model.Translation) (*model.Article, error) {
+	before, err := s.authorize(ctx, p, id)
+	if err == nil {
+		loc, err = normalizeLocale(loc)
+	}
+	if err == nil {
+		var ts map[string]model.Translation
+		ts, err = normalizeTranslations(before.Locale, map[string]model.Translation{loc: t})
+		t = ts[loc]
+	}
+	if _, exists := before.Translations[loc]; err == nil && !exists && len(before.Translations) >= maxTranslations {
+		err = fmt.Errorf("%w: at most %d translations", ErrInvalidTranslation, maxTranslations)
+	}
+	if err != nil {
+		s.record(ctx, p, "article.translation.put", id, before, nil, err)
+		return nil, err
+	}
+
+	a, rev, err := s.repo.PutTranslationMaster(ctx, id, loc, t, p.UserID, p.Username)
+	if err != nil {
+		s.record(ctx, p, "article.translation.put", id, before, nil, err)
+		return nil, err
+	}
+	s.record(ctx, p, "article.translation.put", id, before, &a, nil)
+	s.replicate(a, rev)
+	return &a, nil
+}
+
+// 🔹 Çeviriyi sil (yazarı veya editör)
+func (s *Service) DeleteTranslation(ctx context.Context, p auth.Principal, id int64, loc string) (*model.Article, error) {
+	before, err := s.authorize(ctx, p, id)
+	if err == nil {
+		loc, err = normalizeLocale(loc)
+	}
+	if err != nil {
+		s.record(ctx, p, "article.translation.delete", id, before, nil, err)
+		return nil, err
+	}
+
+	a, rev, err := s.repo.DeleteTranslationMaster(ctx, id, loc, p.UserID, p.Username)
+	if errors.Is(err, ErrNotFound) && before.DeletedAt == nil {
+		err = ErrTranslationNotFound
+	}
+	if err != nil {
+		s.record(ctx, p, "article.translation.delete", id, before, nil, err)
+		return nil, err
+	}
+	s.record(ctx, p, "article.translation.delete", id, before, &a, nil)
+	s.replicate(a, rev)
+	return &a, nil
+}
+
+// defaultLocale dil verilmeyen yeni makalelerin dili (topolojinin default_locale'i)
+func (s *Service) defaultLocale() string {
+	return s.repo.regions.DefaultLocale()
+}
+
+// ------------------------------------------------------
+//  Yardımcı: Dil ve çeviri doğrulama
+// ------------------------------------------------------
+
+func normalizeLocale(tag string) (string, error) {
+	loc, ok := locale.Normalize(tag)
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrInvalidLocale, tag)
+	}
+	return loc, nil
+}
+
+// normalizeTranslations dil anahtarlarını normalize eder, her çevirinin
+// başlık ve içeriğinin dolu olduğunu doğrular ve içeriği HTML'e çevirir.
+// Makalenin kendi dili (base) çeviri olarak verilemez.
+func normalizeTranslations(base string, in map[string]model.Translation) (map[string]model.Translation, error) {
+	if len(in) > maxTranslations {
+		return nil, fmt.Errorf("%w: at most %d translations", ErrInvalidTranslation, maxTranslations)
+	}
+	out := make(map[string]model.Translation, len(in))
+	for tag, t := range in {
+		loc, err := normalizeLocale(tag)
+		if err != nil {
+			return nil, err
+		}
+		if loc == base {
+			return nil, fmt.Errorf("%w: %s is the article's own locale", ErrInvalidTranslation, loc)
+		}
+		if _, dup := out[loc]; dup {
+			return nil, fmt.Errorf("%w: %s given twice", ErrInvalidTranslation, loc)
+		}
+		t.Title = strings.TrimSpace(t.Title)
+		t.ContentLong = strings.TrimSpace(t.ContentLong)
+		if t.Title == "" || t.ContentLong == "" {
+			return nil, fmt.Errorf("%w: %s needs a title and content_long", ErrInvalidTranslation, loc)
+		}
+		t.ContentHTML, t.TOC = render(t.Title, t.ContentLong, &t.Summary)
+		out[loc] = t
+	}
+	return out, nil
+}
@@ -0,0 +1,130 @@
+package attachment
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"geo-repl-demo/internal/article"
+	"geo-repl-demo/internal/auth"
+)
+
+type Handler struct {
+	svc *Service
+}
+
+func NewHandler(svc *Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func RegisterRoutes(r *gin.Engine, h *Handler) {
+	api := r.Group("/api")
+	{
+		api.GET("/articles/:id/attachments", h.list)
+		api.POST("/articles/:id/attachments", auth.Require(auth.PermArticleEditOwn), h.upload)
+		api.GET("/attachments/:id/content", h.content)
+	}
+}
+
+func (h *Handler) list(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	as, err := h.svc.List(c.Request.Context(), c.GetString("region"), c.GetString("country"), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, as)
+}
+
+// upload multipart gövdedeki "file" parçasını belleğe almadan blob store'a akıtır
+func (h *Handler) upload(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	// Multipart başlıkları için 64 KB pay
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.svc.MaxBytes()+64<<10)
+	mr, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expected multipart/form-data with a file field"})
+		return
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file field is required"})
+			return
+		}
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		p, _ := auth.FromContext(c)
+		a, err := h.svc.Upload(c.Request.Context(), p, id, part.FileName(), part)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, a)
+		return
+	}
+}
+
+// content eki istenen (ya da en yakın) bölgenin store'undan sunar
+func (h *Handler) content(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	a, rc, err := h.svc.Open(c.Request.Context(), c.GetString("region"), c.GetString("country"), id, c.Query("store"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	defer rc.Close()
+
+	disposition := "attachment"
+	if Inline(a.ContentType) {
+		disposition = "inline"
+	}
+	c.DataFromReader(http.StatusOK, a.Size, a.ContentType, rc, map[string]string{
+		"Content-Disposition":     mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename}),
+		"X-Content-Type-Options":  "nosniff",
+		"Content-Security-Policy": "sandbox",
+		"Cache-Control":           "private, max-age=3600",
+		"ETag":                    `"` + a.SHA256 + `"`,
+		"X-Blob-Store":            a.Store,
+	})
+}
+
+func writeError(c *gin.Context, err error) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTooLarge), errors.As(err, &tooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": ErrTooLarge.Error()})
+	case errors.Is(err, ErrNotFound), errors.Is(err, article.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, article.ErrGeoBlocked):
+		c.JSON(http.StatusUnavailableForLegalReasons, gin.H{"error": err.Error()})
+	case errors.Is(err, article.ErrNotOwner), errors.Is(err, article.ErrNoAuthor):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": auth.CodeForbidden})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,104 @@
+package attachment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"geo-repl-demo/internal/db"
+	"geo-repl-demo/internal/model"
+	"geo-repl-demo/internal/region"
+)
+
+type Repository struct {
+	master   *db.Master
+	replicas *db.ReplicaSet
+	regions  *region.Registry
+}
+
+func NewRepository(master *db.Master, replicas *db.ReplicaSet, regions *region.Registry) *Repository {
+	return &Repository{master: master, replicas: replicas, regions: regions}
+}
+
+// node returns the node serving region: its replica if that is bootstrapped
+// and healthy, otherwise the master. A replica only has an attachment row
+// once the blob is in its store, so the node's store serves its rows.
+func (r *Repository) node(region string) (string, *pgxpool.Pool) {
+	node := r.regions.NodeFor(region)
+	if r.replicas != nil {
+		if rep, ok := r.replicas.Get(node.Name); ok && rep.Healthy() {
+			return rep.Node.Name, rep.Pool
+		}
+	}
+	return r.master.Node.Name, r.master.Pool
+}
+
+// known reports whether name is a node of the current topology.
+func (r *Repository) known(name string) bool {
+	if name == r.master.Node.Name {
+		return true
+	}
+	if r.replicas == nil {
+		return false
+	}
+	_, ok := r.replicas.Get(name)
+	return ok
+}
+
+// InsertMaster records an uploaded attachment on the master.
+func (r *Repository) InsertMaster(ctx context.Context, a model.Attachment) (model.Attachment, error) {
+	var out model.Attachment
+	err := db.ScanAttachment(r.master.Pool.QueryRow(ctx, `
+		INSERT INTO attachments (article_id, blob_key, filename, content_type, size, sha256, uploaded_by)
+		VALUES ($1,$2,$3,$4,$5,$6,$7)
+		RETURNING `+db.AttachmentColumns,
+		a.ArticleID, a.Key, a.Filename, a.ContentType, a.Size, a.SHA256, a.UploadedBy), &out)
+	if err != nil {
+		return model.Attachment{}, fmt.Errorf("insert attachment: %w", err)
+	}
+	return out, nil
+}
+
+// List returns the attachments of an article on the node serving region,
+// together with that node's name.
+func (r *Repository) List(ctx context.Context, region string, articleID int64) (string, []model.Attachment, error) {
+	name, pool := r.node(region)
+	rows, err := pool.Query(ctx, `
+		SELECT `+db.AttachmentColumns+` FROM attachments
+		WHERE article_id=$1 ORDER BY id`, articleID)
+	if err != nil {
+		return name, nil, err
+	}
+	defer rows.Close()
+
+	as := []model.Attachment{}
+	for rows.Next() {
+		var a model.Attachment
+		if err := db.ScanAttachment(rows, &a); err != nil {
+			return name, nil, err
+		}
+		as = append(as, a)
+	}
+	return name, as, rows.Err()
+}
+
+// Get returns an attachment from the node serving region.
+func (r *Repository) Get(ctx context.Context, region string, id int64) (string, model.Attachment, error) {
+	name, pool := r.node(region)
+	var a model.Attachment
+	err := db.ScanAttachment(pool.QueryRow(ctx, `
+		SELECT `+db.AttachmentColumns+` FROM attachments WHERE id=$1`, id), &a)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return name, a, ErrNotFound
+	}
+	return name, a, err
+}
+
+// MasterNode returns the name of the master node, whose store receives
+// uploads.
+func (r *Repository) MasterNode() string {
+	return r.master.Node.Name
+}
@@ -0,0 +1,260 @@
+// Package attachment stores files attached to articles. Uploads go to the
+// master's blob store; the replicator copies them to every regional store
+// and readers download from the store nearest to them.
+package attachment
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"geo-repl-demo/internal/audit"
+	"geo-repl-demo/internal/auth"
+	"geo-repl-demo/internal/blob"
+	"geo-repl-demo/internal/model"
+	"geo-repl-demo/internal/replication"
+)
+
+var (
+	ErrNotFound     = errors.New("attachment not found")
+	ErrInvalidInput = errors.New("invalid input")
+	ErrTooLarge     = errors.New("attachment is too large")
+)
+
+const maxFilenameLen = 255
+
+// inlineTypes may be shown in the browser; everything else is served as a
+// download so uploaded HTML or SVG never runs in the API's origin.
+var inlineTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Articles checks access to the article an attachment belongs to; it is
+// implemented by article.Service.
+type Articles interface {
+	Get(ctx context.Context, region, country string, id int64) (*model.Article, error)
+	Editable(ctx context.Context, p auth.Principal, id int64) (*model.Article, error)
+}
+
+type Service struct {
+	repo       *Repository
+	articles   Articles
+	blobs      *blob.Stores
+	replicator *replication.Replicator
+	audit      audit.Sink
+	maxBytes   int64
+}
+
+func NewService(repo *Repository, articles Articles, blobs *blob.Stores, replicator *replication.Replicator, sink audit.Sink, maxBytes int64) *Service {
+	return &Service{repo: repo, articles: articles, blobs: blobs, replicator: replicator, audit: sink, maxBytes: maxBytes}
+}
+
+// MaxBytes is the largest accepted upload.
+func (s *Service) MaxBytes() int64 {
+	return s.maxBytes
+}
+
+// Upload stores r in the master's blob store and records it as an
+// attachment of the article. The copies to the regional stores are made
+// asynchronously.
+func (s *Service) Upload(ctx context.Context, p auth.Principal, articleID int64, filename string, r io.Reader) (*model.Attachment, error) {
+	a, err := s.upload(ctx, p, articleID, filename, r)
+	if s.audit != nil {
+		target := ""
+		if a.ID != 0 {
+			target = fmt.Sprint(a.ID)
+		}
+		e := p.AuditEvent(ctx, "attachment.create", "attachment", target)
+		e.Detail = map[string]string{"article_id": fmt.Sprint(articleID), "filename": a.Filename}
+		if err != nil {
+			e.Outcome = audit.Failed
+			e.Detail["error"] = err.Error()
+		} else {
+			e.AfterHash = audit.Hash(a)
+		}
+		s.audit.Record(ctx, e)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (s *Service) upload(ctx context.Context, p auth.Principal, articleID int64, filename string, r io.Reader) (model.Attachment, error) {
+	a := model.Attachment{ArticleID: articleID, Filename: cleanFilename(filename), UploadedBy: p.UserID}
+	if _, err := s.articles.Editable(ctx, p, articleID); err != nil {
+		return a, err
+	}
+	store, err := s.blobs.For(s.repo.MasterNode())
+	if err != nil {
+		return a, err
+	}
+
+	br := bufio.NewReaderSize(&limitReader{r: r, n: s.maxBytes}, 512)
+	head, _ := br.Peek(512)
+	a.ContentType = contentType(head, a.Filename)
+	a.Key = fmt.Sprintf("articles/%d/%s", articleID, randomHex(16))
+
+	info, err := store.Put(ctx, a.Key, br)
+	if err != nil {
+		return a, err
+	}
+	if info.Size == 0 {
+		_ = store.Delete(ctx, a.Key)
+		return a, fmt.Errorf("%w: file is empty", ErrInvalidInput)
+	}
+	a.Size, a.SHA256 = info.Size, info.SHA256
+
+	out, err := s.repo.InsertMaster(ctx, a)
+	if err != nil {
+		_ = store.Delete(ctx, a.Key)
+		return a, err
+	}
+	if s.replicator != nil {
+		go s.replicator.ScheduleAttachment(out)
+	}
+	s.locate(&out, s.repo.MasterNode())
+	return out, nil
+}
+
+// List returns the attachments of an article as the reader's regional node
+// has them, with URLs pointing at that node's store.
+func (s *Service) List(ctx context.Context, region, country string, articleID int64) ([]model.Attachment, error) {
+	if _, err := s.articles.Get(ctx, region, country, articleID); err != nil {
+		return nil, err
+	}
+	node, as, err := s.repo.List(ctx, region, articleID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range as {
+		s.locate(&as[i], node)
+	}
+	return as, nil
+}
+
+// Open returns an attachment and its contents. store names the node whose
+// blob store to read; empty or unknown picks the reader's nearest. A store
+// that does not have the blob yet falls back to the master's.
+func (s *Service) Open(ctx context.Context, region, country string, id int64, store string) (model.Attachment, io.ReadCloser, error) {
+	node, a, err := s.repo.Get(ctx, region, id)
+	if err != nil {
+		return a, nil, err
+	}
+	if _, err := s.articles.Get(ctx, region, country, a.ArticleID); err != nil {
+		return a, nil, err
+	}
+	if store == "" || !s.repo.known(store) {
+		store = node
+	}
+
+	for _, name := range []string{store, s.repo.MasterNode()} {
+		st, err := s.blobs.For(name)
+		if err != nil {
+			return a, nil, err
+		}
+		rc, _, err := st.Open(ctx, a.Key)
+		if errors.Is(err, blob.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return a, nil, err
+		}
+		a.Store = name
+		return a, rc, nil
+	}
+	return a, nil, ErrNotFound
+}
+
+// PurgeBlobs deletes the blobs of a purged article from node's store. It is
+// registered as an article purge hook.
+func (s *Service) PurgeBlobs(ctx context.Context, node string, articleID int64) error {
+	st, err := s.blobs.For(node)
+	if err != nil {
+		return err
+	}
+	return st.DeletePrefix(ctx, fmt.Sprintf("articles/%d", articleID))
+}
+
+// Inline reports whether a content type may be displayed by the browser.
+func Inline(contentType string) bool {
+	return inlineTypes[contentType]
+}
+
+func (s *Service) locate(a *model.Attachment, node string) {
+	a.Store = node
+	a.URL = fmt.Sprintf("/api/attachments/%d/content?store=%s", a.ID, node)
+}
+
+// contentType sniffs the first bytes of a file and falls back to its
+// extension when they are not conclusive.
+func contentType(head []byte, filename string) string {
+	ct := http.DetectContentType(head)
+	if ct == "application/octet-stream" {
+		if byExt := mime.TypeByExtension(path.Ext(filename)); byExt != "" {
+			ct = byExt
+		}
+	}
+	if mt, _, err := mime.ParseMediaType(ct); err == nil {
+		return mt
+	}
+	return "application/octet-stream"
+}
+
+// cleanFilename keeps the base name of an uploaded file without control
+// characters, quotes or path separators.
+func cleanFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' || r == '/' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	for utf8.RuneCountInString(name) > maxFilenameLen {
+		_, size := utf8.DecodeRuneInString(name)
+		name = name[size:] // uzantı korunsun diye baştan kısaltılır
+	}
+	if name == "" || name == "." || name == ".." {
+		return "file"
+	}
+	return name
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// limitReader fails with ErrTooLarge once more than n bytes were read.
+type limitReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, ErrTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,107 @@
+// Package audit records security-relevant actions.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"geo-repl-demo/internal/model"
+	"geo-repl-demo/internal/reqkey"
+)
+
+// Event is a single audited action.
+type Event = model.AuditEntry
+
+// Outcomes.
+const (
+	Allowed = "allowed"
+	Denied  = "denied"
+	Failed  = "failed"
+)
+
+// Sink receives audit events. Implementations must not block the request
+// path for long.
+type Sink interface {
+	Record(ctx context.Context, e Event)
+}
+
+// LogSink writes audit events to the standard logger as JSON.
+type LogSink struct{}
+
+// Record implements Sink.
+func (LogSink) Record(_ context.Context, e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	raw, _ := json.Marshal(e)
+	log.Printf("🧾 audit %s", raw)
+}
+
+// Request is what the audit log needs to know about the HTTP request
+// behind an action.
+type Request struct {
+	ClientIP string
+	Region   string
+}
+
+type requestKey struct{}
+
+// WithRequest attaches request metadata to ctx.
+func WithRequest(ctx context.Context, r Request) context.Context {
+	return context.WithValue(ctx, requestKey{}, r)
+}
+
+// RequestFrom returns the metadata attached by WithRequest.
+func RequestFrom(ctx context.Context) Request {
+	r, _ := ctx.Value(requestKey{}).(Request)
+	return r
+}
+
+// Middleware copies the resolved client address and region into the
+// request context, so services can audit without access to gin. It must
+// run after RegionMiddleware.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := WithRequest(c.Request.Context(), Request{
+			ClientIP: c.GetString(reqkey.ClientAddr),
+			Region:   c.GetString("region"),
+		})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// Stamp fills the time, client IP and region of e from ctx where unset.
+func Stamp(ctx context.Context, e Event) Event {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	r := RequestFrom(ctx)
+	if e.ClientIP == "" {
+		e.ClientIP = r.ClientIP
+	}
+	if e.Region == "" {
+		e.Region = r.Region
+	}
+	return e
+}
+
+// Hash fingerprints an entity state as the SHA-256 of its JSON encoding.
+// A nil value (the entity did not exist) hashes to "".
+func Hash(v any) string {
+	if v == nil {
+		return ""
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,97 @@
+package auditlog
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"geo-repl-demo/internal/auth"
+)
+
+type Handler struct {
+	store *Store
+}
+
+func NewHandler(store *Store) *Handler {
+	return &Handler{store: store}
+}
+
+// RegisterRoutes mounts the audit query endpoint (admin only).
+func RegisterRoutes(r *gin.Engine, h *Handler) {
+	api := r.Group("/api")
+	{
+		api.GET("/audit", auth.Require(auth.PermAdmin), h.query)
+	}
+}
+
+// query: GET /api/audit?actor=&action=&entity=&target=&region_filter=
+// &outcome=&since=&until=&before_id=&limit=&consistent=true
+//
+// The region filter is named region_filter because ?region= is the
+// routing override in dev mode.
+func (h *Handler) query(c *gin.Context) {
+	f := Filter{
+		Actor:   c.Query("actor"),
+		Action:  c.Query("action"),
+		Entity:  c.Query("entity"),
+		Target:  c.Query("target"),
+		Region:  c.Query("region_filter"),
+		Outcome: c.Query("outcome"),
+	}
+	var err error
+	if f.Since, err = parseTime(c.Query("since")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "since: " + err.Error()})
+		return
+	}
+	if f.Until, err = parseTime(c.Query("until")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "until: " + err.Error()})
+		return
+	}
+	if v := c.Query("before_id"); v != "" {
+		if f.BeforeID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid before_id"})
+			return
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+	consistent, _ := strconv.ParseBool(c.Query("consistent"))
+
+	entries, node, err := h.store.Query(c.Request.Context(), c.GetString("region"), f, consistent)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := gin.H{"entries": entries, "node": node}
+	if n := len(entries); n > 0 && n == effectiveLimit(f.Limit) {
+		resp["next_before_id"] = entries[n-1].ID
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func effectiveLimit(l int) int {
+	if l <= 0 {
+		return defaultLimit
+	}
+	if l > maxLimit {
+		return maxLimit
+	}
+	return l
+}
+
+// parseTime accepts RFC 3339 timestamps and plain dates.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
@@ -0,0 +1,44 @@
+// Package auditlog stores audit events in the replicated audit_log table
+// and serves them to admins.
+package auditlog
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"geo-repl-demo/internal/audit"
+	"geo-repl-demo/internal/db"
+	"geo-repl-demo/internal/replication"
+)
+
+// DBSink appends audit events to the master's audit_log and replicates
+// them. Events that cannot be stored are written to the standard logger so
+// they are not lost silently.
+type DBSink struct {
+	master     *db.Master
+	replicator *replication.Replicator
+	fallback   audit.LogSink
+}
+
+func NewDBSink(master *db.Master, replicator *replication.Replicator) *DBSink {
+	return &DBSink{master: master, replicator: replicator}
+}
+
+// Record implements audit.Sink. The insert runs detached from the request
+// so a client hanging up does not drop the entry.
+func (s *DBSink) Record(ctx context.Context, e audit.Event) {
+	e = audit.Stamp(ctx, e)
+	wctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 3*time.Second)
+	defer cancel()
+
+	stored, err := db.InsertAudit(wctx, s.master.Pool, e)
+	if err != nil {
+		log.Printf("⚠️ Audit kaydı yazılamadı: %v", err)
+		s.fallback.Record(ctx, e)
+		return
+	}
+	if s.replicator != nil {
+		go s.replicator.ScheduleAudit(stored)
+	}
+}
@@ -0,0 +1,131 @@
+package auditlog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"geo-repl-demo/internal/db"
+	"geo-repl-demo/internal/model"
+	"geo-repl-demo/internal/region"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// Filter selects audit entries. Empty fields match everything.
+type Filter struct {
+	Actor   string
+	Action  string // exact, or a prefix when it ends in "*"
+	Entity  string
+	Target  string
+	Region  string
+	Outcome string
+	Since   time.Time
+	Until   time.Time
+	// BeforeID pages backwards: only entries with a smaller ID are returned.
+	BeforeID int64
+	Limit    int
+}
+
+// Store queries the audit log.
+type Store struct {
+	master   *db.Master
+	replicas *db.ReplicaSet
+	regions  *region.Registry
+}
+
+func NewStore(master *db.Master, replicas *db.ReplicaSet, regions *region.Registry) *Store {
+	return &Store{master: master, replicas: replicas, regions: regions}
+}
+
+// Query returns matching entries, newest first. It reads the replica of
+// region, which may lag the master by a few seconds, unless consistent is
+// set. The second result names the node that answered.
+func (s *Store) Query(ctx context.Context, region string, f Filter, consistent bool) ([]model.AuditEntry, string, error) {
+	pool, node := s.master.Pool, s.master.Node.Name
+	if !consistent {
+		pool, node = s.poolForRegion(region)
+	}
+
+	var where []string
+	var args []any
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Actor != "" {
+		add("actor = $%d", f.Actor)
+	}
+	if prefix, ok := strings.CutSuffix(f.Action, "*"); ok {
+		add("starts_with(action, $%d)", prefix)
+	} else if f.Action != "" {
+		add("action = $%d", f.Action)
+	}
+	if f.Entity != "" {
+		add("entity = $%d", f.Entity)
+	}
+	if f.Target != "" {
+		add("target = $%d", f.Target)
+	}
+	if f.Region != "" {
+		add("region = $%d", f.Region)
+	}
+	if f.Outcome != "" {
+		add("outcome = $%d", f.Outcome)
+	}
+	if !f.Since.IsZero() {
+		add("at >= $%d", f.Since)
+	}
+	if !f.Until.IsZero() {
+		add("at < $%d", f.Until)
+	}
+	if f.BeforeID > 0 {
+		add("id < $%d", f.BeforeID)
+	}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	query := `SELECT ` + db.AuditColumns + ` FROM audit_log`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(` ORDER BY id DESC LIMIT $%d`, len(args))
+
+	rows, err := pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, node, err
+	}
+	defer rows.Close()
+
+	entries := []model.AuditEntry{}
+	for rows.Next() {
+		var e model.AuditEntry
+		if err := db.ScanAudit(rows, &e); err != nil {
+			return nil, node, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, node, rows.Err()
+}
+
+func (s *Store) poolForRegion(region string) (*pgxpool.Pool, string) {
+	node := s.regions.NodeFor(region)
+	if s.replicas != nil {
+		if rep, ok := s.replicas.Get(node.Name); ok && rep.Healthy() {
+			return rep.Pool, rep.Node.Name
+		}
+	}
+	return s.master.Pool, s.master.Node.Name
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Permission is an action guarded by Require.
+type Permission string
+
+const (
+	PermArticleRead    Permission = "article:read"
+	PermArticleCreate  Permission = "article:create"
+	PermArticleEditOwn Permission = "article:edit-own"
+	PermArticleEditAny Permission = "article:edit-any"
+	PermComment        Permission = "comment:create"
+	// PermAdmin covers replication, topology and other operator endpoints.
+	PermAdmin Permission = "admin"
+)
+
+// permissions is the role → permission matrix.
+var permissions = map[string][]Permission{
+	RoleReader: {PermArticleRead, PermComment},
+	RoleWriter: {PermArticleRead, PermComment, PermArticleCreate, PermArticleEditOwn},
+	RoleEditor: {PermArticleRead, PermComment, PermArticleCreate, PermArticleEditOwn, PermArticleEditAny},
+	RoleAdmin:  {PermArticleRead, PermComment, PermArticleCreate, PermArticleEditOwn, PermArticleEditAny, PermAdmin},
+}
+
+// API key scopes.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+// scopePermissions is the API key scope → permission matrix.
+var scopePermissions = map[string][]Permission{
+	ScopeRead:  {PermArticleRead},
+	ScopeWrite: {PermArticleRead, PermComment, PermArticleCreate, PermArticleEditOwn},
+	ScopeAdmin: {PermArticleRead, PermComment, PermArticleCreate, PermArticleEditOwn, PermArticleEditAny, PermAdmin},
+}
+
+// ValidScope reports whether scope is one of the known API key scopes.
+func ValidScope(scope string) bool {
+	_, ok := scopePermissions[scope]
+	return ok
+}
+
+// Can reports whether the principal's role grants perm. For API keys one of
+// the key's scopes must grant it as well.
+func (p Principal) Can(perm Permission) bool {
+	if !grants(permissions[p.Role], perm) {
+		return false
+	}
+	if !p.IsAPIKey() {
+		return true
+	}
+	for _, scope := range p.Scopes {
+		if grants(scopePermissions[scope], perm) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopeAllowed reports whether the principal may hand out scope to an API
+// key: every permission of the scope must be one the principal has.
+func (p Principal) ScopeAllowed(scope string) bool {
+	perms, ok := scopePermissions[scope]
+	if !ok {
+		return false
+	}
+	for _, perm := range perms {
+		if !p.Can(perm) {
+			return false
+		}
+	}
+	return true
+}
+
+func grants(perms []Permission, perm Permission) bool {
+	for _, granted := range perms {
+		if granted == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Error codes of the JSON error body written by Unauthorized and Forbidden.
+const (
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden    = "forbidden"
+)
+
+// Unauthorized aborts with 401 and the standard error body.
+func Unauthorized(c *gin.Context, msg string) {
+	c.Header("WWW-Authenticate", `Bearer realm="api"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": msg, "code": CodeUnauthorized})
+}
+
+// Forbidden aborts with 403 and the standard error body.
+func Forbidden(c *gin.Context, msg string, perm Permission) {
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": msg, "code": CodeForbidden, "required": perm})
+}
+
+// Authenticated requires a valid bearer token.
+func Authenticated() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requirePrincipal(c); ok {
+			c.Next()
+		}
+	}
+}
+
+// Require requires a valid bearer token whose role grants perm.
+func Require(perm Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := requirePrincipal(c)
+		if !ok {
+			return
+		}
+		if !p.Can(perm) {
+			msg := "role " + p.Role + " is not allowed to do this"
+			if p.IsAPIKey() && grants(permissions[p.Role], perm) {
+				msg = "api key scopes do not allow this"
+			}
+			Forbidden(c, msg, perm)
+			return
+		}
+		c.Next()
+	}
+}
+
+// requirePrincipal returns the request's principal or aborts with 401.
+func requirePrincipal(c *gin.Context) (Principal, bool) {
+	if p, ok := FromContext(c); ok {
+		return p, true
+	}
+	if err, ok := c.Get(authErrorKey); ok {
+		Unauthorized(c, "invalid token: "+err.(error).Error())
+		return Principal{}, false
+	}
+	Unauthorized(c, "authentication required")
+	return Principal{}, false
+}
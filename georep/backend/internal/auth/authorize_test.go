@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveRequire runs a request through Require(perm) as p; a nil p is an
+// anonymous caller and authErr a rejected bearer token.
+func serveRequire(perm Permission, p *Principal, authErr error) int {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if p != nil {
+			c.Set(principalKey, *p)
+		}
+		if authErr != nil {
+			c.Set(authErrorKey, authErr)
+		}
+	})
+	r.GET("/", Require(perm), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w.Code
+}
+
+func TestRequireRoles(t *testing.T) {
+	perms := []Permission{PermArticleRead, PermComment, PermArticleCreate, PermArticleEditOwn, PermArticleEditAny, PermAdmin}
+	// allowed[role] lists the permissions the role has, in perms order.
+	allowed := map[string][]bool{
+		RoleReader: {true, true, false, false, false, false},
+		RoleWriter: {true, true, true, true, false, false},
+		RoleEditor: {true, true, true, true, true, false},
+		RoleAdmin:  {true, true, true, true, true, true},
+		"unknown":  {false, false, false, false, false, false},
+	}
+	for role, want := range allowed {
+		for i, perm := range perms {
+			code := serveRequire(perm, &Principal{UserID: 1, Username: "u", Role: role}, nil)
+			expected := http.StatusForbidden
+			if want[i] {
+				expected = http.StatusNoContent
+			}
+			if code != expected {
+				t.Errorf("role %s, %s: HTTP %d, want %d", role, perm, code, expected)
+			}
+		}
+	}
+}
+
+func TestRequireAPIKeyScopes(t *testing.T) {
+	tests := []struct {
+		role   string
+		scopes []string
+		perm   Permission
+		want   int
+	}{
+		{RoleAdmin, []string{ScopeRead}, PermArticleRead, http.StatusNoContent},
+		{RoleAdmin, []string{ScopeRead}, PermArticleCreate, http.StatusForbidden},
+		{RoleAdmin, []string{ScopeRead}, PermComment, http.StatusForbidden},
+		{RoleAdmin, []string{ScopeWrite}, PermArticleEditOwn, http.StatusNoContent},
+		{RoleAdmin, []string{ScopeWrite}, PermArticleEditAny, http.StatusForbidden},
+		{RoleAdmin, []string{ScopeRead, ScopeAdmin}, PermAdmin, http.StatusNoContent},
+		// A scope never grants more than the owner's role.
+		{RoleWriter, []string{ScopeAdmin}, PermAdmin, http.StatusForbidden},
+		{RoleReader, []string{ScopeWrite}, PermArticleCreate, http.StatusForbidden},
+		{RoleEditor, nil, PermArticleRead, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		p := &Principal{UserID: 1, Username: "u", Role: tt.role, KeyID: "k1", Scopes: tt.scopes}
+		if code := serveRequire(tt.perm, p, nil); code != tt.want {
+			t.Errorf("%s key with %v, %s: HTTP %d, want %d", tt.role, tt.scopes, tt.perm, code, tt.want)
+		}
+	}
+}
+
+func TestRequireUnauthenticated(t *testing.T) {
+	if code := serveRequire(PermArticleRead, nil, nil); code != http.StatusUnauthorized {
+		t.Errorf("anonymous: HTTP %d, want 401", code)
+	}
+	if code := serveRequire(PermArticleRead, nil, errors.New("expired")); code != http.StatusUnauthorized {
+		t.Errorf("rejected token: HTTP %d, want 401", code)
+	}
+}
+
+func TestScopeAllowed(t *testing.T) {
+	writer := Principal{Role: RoleWriter}
+	if !writer.ScopeAllowed(ScopeWrite) || writer.ScopeAllowed(ScopeAdmin) || writer.ScopeAllowed("bogus") {
+		t.Error("writer may hand out read and write scopes only")
+	}
+	admin := Principal{Role: RoleAdmin}
+	if !admin.ScopeAllowed(ScopeAdmin) {
+		t.Error("admin may hand out the admin scope")
+	}
+}
@@ -1,59 +1,170 @@
 package auth
 
 import (
+	"errors"
+	"log"
 	"net/http"
-	"strings"
+	"net/url"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+
+	"geo-repl-demo/internal/model"
 )
 
 type LoginRequest struct {
-	Username string `json:"username"`
-	Role     string `json:"role"`
-	Region   string `json:"region"`
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 type LoginResponse struct {
-	Username string `json:"username"`
-	Role     string `json:"role"`
-	Region   string `json:"region"`
-	Token    string `json:"token"`
+	User model.User `json:"user"`
+	TokenPair
 }
 
-type Handler struct{}
+type Handler struct {
+	svc  *Service
+	oidc *OIDC
+}
 
-func NewHandler() *Handler {
-	return &Handler{}
+// NewHandler returns the auth handler; oidc may be nil when SSO is off.
+func NewHandler(svc *Service, oidc *OIDC) *Handler {
+	return &Handler{svc: svc, oidc: oidc}
 }
 
 func RegisterRoutes(r *gin.Engine, h *Handler) {
 	api := r.Group("/api")
 	{
+		api.POST("/register", h.register)
 		api.POST("/login", h.login)
+		api.POST("/refresh", h.refresh)
+		api.GET("/me", Authenticated(), h.me)
+		api.GET("/oidc/config", h.oidcConfig)
+		api.GET("/oidc/login", h.oidcLogin)
+		api.GET("/oidc/callback", h.oidcCallback)
+	}
+}
+
+func (h *Handler) register(c *gin.Context) {
+	var req RegisterInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	caller, _ := FromContext(c)
+	u, pair, err := h.svc.Register(c.Request.Context(), req, caller, c.GetString("region"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, LoginResponse{User: u, TokenPair: pair})
 }
 
-// Fake login: just echoes back role and region, generates a dummy token.
+// login verifies the password at the routed region and issues a token pair.
 func (h *Handler) login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	req.Username = strings.TrimSpace(req.Username)
-	req.Role = strings.ToLower(strings.TrimSpace(req.Role))
-	req.Region = strings.ToLower(strings.TrimSpace(req.Region))
-	if req.Username == "" || req.Role == "" || req.Region == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "username, role, region required"})
+	u, pair, err := h.svc.Login(c.Request.Context(), req.Username, req.Password, c.GetString("region"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, LoginResponse{User: u, TokenPair: pair})
+}
+
+func (h *Handler) refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	resp := LoginResponse{
-		Username: req.Username,
-		Role:     req.Role,
-		Region:   req.Region,
-		Token:    "dummy-token-" + req.Username,
+	u, pair, err := h.svc.Refresh(c.Request.Context(), req.RefreshToken, c.GetString("region"))
+	if err != nil {
+		writeError(c, err)
+		return
 	}
-	c.JSON(http.StatusOK, resp)
+	c.JSON(http.StatusOK, LoginResponse{User: u, TokenPair: pair})
 }
 
+// me returns the principal of the access token; verified locally.
+func (h *Handler) me(c *gin.Context) {
+	p, _ := FromContext(c)
+	c.JSON(http.StatusOK, p)
+}
 
+// oidcConfig tells the UI whether SSO is available.
+func (h *Handler) oidcConfig(c *gin.Context) {
+	if h.oidc == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "login_path": "/api/oidc/login", "mock": h.oidc.Mock()})
+}
+
+// oidcLogin redirects the browser to the IdP.
+func (h *Handler) oidcLogin(c *gin.Context) {
+	if h.oidc == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrOIDCDisabled.Error()})
+		return
+	}
+	target, ck, err := h.oidc.Start(c.Request.Context(), c.Request.TLS != nil)
+	if err != nil {
+		log.Printf("⚠️ OIDC başlatılamadı: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	http.SetCookie(c.Writer, ck)
+	c.Redirect(http.StatusFound, target)
+}
+
+// oidcCallback completes the login and hands the issued tokens to the UI in
+// the URL fragment, which browsers do not send to servers or in Referer.
+func (h *Handler) oidcCallback(c *gin.Context) {
+	if h.oidc == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrOIDCDisabled.Error()})
+		return
+	}
+	fragment := url.Values{}
+	claims, ck, err := h.oidc.Finish(c.Request.Context(), c.Request)
+	http.SetCookie(c.Writer, ck)
+	if err == nil {
+		var u model.User
+		var pair TokenPair
+		u, pair, err = h.svc.LoginOIDC(c.Request.Context(), claims, h.oidc.Role(claims), h.oidc.Region(claims), c.GetString("region"))
+		if err == nil {
+			fragment.Set("access_token", pair.AccessToken)
+			fragment.Set("refresh_token", pair.RefreshToken)
+			fragment.Set("expires_at", strconv.FormatInt(pair.AccessExpiresAt.Unix(), 10))
+			fragment.Set("username", u.Username)
+			fragment.Set("role", u.Role)
+			fragment.Set("region", u.HomeRegion)
+		}
+	}
+	if err != nil {
+		log.Printf("⚠️ OIDC girişi başarısız: %v", err)
+		fragment.Set("error", err.Error())
+	}
+	c.Redirect(http.StatusFound, h.oidc.PostLoginURL()+"#"+fragment.Encode())
+}
+
+func writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrRoleNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrUsernameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
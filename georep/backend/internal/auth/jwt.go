@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Token errors.
+var (
+	ErrMalformedToken = errors.New("malformed token")
+	ErrTokenExpired   = errors.New("token expired")
+	ErrWrongTokenType = errors.New("wrong token type")
+)
+
+// Token types carried in the "typ" claim.
+const (
+	TokenAccess  = "access"
+	TokenRefresh = "refresh"
+)
+
+// jwtIssuer is the "iss" claim of every token we issue.
+const jwtIssuer = "geo-repl-demo"
+
+// jwtHeader is the fixed, pre-encoded HS256 header.
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Claims is the JWT payload. Every region shares the signing key, so a
+// token can be verified anywhere without asking the master.
+type Claims struct {
+	Subject   string `json:"sub"` // user ID
+	Username  string `json:"name"`
+	Role      string `json:"role"`
+	Region    string `json:"region"` // home region
+	Type      string `json:"typ"`
+	Issuer    string `json:"iss"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// signJWT encodes and signs claims as a compact HS256 JWT.
+func signJWT(key []byte, c Claims) (string, error) {
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(raw)
+	return unsigned + "." + jwtMAC(key, unsigned), nil
+}
+
+// parseJWT verifies the signature, algorithm, issuer and expiry of token.
+func parseJWT(key []byte, token string, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrMalformedToken
+	}
+
+	// Yalnızca HS256 kabul edilir ("alg": "none" vb. reddedilir)
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return Claims{}, ErrMalformedToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(rawHeader, &header); err != nil || header.Alg != "HS256" {
+		return Claims{}, ErrMalformedToken
+	}
+
+	if !hmac.Equal([]byte(parts[2]), []byte(jwtMAC(key, parts[0]+"."+parts[1]))) {
+		return Claims{}, ErrInvalidSignature
+	}
+
+	rawClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return Claims{}, ErrMalformedToken
+	}
+	var c Claims
+	if err := json.Unmarshal(rawClaims, &c); err != nil || c.Issuer != jwtIssuer {
+		return Claims{}, ErrMalformedToken
+	}
+	if now.Unix() >= c.ExpiresAt {
+		return Claims{}, ErrTokenExpired
+	}
+	return c, nil
+}
+
+func jwtMAC(key []byte, unsigned string) string {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,357 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"geo-repl-demo/internal/config"
+)
+
+// MockUser is an account of the mock IdP.
+type MockUser struct {
+	Subject  string   `json:"sub"`
+	Username string   `json:"preferred_username"`
+	Name     string   `json:"name"`
+	Email    string   `json:"email"`
+	Groups   []string `json:"groups"`
+	Region   string   `json:"region"`
+}
+
+// DefaultMockUsers cover every role of the default OIDC_ROLE_MAP.
+var DefaultMockUsers = []MockUser{
+	{Subject: "mock-1", Username: "ayse", Name: "Ayşe Admin", Email: "ayse@example.test", Groups: []string{"geo-admins"}, Region: "tr"},
+	{Subject: "mock-2", Username: "john", Name: "John Editor", Email: "john@example.test", Groups: []string{"geo-editors"}, Region: "us"},
+	{Subject: "mock-3", Username: "maria", Name: "Maria Writer", Email: "maria@example.test", Groups: []string{"geo-writers"}, Region: "sa"},
+	{Subject: "mock-4", Username: "guest", Name: "Guest Reader", Email: "guest@example.test", Region: "eu"},
+}
+
+const mockCodeTTL = time.Minute
+
+// MockIdP is a minimal OpenID Connect provider for development and tests.
+// It serves discovery, an authorization endpoint that logs in a mock user
+// without a password, a token endpoint that enforces PKCE, and a JWKS. It
+// signs ID tokens with an RSA key generated at startup.
+type MockIdP struct {
+	cfg   config.OIDC
+	users []MockUser
+	key   *rsa.PrivateKey
+	kid   string
+	path  string // issuer URL path, e.g. "/mock-idp"
+	mux   *http.ServeMux
+
+	mu    sync.Mutex
+	codes map[string]mockCode
+}
+
+type mockCode struct {
+	user      MockUser
+	nonce     string
+	challenge string
+	redirect  string
+	expiresAt time.Time
+}
+
+// NewMockIdP returns a provider issuing tokens as cfg.Issuer for the single
+// client cfg.ClientID.
+func NewMockIdP(cfg config.OIDC, users []MockUser) (*MockIdP, error) {
+	issuer, err := url.Parse(cfg.Issuer)
+	if err != nil || issuer.Host == "" {
+		return nil, fmt.Errorf("mock idp: invalid issuer %q", cfg.Issuer)
+	}
+	if strings.Trim(issuer.Path, "/") == "" {
+		return nil, fmt.Errorf("mock idp: issuer %q needs a path to be mounted at", cfg.Issuer)
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := randomToken(8)
+	if err != nil {
+		return nil, err
+	}
+	m := &MockIdP{
+		cfg:   cfg,
+		users: users,
+		key:   key,
+		kid:   kid,
+		path:  strings.TrimSuffix(issuer.Path, "/"),
+		mux:   http.NewServeMux(),
+		codes: map[string]mockCode{},
+	}
+	m.mux.HandleFunc("/.well-known/openid-configuration", m.discovery)
+	m.mux.HandleFunc("/authorize", m.authorize)
+	m.mux.HandleFunc("/token", m.token)
+	m.mux.HandleFunc("/jwks", m.jwks)
+	return m, nil
+}
+
+// Path is the URL path the provider must be mounted at (with the prefix
+// stripped before ServeHTTP).
+func (m *MockIdP) Path() string {
+	return m.path
+}
+
+// ServeHTTP serves the provider endpoints relative to Path.
+func (m *MockIdP) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mux.ServeHTTP(w, r)
+}
+
+// Client returns an HTTP client that serves requests to the issuer
+// in-process, so the backend does not need to reach itself over the
+// network. Other requests go out normally.
+func (m *MockIdP) Client() *http.Client {
+	return &http.Client{Transport: mockTransport{idp: m}, Timeout: 10 * time.Second}
+}
+
+type mockTransport struct {
+	idp *MockIdP
+}
+
+func (t mockTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	rel, ok := strings.CutPrefix(r.URL.String(), strings.TrimSuffix(t.idp.cfg.Issuer, "/"))
+	if !ok {
+		return http.DefaultTransport.RoundTrip(r)
+	}
+	inner := r.Clone(r.Context())
+	inner.URL, _ = url.Parse(rel)
+	inner.RequestURI = rel
+	rec := httptest.NewRecorder()
+	t.idp.ServeHTTP(rec, inner)
+	resp := rec.Result()
+	resp.Request = r
+	return resp, nil
+}
+
+func (m *MockIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	issuer := strings.TrimSuffix(m.cfg.Issuer, "/")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                m.cfg.Issuer,
+		"authorization_endpoint":                issuer + "/authorize",
+		"token_endpoint":                        issuer + "/token",
+		"jwks_uri":                              issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "profile", "email"},
+	})
+}
+
+var mockLoginPage = template.Must(template.New("login").Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>Mock IdP</title></head>
+<body style="font-family:sans-serif;max-width:28rem;margin:3rem auto">
+<h2>Mock IdP — hesap seçin</h2>
+<ul>{{range .}}<li style="margin:.5rem 0"><a href="{{.URL}}">{{.User.Name}}</a>
+<small>({{.User.Username}}, {{.User.Region}}{{range .User.Groups}}, {{.}}{{end}})</small></li>{{end}}</ul>
+</body></html>`))
+
+// authorize logs in the mock user named by login_hint, or shows a page to
+// pick one. Only the configured client and redirect URI are accepted.
+func (m *MockIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != m.cfg.ClientID || q.Get("redirect_uri") != m.cfg.RedirectURL {
+		http.Error(w, "unknown client or redirect_uri", http.StatusBadRequest)
+		return
+	}
+	redirect, _ := url.Parse(m.cfg.RedirectURL)
+	fail := func(code, desc string) {
+		v := url.Values{"error": {code}, "error_description": {desc}, "state": {q.Get("state")}}
+		redirect.RawQuery = v.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	}
+	if q.Get("response_type") != "code" {
+		fail("unsupported_response_type", "only code is supported")
+		return
+	}
+	if q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256" {
+		fail("invalid_request", "PKCE with S256 is required")
+		return
+	}
+	if !strings.Contains(" "+q.Get("scope")+" ", " openid ") {
+		fail("invalid_scope", "openid scope is required")
+		return
+	}
+
+	hint := q.Get("login_hint")
+	if hint == "" {
+		type choice struct {
+			User MockUser
+			URL  string
+		}
+		var choices []choice
+		for _, u := range m.users {
+			cq := r.URL.Query()
+			cq.Set("login_hint", u.Username)
+			choices = append(choices, choice{User: u, URL: m.path + "/authorize?" + cq.Encode()})
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = mockLoginPage.Execute(w, choices)
+		return
+	}
+	user, ok := m.user(hint)
+	if !ok {
+		fail("access_denied", "unknown mock user")
+		return
+	}
+
+	code, err := randomToken(24)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	m.mu.Lock()
+	now := time.Now()
+	for c, mc := range m.codes {
+		if now.After(mc.expiresAt) {
+			delete(m.codes, c)
+		}
+	}
+	m.codes[code] = mockCode{
+		user:      user,
+		nonce:     q.Get("nonce"),
+		challenge: q.Get("code_challenge"),
+		redirect:  q.Get("redirect_uri"),
+		expiresAt: now.Add(mockCodeTTL),
+	}
+	m.mu.Unlock()
+
+	redirect.RawQuery = url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// token redeems a code once, checking client, redirect URI and PKCE.
+func (m *MockIdP) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientID, secret, basic := r.BasicAuth()
+	if basic {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != m.cfg.ClientID ||
+		(m.cfg.ClientSecret != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(m.cfg.ClientSecret)) != 1) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	m.mu.Lock()
+	mc, ok := m.codes[code]
+	delete(m.codes, code) // kod tek kullanımlık
+	m.mu.Unlock()
+	invalid := func(desc string) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": desc})
+	}
+	switch {
+	case !ok || time.Now().After(mc.expiresAt):
+		invalid("unknown or expired code")
+		return
+	case r.PostForm.Get("redirect_uri") != mc.redirect:
+		invalid("redirect_uri mismatch")
+		return
+	case pkceChallenge(r.PostForm.Get("code_verifier")) != mc.challenge:
+		invalid("PKCE verification failed")
+		return
+	}
+
+	idToken, err := m.IDToken(mc.user, mc.nonce, time.Now())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	access, _ := randomToken(24)
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": access,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (m *MockIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := m.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": m.kid,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// IDToken signs an ID token for u; exported so tests can mint tokens.
+func (m *MockIdP) IDToken(u MockUser, nonce string, now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": m.kid})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iss":                m.cfg.Issuer,
+		"sub":                u.Subject,
+		"aud":                m.cfg.ClientID,
+		"azp":                m.cfg.ClientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              nonce,
+		"name":               u.Name,
+		"preferred_username": u.Username,
+		"email":              u.Email,
+		"groups":             u.Groups,
+		"region":             u.Region,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, m.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func (m *MockIdP) user(name string) (MockUser, bool) {
+	for _, u := range m.users {
+		if u.Username == name || u.Subject == name {
+			return u, true
+		}
+	}
+	return MockUser{}, false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,506 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"geo-repl-demo/internal/config"
+)
+
+// OIDC errors.
+var (
+	ErrOIDCDisabled = errors.New("oidc login is not configured")
+	ErrOIDCFlow     = errors.New("oidc login expired or was started elsewhere")
+	ErrIDToken      = errors.New("invalid id token")
+)
+
+const (
+	oidcFlowCookie = "oidc_flow"
+	oidcFlowTTL    = 10 * time.Minute
+	// oidcLeeway tolerates clock skew between us and the IdP.
+	oidcLeeway = time.Minute
+	// jwksMinRefresh limits JWKS refetches triggered by unknown key IDs.
+	jwksMinRefresh = time.Minute
+)
+
+// roleRank orders roles so that the highest mapped role wins.
+var roleRank = map[string]int{RoleReader: 1, RoleWriter: 2, RoleEditor: 3, RoleAdmin: 4}
+
+// IDClaims are the validated claims of an ID token.
+type IDClaims struct {
+	Issuer            string
+	Subject           string
+	Name              string
+	PreferredUsername string
+	Email             string
+	// Raw holds every claim, for role and region mapping.
+	Raw map[string]any
+}
+
+// providerMetadata is the part of the discovery document we use.
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcFlow is kept in a signed cookie between login and callback.
+type oidcFlow struct {
+	State     string    `json:"state"`
+	Nonce     string    `json:"nonce"`
+	Verifier  string    `json:"verifier"`
+	ExpiresAt time.Time `json:"exp"`
+}
+
+// OIDC is an OpenID Connect relying party for the authorization-code flow
+// with PKCE. Discovery and signing keys are fetched lazily and cached, so
+// the IdP need not be reachable at startup.
+type OIDC struct {
+	cfg    config.OIDC
+	client *http.Client
+	flows  *Signer
+
+	mu          sync.Mutex
+	meta        *providerMetadata
+	keys        map[string]*rsa.PublicKey
+	keysFetched time.Time
+}
+
+// NewOIDC returns a relying party. flowKey signs the login-flow cookie;
+// client is used for all IdP requests (nil means http.DefaultClient).
+func NewOIDC(cfg config.OIDC, flowKey []byte, client *http.Client) *OIDC {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &OIDC{cfg: cfg, client: client, flows: NewSigner(flowKey)}
+}
+
+// Mock reports whether the embedded mock IdP is in use.
+func (o *OIDC) Mock() bool {
+	return o.cfg.Mock
+}
+
+// PostLoginURL is where the browser goes after the callback.
+func (o *OIDC) PostLoginURL() string {
+	return o.cfg.PostLoginURL
+}
+
+// Start begins a login: it returns the IdP authorization URL and the
+// cookie binding the callback to this browser.
+func (o *OIDC) Start(ctx context.Context, secure bool) (string, *http.Cookie, error) {
+	meta, err := o.discover(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	flow := oidcFlow{ExpiresAt: time.Now().Add(oidcFlowTTL).UTC()}
+	for _, p := range []*string{&flow.State, &flow.Nonce, &flow.Verifier} {
+		if *p, err = randomToken(32); err != nil {
+			return "", nil, err
+		}
+	}
+	value, err := o.flows.Sign(flow)
+	if err != nil {
+		return "", nil, err
+	}
+
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {o.cfg.ClientID},
+		"redirect_uri":          {o.cfg.RedirectURL},
+		"scope":                 {"openid profile email"},
+		"state":                 {flow.State},
+		"nonce":                 {flow.Nonce},
+		"code_challenge":        {pkceChallenge(flow.Verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + q.Encode(), o.flowCookie(value, flow.ExpiresAt, secure), nil
+}
+
+// Finish handles the callback request: it checks state against the flow
+// cookie, redeems the code with the PKCE verifier and validates the ID
+// token. The returned cookie clears the flow.
+func (o *OIDC) Finish(ctx context.Context, r *http.Request) (IDClaims, *http.Cookie, error) {
+	clear := o.flowCookie("", time.Time{}, r.TLS != nil)
+	ck, err := r.Cookie(oidcFlowCookie)
+	if err != nil {
+		return IDClaims{}, clear, ErrOIDCFlow
+	}
+	var flow oidcFlow
+	if err := o.flows.Verify(ck.Value, &flow); err != nil || time.Now().After(flow.ExpiresAt) {
+		return IDClaims{}, clear, ErrOIDCFlow
+	}
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		return IDClaims{}, clear, fmt.Errorf("identity provider: %s %s", e, q.Get("error_description"))
+	}
+	if q.Get("state") != flow.State {
+		return IDClaims{}, clear, ErrOIDCFlow
+	}
+	code := q.Get("code")
+	if code == "" {
+		return IDClaims{}, clear, fmt.Errorf("%w: missing code", ErrOIDCFlow)
+	}
+
+	meta, err := o.discover(ctx)
+	if err != nil {
+		return IDClaims{}, clear, err
+	}
+	raw, err := o.exchange(ctx, meta, code, flow.Verifier)
+	if err != nil {
+		return IDClaims{}, clear, err
+	}
+	claims, err := o.VerifyIDToken(ctx, raw, flow.Nonce)
+	return claims, clear, err
+}
+
+// VerifyIDToken validates an RS256 ID token: signature against the IdP's
+// JWKS, issuer, audience, authorized party, expiry and nonce.
+func (o *OIDC) VerifyIDToken(ctx context.Context, raw, nonce string) (IDClaims, error) {
+	meta, err := o.discover(ctx)
+	if err != nil {
+		return IDClaims{}, err
+	}
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return IDClaims{}, fmt.Errorf("%w: malformed", ErrIDToken)
+	}
+
+	// Yalnızca RS256: "none" ve HS256 (anahtar karışıklığı) reddedilir
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "RS256" {
+		return IDClaims{}, fmt.Errorf("%w: unsupported algorithm", ErrIDToken)
+	}
+	key, err := o.key(ctx, meta, header.Kid)
+	if err != nil {
+		return IDClaims{}, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return IDClaims{}, fmt.Errorf("%w: malformed signature", ErrIDToken)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return IDClaims{}, fmt.Errorf("%w: bad signature", ErrIDToken)
+	}
+
+	var c struct {
+		Issuer            string   `json:"iss"`
+		Subject           string   `json:"sub"`
+		Audience          audience `json:"aud"`
+		AuthorizedParty   string   `json:"azp"`
+		ExpiresAt         int64    `json:"exp"`
+		IssuedAt          int64    `json:"iat"`
+		Nonce             string   `json:"nonce"`
+		Name              string   `json:"name"`
+		PreferredUsername string   `json:"preferred_username"`
+		Email             string   `json:"email"`
+	}
+	var all map[string]any
+	if decodeSegment(parts[1], &c) != nil || decodeSegment(parts[1], &all) != nil {
+		return IDClaims{}, fmt.Errorf("%w: malformed claims", ErrIDToken)
+	}
+
+	now := time.Now()
+	switch {
+	case c.Issuer != meta.Issuer:
+		return IDClaims{}, fmt.Errorf("%w: issuer %q", ErrIDToken, c.Issuer)
+	case c.Subject == "":
+		return IDClaims{}, fmt.Errorf("%w: missing subject", ErrIDToken)
+	case !c.Audience.contains(o.cfg.ClientID):
+		return IDClaims{}, fmt.Errorf("%w: audience", ErrIDToken)
+	case len(c.Audience) > 1 && c.AuthorizedParty != o.cfg.ClientID:
+		return IDClaims{}, fmt.Errorf("%w: authorized party", ErrIDToken)
+	case now.Add(-oidcLeeway).Unix() >= c.ExpiresAt:
+		return IDClaims{}, fmt.Errorf("%w: expired", ErrIDToken)
+	case c.IssuedAt > now.Add(oidcLeeway).Unix():
+		return IDClaims{}, fmt.Errorf("%w: issued in the future", ErrIDToken)
+	case nonce != "" && c.Nonce != nonce:
+		return IDClaims{}, fmt.Errorf("%w: nonce", ErrIDToken)
+	}
+	return IDClaims{
+		Issuer:            c.Issuer,
+		Subject:           c.Subject,
+		Name:              c.Name,
+		PreferredUsername: c.PreferredUsername,
+		Email:             c.Email,
+		Raw:               all,
+	}, nil
+}
+
+// Role maps the configured role claim to a role; the highest mapped value
+// wins and unmapped users get the default role.
+func (o *OIDC) Role(c IDClaims) string {
+	role := o.cfg.DefaultRole
+	if !ValidRole(role) {
+		role = RoleReader
+	}
+	for _, v := range claimStrings(c.Raw[o.cfg.RoleClaim]) {
+		if mapped, ok := o.cfg.RoleMap[v]; ok && roleRank[mapped] > roleRank[role] {
+			role = mapped
+		}
+	}
+	return role
+}
+
+// Region returns the value of the configured region claim, if any.
+func (o *OIDC) Region(c IDClaims) string {
+	if vs := claimStrings(c.Raw[o.cfg.RegionClaim]); len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+// discover fetches and caches the discovery document. Per the spec its
+// issuer must match the configured one exactly.
+func (o *OIDC) discover(ctx context.Context) (providerMetadata, error) {
+	if !o.cfg.Enabled() {
+		return providerMetadata{}, ErrOIDCDisabled
+	}
+	o.mu.Lock()
+	if o.meta != nil {
+		defer o.mu.Unlock()
+		return *o.meta, nil
+	}
+	o.mu.Unlock()
+
+	var meta providerMetadata
+	wellKnown := strings.TrimSuffix(o.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := o.getJSON(ctx, wellKnown, &meta); err != nil {
+		return providerMetadata{}, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if meta.Issuer != o.cfg.Issuer {
+		return providerMetadata{}, fmt.Errorf("oidc discovery: issuer %q does not match %q", meta.Issuer, o.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return providerMetadata{}, errors.New("oidc discovery: incomplete provider metadata")
+	}
+
+	o.mu.Lock()
+	o.meta = &meta
+	o.mu.Unlock()
+	return meta, nil
+}
+
+// key returns the signing key kid, refetching the JWKS when the IdP has
+// rotated keys.
+func (o *OIDC) key(ctx context.Context, meta providerMetadata, kid string) (*rsa.PublicKey, error) {
+	o.mu.Lock()
+	k, ok := o.lookupKey(kid)
+	stale := time.Since(o.keysFetched) >= jwksMinRefresh
+	o.mu.Unlock()
+	if ok {
+		return k, nil
+	}
+	if !stale {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrIDToken, kid)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := o.getJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("oidc jwks: %w", err)
+	}
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, err1 := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, err2 := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err1 != nil || err2 != nil || len(e) == 0 || len(e) > 4 {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.keys = keys
+	o.keysFetched = time.Now()
+	if k, ok := o.lookupKey(kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("%w: unknown key %q", ErrIDToken, kid)
+}
+
+// lookupKey finds kid in the cached set; a token without kid is accepted
+// only when the IdP publishes a single key. Callers hold o.mu.
+func (o *OIDC) lookupKey(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(o.keys) == 1 {
+		for _, k := range o.keys {
+			return k, true
+		}
+	}
+	k, ok := o.keys[kid]
+	return k, ok
+}
+
+// exchange redeems an authorization code at the token endpoint.
+func (o *OIDC) exchange(ctx context.Context, meta providerMetadata, code, verifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.cfg.RedirectURL},
+		"client_id":     {o.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if o.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(o.cfg.ClientID), url.QueryEscape(o.cfg.ClientSecret))
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oidc token: %w", err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("oidc token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return "", fmt.Errorf("oidc token: %s %s (HTTP %d)", body.Error, body.ErrorDescription, resp.StatusCode)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("oidc token: response has no id_token")
+	}
+	return body.IDToken, nil
+}
+
+func (o *OIDC) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: HTTP %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+func (o *OIDC) flowCookie(value string, expires time.Time, secure bool) *http.Cookie {
+	ck := &http.Cookie{
+		Name:     oidcFlowCookie,
+		Value:    value,
+		Path:     "/api/oidc",
+		HttpOnly: true,
+		Secure:   secure,
+		// Lax: callback, IdP'den gelen üst düzey GET yönlendirmesidir
+		SameSite: http.SameSiteLaxMode,
+	}
+	if value == "" {
+		ck.MaxAge = -1
+	} else {
+		ck.Expires = expires
+	}
+	return ck
+}
+
+// audience is the "aud" claim, which may be a string or a list.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a audience) contains(v string) bool {
+	for _, x := range a {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// claimStrings reads a claim that may be a string or a list of strings.
+func claimStrings(v any) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		out := make([]string, 0, len(t))
+		for _, x := range t {
+			if s, ok := x.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func decodeSegment(seg string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// pkceChallenge is the S256 code challenge of verifier (RFC 7636).
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
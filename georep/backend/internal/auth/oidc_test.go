@@ -0,0 +1,279 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"geo-repl-demo/internal/config"
+)
+
+func testOIDCConfig() config.OIDC {
+	return config.OIDC{
+		Issuer:      "http://idp.test/mock-idp",
+		ClientID:    "geo-writer-ui",
+		RedirectURL: "http://app.test/api/oidc/callback",
+		RoleClaim:   "groups",
+		RoleMap:     map[string]string{"geo-admins": RoleAdmin, "geo-writers": RoleWriter},
+		DefaultRole: RoleReader,
+		RegionClaim: "region",
+		Mock:        true,
+	}
+}
+
+// newTestOIDC returns a relying party talking in-process to a mock IdP.
+func newTestOIDC(t *testing.T) (*OIDC, *MockIdP) {
+	t.Helper()
+	cfg := testOIDCConfig()
+	idp, err := NewMockIdP(cfg, DefaultMockUsers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewOIDC(cfg, []byte("test-flow-key"), idp.Client()), idp
+}
+
+// authorize follows the authorization URL at the mock IdP as user and
+// returns the callback URL it redirects to.
+func authorize(t *testing.T, idp *MockIdP, authURL, user string) *url.URL {
+	t.Helper()
+	client := idp.Client()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	if user != "" {
+		authURL += "&login_hint=" + url.QueryEscape(user)
+	}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: HTTP %d", resp.StatusCode)
+	}
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func callback(loc *url.URL, ck *http.Cookie) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, loc.String(), nil)
+	if ck != nil {
+		r.AddCookie(ck)
+	}
+	return r
+}
+
+func TestOIDCLoginWithMockIdP(t *testing.T) {
+	rp, idp := newTestOIDC(t)
+	ctx := context.Background()
+
+	authURL, ck, err := rp.Start(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, _ := url.Parse(authURL)
+	if q.Query().Get("code_challenge_method") != "S256" || q.Query().Get("nonce") == "" || q.Query().Get("state") == "" {
+		t.Fatalf("authorization URL lacks PKCE, nonce or state: %s", authURL)
+	}
+
+	claims, clear, err := rp.Finish(ctx, callback(authorize(t, idp, authURL, "ayse"), ck))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "mock-1" || claims.PreferredUsername != "ayse" {
+		t.Errorf("claims = %+v", claims)
+	}
+	if got := rp.Role(claims); got != RoleAdmin {
+		t.Errorf("Role = %q, want %q", got, RoleAdmin)
+	}
+	if got := rp.Region(claims); got != "tr" {
+		t.Errorf("Region = %q, want tr", got)
+	}
+	if clear.MaxAge >= 0 {
+		t.Errorf("flow cookie is not cleared: %+v", clear)
+	}
+}
+
+func TestOIDCFinishRejects(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		// tamper changes the callback URL and flow cookie of a valid login.
+		tamper func(rp *OIDC, loc *url.URL, ck *http.Cookie) (*url.URL, *http.Cookie)
+		want   error
+		msg    string // for errors reported by the IdP
+	}{
+		{
+			name:   "no flow cookie",
+			tamper: func(_ *OIDC, loc *url.URL, _ *http.Cookie) (*url.URL, *http.Cookie) { return loc, nil },
+			want:   ErrOIDCFlow,
+		},
+		{
+			name: "forged flow cookie",
+			tamper: func(_ *OIDC, loc *url.URL, ck *http.Cookie) (*url.URL, *http.Cookie) {
+				forged := *ck
+				forged.Value = ck.Value[:len(ck.Value)-2] + "AA"
+				return loc, &forged
+			},
+			want: ErrOIDCFlow,
+		},
+		{
+			name: "state mismatch",
+			tamper: func(_ *OIDC, loc *url.URL, ck *http.Cookie) (*url.URL, *http.Cookie) {
+				q := loc.Query()
+				q.Set("state", "attacker")
+				loc.RawQuery = q.Encode()
+				return loc, ck
+			},
+			want: ErrOIDCFlow,
+		},
+		{
+			name: "wrong PKCE verifier",
+			tamper: func(rp *OIDC, loc *url.URL, ck *http.Cookie) (*url.URL, *http.Cookie) {
+				return loc, reflow(t, rp, ck, func(f *oidcFlow) { f.Verifier = "not-the-verifier" })
+			},
+			msg: "PKCE verification failed",
+		},
+		{
+			name: "wrong nonce",
+			tamper: func(rp *OIDC, loc *url.URL, ck *http.Cookie) (*url.URL, *http.Cookie) {
+				return loc, reflow(t, rp, ck, func(f *oidcFlow) { f.Nonce = "replayed-nonce" })
+			},
+			want: ErrIDToken,
+		},
+		{
+			name: "expired flow",
+			tamper: func(rp *OIDC, loc *url.URL, ck *http.Cookie) (*url.URL, *http.Cookie) {
+				return loc, reflow(t, rp, ck, func(f *oidcFlow) { f.ExpiresAt = time.Now().Add(-time.Second) })
+			},
+			want: ErrOIDCFlow,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp, idp := newTestOIDC(t)
+			authURL, ck, err := rp.Start(ctx, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loc, ck := tt.tamper(rp, authorize(t, idp, authURL, "maria"), ck)
+			_, _, err = rp.Finish(ctx, callback(loc, ck))
+			if err == nil {
+				t.Fatal("Finish succeeded")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			if tt.msg != "" && !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("err = %v, want %q", err, tt.msg)
+			}
+		})
+	}
+}
+
+// reflow re-signs the flow cookie after changing it, as if the callback
+// belonged to another login of the same browser.
+func reflow(t *testing.T, rp *OIDC, ck *http.Cookie, change func(*oidcFlow)) *http.Cookie {
+	t.Helper()
+	var flow oidcFlow
+	if err := rp.flows.Verify(ck.Value, &flow); err != nil {
+		t.Fatal(err)
+	}
+	change(&flow)
+	value, err := rp.flows.Sign(flow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rp.flowCookie(value, flow.ExpiresAt, false)
+}
+
+func TestOIDCCodeIsSingleUse(t *testing.T) {
+	rp, idp := newTestOIDC(t)
+	ctx := context.Background()
+	authURL, ck, err := rp.Start(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := authorize(t, idp, authURL, "john")
+	if _, _, err := rp.Finish(ctx, callback(loc, ck)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := rp.Finish(ctx, callback(loc, ck)); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("second redemption: err = %v, want invalid_grant", err)
+	}
+}
+
+func TestMockIdPRequiresPKCE(t *testing.T) {
+	rp, idp := newTestOIDC(t)
+	authURL, _, err := rp.Start(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(authURL)
+	q := u.Query()
+	q.Del("code_challenge")
+	u.RawQuery = q.Encode()
+
+	loc := authorize(t, idp, u.String(), "ayse")
+	if loc.Query().Get("error") != "invalid_request" || loc.Query().Get("code") != "" {
+		t.Errorf("authorize without PKCE redirected to %s", loc)
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	rp, idp := newTestOIDC(t)
+	other, err := NewMockIdP(testOIDCConfig(), DefaultMockUsers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	user := DefaultMockUsers[2]
+	now := time.Now()
+
+	mint := func(m *MockIdP, nonce string, at time.Time) string {
+		tok, err := m.IDToken(user, nonce, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tok
+	}
+	valid := mint(idp, "n1", now)
+	parts := strings.Split(valid, ".")
+	claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	forged := strings.Replace(string(claims), `"geo-writers"`, `"geo-admins"`, 1)
+
+	tests := []struct {
+		name  string
+		token string
+		nonce string
+		ok    bool
+	}{
+		{"valid", valid, "n1", true},
+		{"nonce mismatch", valid, "n2", false},
+		{"signed by another key", mint(other, "n1", now), "n1", false},
+		{"claims changed after signing", parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(forged)) + "." + parts[2], "n1", false},
+		{"alg none", base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + ".", "n1", false},
+		{"expired", mint(idp, "n1", now.Add(-time.Hour)), "n1", false},
+		{"issued in the future", mint(idp, "n1", now.Add(time.Hour)), "n1", false},
+		{"malformed", "a.b", "n1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := rp.VerifyIDToken(ctx, tt.token, tt.nonce)
+			if tt.ok && err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrIDToken) {
+				t.Fatalf("err = %v, want ErrIDToken", err)
+			}
+		})
+	}
+}
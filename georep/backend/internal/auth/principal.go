@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"geo-repl-demo/internal/audit"
+)
+
+// Roles.
+const (
+	RoleReader = "reader"
+	RoleWriter = "writer"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	switch role {
+	case RoleReader, RoleWriter, RoleEditor, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// Context keys: the authenticated Principal, or the reason a presented
+// bearer token was rejected.
+const (
+	principalKey = "principal"
+	authErrorKey = "auth_error"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID   int64  `json:"user_id,omitempty"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	Region   string `json:"region"`
+	// KeyID and Scopes are set when the caller authenticated with an API
+	// key; the scopes further restrict what the owner's role allows.
+	KeyID  string   `json:"key_id,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// IsAdmin reports whether the principal has the admin role.
+func (p Principal) IsAdmin() bool {
+	return p.Role == RoleAdmin
+}
+
+// IsAPIKey reports whether the principal authenticated with an API key.
+func (p Principal) IsAPIKey() bool {
+	return p.KeyID != ""
+}
+
+// TokenPair is returned by login, register and refresh.
+type TokenPair struct {
+	AccessToken      string    `json:"access_token"`
+	AccessExpiresAt  time.Time `json:"access_expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// Authenticator issues and verifies bearer tokens.
+type Authenticator struct {
+	key         []byte
+	adminTokens []string
+	accessTTL   time.Duration
+	refreshTTL  time.Duration
+	keys        KeyResolver
+}
+
+// KeyResolver resolves API keys presented instead of a JWT.
+type KeyResolver interface {
+	// IsKey reports whether token looks like an API key.
+	IsKey(token string) bool
+	ResolveKey(ctx context.Context, token string) (Principal, error)
+}
+
+// NewAuthenticator returns an Authenticator signing JWTs with key.
+// adminTokens are static operator tokens that authenticate as admin.
+func NewAuthenticator(key []byte, adminTokens []string) *Authenticator {
+	return &Authenticator{
+		key:         key,
+		adminTokens: adminTokens,
+		accessTTL:   15 * time.Minute,
+		refreshTTL:  30 * 24 * time.Hour,
+	}
+}
+
+// UseKeys enables API key authentication.
+func (a *Authenticator) UseKeys(keys KeyResolver) {
+	a.keys = keys
+}
+
+// Issue returns a fresh access/refresh token pair for p.
+func (a *Authenticator) Issue(p Principal) (TokenPair, error) {
+	now := time.Now().UTC()
+	access, err := a.sign(p, TokenAccess, now, a.accessTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refresh, err := a.sign(p, TokenRefresh, now, a.refreshTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{
+		AccessToken:      access,
+		AccessExpiresAt:  now.Add(a.accessTTL),
+		RefreshToken:     refresh,
+		RefreshExpiresAt: now.Add(a.refreshTTL),
+	}, nil
+}
+
+func (a *Authenticator) sign(p Principal, typ string, now time.Time, ttl time.Duration) (string, error) {
+	return signJWT(a.key, Claims{
+		Subject:   strconv.FormatInt(p.UserID, 10),
+		Username:  p.Username,
+		Role:      p.Role,
+		Region:    p.Region,
+		Type:      typ,
+		Issuer:    jwtIssuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	})
+}
+
+// Verify resolves an access token (or a static admin token) to a principal.
+func (a *Authenticator) Verify(token string) (Principal, error) {
+	for _, admin := range a.adminTokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(admin)) == 1 {
+			return Principal{Username: "admin-token", Role: RoleAdmin}, nil
+		}
+	}
+	return a.verify(token, TokenAccess)
+}
+
+// VerifyRefresh resolves a refresh token to the principal it was issued to.
+func (a *Authenticator) VerifyRefresh(token string) (Principal, error) {
+	return a.verify(token, TokenRefresh)
+}
+
+func (a *Authenticator) verify(token, typ string) (Principal, error) {
+	c, err := parseJWT(a.key, token, time.Now())
+	if err != nil {
+		return Principal{}, err
+	}
+	if c.Type != typ {
+		return Principal{}, ErrWrongTokenType
+	}
+	id, err := strconv.ParseInt(c.Subject, 10, 64)
+	if err != nil {
+		return Principal{}, ErrMalformedToken
+	}
+	return Principal{UserID: id, Username: c.Username, Role: c.Role, Region: c.Region}, nil
+}
+
+// Middleware stores the principal of a valid "Authorization: Bearer" token
+// (or "X-API-Key" header) in the context. Requests without a valid token
+// continue anonymously; routes that need a principal use Require or
+// Authenticated, which report why a presented token was rejected.
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if key := c.GetHeader("X-API-Key"); !ok && key != "" {
+			token, ok = key, true
+		}
+		if ok {
+			token = strings.TrimSpace(token)
+			var p Principal
+			var err error
+			if a.keys != nil && a.keys.IsKey(token) {
+				p, err = a.keys.ResolveKey(c.Request.Context(), token)
+			} else {
+				p, err = a.Verify(token)
+			}
+			if err != nil {
+				c.Set(authErrorKey, err)
+			} else {
+				c.Set(principalKey, p)
+			}
+		}
+		c.Next()
+	}
+}
+
+// FromContext returns the authenticated principal of the request, if any.
+func FromContext(c *gin.Context) (Principal, bool) {
+	v, ok := c.Get(principalKey)
+	if !ok {
+		return Principal{}, false
+	}
+	p, ok := v.(Principal)
+	return p, ok
+}
+
+// AuditEvent returns an audit event for an action by p on entity/target,
+// stamped with the request metadata in ctx.
+func (p Principal) AuditEvent(ctx context.Context, action, entity, target string) audit.Event {
+	e := audit.Event{
+		Actor:   p.Username,
+		ActorID: p.UserID,
+		Role:    p.Role,
+		Action:  action,
+		Entity:  entity,
+		Target:  target,
+		Outcome: audit.Allowed,
+	}
+	if e.Actor == "" {
+		e.Actor = "anonymous"
+	}
+	if p.IsAPIKey() {
+		e.Detail = map[string]string{"key_id": p.KeyID}
+	}
+	return audit.Stamp(ctx, e)
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"geo-repl-demo/internal/db"
+	"geo-repl-demo/internal/model"
+	"geo-repl-demo/internal/region"
+)
+
+type Repository struct {
+	master   *db.Master
+	replicas *db.ReplicaSet
+	regions  *region.Registry
+}
+
+func NewRepository(master *db.Master, replicas *db.ReplicaSet, regions *region.Registry) *Repository {
+	return &Repository{master: master, replicas: replicas, regions: regions}
+}
+
+// InsertUser creates a user on the master.
+func (r *Repository) InsertUser(ctx context.Context, u model.User) (model.User, error) {
+	var out model.User
+	row := r.master.Pool.QueryRow(ctx, `
+		INSERT INTO users (username, password_hash, role, home_region, display_name, oidc_subject)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))
+		RETURNING `+db.UserColumns,
+		u.Username, u.PasswordHash, u.Role, u.HomeRegion, u.DisplayName, u.OIDCSubject)
+	if err := db.ScanUser(row, &out); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			if pgErr.ConstraintName == "users_oidc_subject_key" {
+				return model.User{}, ErrSubjectTaken
+			}
+			return model.User{}, ErrUsernameTaken
+		}
+		return model.User{}, fmt.Errorf("insert user: %w", err)
+	}
+	return out, nil
+}
+
+// FindByUsername reads a user from the region's replica. Users registered
+// moments ago may not have been replicated yet, so a miss falls back to the
+// master.
+func (r *Repository) FindByUsername(ctx context.Context, region, username string) (model.User, error) {
+	return r.find(ctx, region, `username=$1`, username)
+}
+
+// FindByID reads a user by ID (replica first, then master).
+func (r *Repository) FindByID(ctx context.Context, region string, id int64) (model.User, error) {
+	return r.find(ctx, region, `id=$1`, id)
+}
+
+// FindByOIDCSubject reads the user linked to an IdP account from the master.
+func (r *Repository) FindByOIDCSubject(ctx context.Context, subject string) (model.User, error) {
+	var u model.User
+	err := db.ScanUser(r.master.Pool.QueryRow(ctx,
+		`SELECT `+db.UserColumns+` FROM users WHERE oidc_subject=$1`, subject), &u)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.User{}, ErrUserNotFound
+	}
+	return u, err
+}
+
+// UpdateProfile sets the IdP-managed fields of a user on the master.
+func (r *Repository) UpdateProfile(ctx context.Context, id int64, role, homeRegion, displayName string) (model.User, error) {
+	var u model.User
+	err := db.ScanUser(r.master.Pool.QueryRow(ctx, `
+		UPDATE users SET role=$2, home_region=$3, display_name=$4, updated_at=NOW()
+		WHERE id=$1
+		RETURNING `+db.UserColumns, id, role, homeRegion, displayName), &u)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.User{}, ErrUserNotFound
+	}
+	return u, err
+}
+
+func (r *Repository) find(ctx context.Context, region, where string, arg any) (model.User, error) {
+	query := `SELECT ` + db.UserColumns + ` FROM users WHERE ` + where
+	pool := r.poolForRegion(region)
+
+	var u model.User
+	err := db.ScanUser(pool.QueryRow(ctx, query, arg), &u)
+	if errors.Is(err, pgx.ErrNoRows) && pool != r.master.Pool {
+		err = db.ScanUser(r.master.Pool.QueryRow(ctx, query, arg), &u)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.User{}, ErrUserNotFound
+	}
+	return u, err
+}
+
+func (r *Repository) poolForRegion(region string) *pgxpool.Pool {
+	node := r.regions.NodeFor(region)
+	if r.replicas != nil {
+		if rep, ok := r.replicas.Get(node.Name); ok && rep.Healthy() {
+			return rep.Pool
+		}
+	}
+	return r.master.Pool
+}
@@ -0,0 +1,337 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"geo-repl-demo/internal/audit"
+	"geo-repl-demo/internal/model"
+	"geo-repl-demo/internal/region"
+	"geo-repl-demo/internal/replication"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrUsernameTaken      = errors.New("username already taken")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidInput       = errors.New("invalid input")
+	ErrRoleNotAllowed     = errors.New("role requires an admin")
+	// ErrSubjectTaken is returned when two logins race to link the same
+	// IdP account.
+	ErrSubjectTaken = errors.New("identity provider account already linked")
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9_.-]{3,32}$`)
+
+const minPasswordLen = 8
+
+type RegisterInput struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	Role     string `json:"role"`
+	Region   string `json:"region"`
+	// DisplayName is shown as the author of the user's articles.
+	DisplayName string `json:"display_name"`
+}
+
+// Service handles users and token issuance.
+type Service struct {
+	repo       *Repository
+	replicator *replication.Replicator
+	authn      *Authenticator
+	regions    *region.Registry
+	audit      audit.Sink
+}
+
+func NewService(repo *Repository, replicator *replication.Replicator, authn *Authenticator, regions *region.Registry, sink audit.Sink) *Service {
+	return &Service{repo: repo, replicator: replicator, authn: authn, regions: regions, audit: sink}
+}
+
+// Register creates a user on the master and replicates it. Anyone may
+// register as reader; other roles require an admin caller.
+// routed is the region the request was routed to, used as the default
+// home region.
+func (s *Service) Register(ctx context.Context, in RegisterInput, caller Principal, routed string) (model.User, TokenPair, error) {
+	u, pair, err := s.register(ctx, in, caller, routed)
+	e := caller.AuditEvent(ctx, "auth.register", "user", strings.ToLower(strings.TrimSpace(in.Username)))
+	if err == nil {
+		e.AfterHash = audit.Hash(u)
+	}
+	s.record(ctx, e, err)
+	return u, pair, err
+}
+
+func (s *Service) register(ctx context.Context, in RegisterInput, caller Principal, routed string) (model.User, TokenPair, error) {
+	username := strings.ToLower(strings.TrimSpace(in.Username))
+	if !usernamePattern.MatchString(username) {
+		return model.User{}, TokenPair{}, fmt.Errorf("%w: username must be 3-32 characters of a-z, 0-9, _ . -", ErrInvalidInput)
+	}
+	if len(in.Password) < minPasswordLen {
+		return model.User{}, TokenPair{}, fmt.Errorf("%w: password must be at least %d characters", ErrInvalidInput, minPasswordLen)
+	}
+
+	role, err := registerRole(in.Role, caller)
+	if err != nil {
+		return model.User{}, TokenPair{}, err
+	}
+
+	home := s.regions.Resolve(routed)
+	if in.Region != "" {
+		reg, ok := s.regions.Lookup(in.Region)
+		if !ok {
+			return model.User{}, TokenPair{}, fmt.Errorf("%w: unknown region %q", ErrInvalidInput, in.Region)
+		}
+		home = reg
+	}
+
+	display := strings.TrimSpace(in.DisplayName)
+	if len(display) > 64 {
+		return model.User{}, TokenPair{}, fmt.Errorf("%w: display_name must be at most 64 characters", ErrInvalidInput)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(in.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return model.User{}, TokenPair{}, err
+	}
+	u, err := s.repo.InsertUser(ctx, model.User{
+		Username:     username,
+		PasswordHash: string(hash),
+		Role:         role,
+		HomeRegion:   home.ID,
+		DisplayName:  display,
+	})
+	if err != nil {
+		return model.User{}, TokenPair{}, err
+	}
+
+	if s.replicator != nil {
+		go s.replicator.ScheduleUser(u)
+	}
+
+	pair, err := s.authn.Issue(principalOf(u))
+	return u, pair, err
+}
+
+// registerRole validates the requested role. Self-registration only makes
+// readers: a writer could create articles, so it takes an admin like the
+// other roles.
+func registerRole(requested string, caller Principal) (string, error) {
+	role := strings.ToLower(strings.TrimSpace(requested))
+	if role == "" {
+		role = RoleReader
+	}
+	if !ValidRole(role) {
+		return "", fmt.Errorf("%w: unknown role %q", ErrInvalidInput, role)
+	}
+	if role != RoleReader && !caller.IsAdmin() {
+		return "", ErrRoleNotAllowed
+	}
+	return role, nil
+}
+
+// Login checks the password against the replica of the routed region.
+func (s *Service) Login(ctx context.Context, username, password, routed string) (model.User, TokenPair, error) {
+	u, pair, err := s.login(ctx, username, password, routed)
+	s.recordLogin(ctx, "auth.login", username, u, err)
+	return u, pair, err
+}
+
+func (s *Service) login(ctx context.Context, username, password, routed string) (model.User, TokenPair, error) {
+	u, err := s.repo.FindByUsername(ctx, routed, strings.ToLower(strings.TrimSpace(username)))
+	if errors.Is(err, ErrUserNotFound) {
+		// Zamanlama farkından kullanıcı adı sızmasın
+		_ = bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return model.User{}, TokenPair{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return model.User{}, TokenPair{}, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		return model.User{}, TokenPair{}, ErrInvalidCredentials
+	}
+
+	pair, err := s.authn.Issue(principalOf(u))
+	return u, pair, err
+}
+
+// Refresh exchanges a refresh token for a new pair. The user is re-read so
+// role changes take effect.
+func (s *Service) Refresh(ctx context.Context, refreshToken, routed string) (model.User, TokenPair, error) {
+	p, err := s.authn.VerifyRefresh(refreshToken)
+	if err != nil {
+		return model.User{}, TokenPair{}, ErrInvalidCredentials
+	}
+	u, err := s.repo.FindByID(ctx, routed, p.UserID)
+	if errors.Is(err, ErrUserNotFound) {
+		return model.User{}, TokenPair{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return model.User{}, TokenPair{}, err
+	}
+
+	pair, err := s.authn.Issue(principalOf(u))
+	return u, pair, err
+}
+
+// LoginOIDC signs in the user linked to a validated ID token, creating it
+// on first login. Role, home region and display name are managed by the
+// IdP and refreshed on every login.
+func (s *Service) LoginOIDC(ctx context.Context, c IDClaims, role, region, routed string) (model.User, TokenPair, error) {
+	u, pair, err := s.loginOIDC(ctx, c, role, region, routed)
+	s.recordLogin(ctx, "auth.login.oidc", c.Issuer+"|"+c.Subject, u, err)
+	return u, pair, err
+}
+
+func (s *Service) loginOIDC(ctx context.Context, c IDClaims, role, region, routed string) (model.User, TokenPair, error) {
+	if !ValidRole(role) {
+		return model.User{}, TokenPair{}, fmt.Errorf("%w: unknown role %q", ErrInvalidInput, role)
+	}
+	home, ok := s.regions.Lookup(region)
+	if !ok {
+		home = s.regions.Resolve(routed)
+	}
+	subject := c.Issuer + "|" + c.Subject
+	display := displayName(c.Name)
+
+	u, err := s.repo.FindByOIDCSubject(ctx, subject)
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		u, err = s.provisionOIDC(ctx, c, subject, role, home.ID, display)
+		if err != nil {
+			return model.User{}, TokenPair{}, err
+		}
+		if s.replicator != nil {
+			go s.replicator.ScheduleUser(u)
+		}
+	case err != nil:
+		return model.User{}, TokenPair{}, err
+	case u.Role != role || u.HomeRegion != home.ID || u.DisplayName != display:
+		if u, err = s.repo.UpdateProfile(ctx, u.ID, role, home.ID, display); err != nil {
+			return model.User{}, TokenPair{}, err
+		}
+		if s.replicator != nil {
+			go s.replicator.ScheduleUser(u)
+		}
+	}
+
+	pair, err := s.authn.Issue(principalOf(u))
+	return u, pair, err
+}
+
+// provisionOIDC creates the local user for an IdP account. The username is
+// derived from the claims; on a clash a numeric suffix is added.
+func (s *Service) provisionOIDC(ctx context.Context, c IDClaims, subject, role, home, display string) (model.User, error) {
+	base := oidcUsername(c)
+	for i := 1; i <= 20; i++ {
+		username := base
+		if i > 1 {
+			username = fmt.Sprintf("%s-%d", base, i)
+		}
+		u, err := s.repo.InsertUser(ctx, model.User{
+			Username: username,
+			// '!' hiçbir bcrypt hash'i ile eşleşmez: yalnızca SSO ile giriş
+			PasswordHash: "!",
+			Role:         role,
+			HomeRegion:   home,
+			DisplayName:  display,
+			OIDCSubject:  subject,
+		})
+		switch {
+		case errors.Is(err, ErrUsernameTaken):
+			continue
+		case errors.Is(err, ErrSubjectTaken):
+			return s.repo.FindByOIDCSubject(ctx, subject)
+		}
+		return u, err
+	}
+	return model.User{}, ErrUsernameTaken
+}
+
+// oidcUsername picks a local username from preferred_username, the email's
+// local part or the subject, reduced to the allowed characters.
+func oidcUsername(c IDClaims) string {
+	local, _, _ := strings.Cut(c.Email, "@")
+	for _, candidate := range []string{c.PreferredUsername, local, c.Subject} {
+		var b strings.Builder
+		for _, r := range strings.ToLower(candidate) {
+			if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '.' || r == '-' {
+				b.WriteRune(r)
+			}
+		}
+		name := b.String()
+		if len(name) > 28 {
+			name = name[:28]
+		}
+		if len(name) >= 3 {
+			return name
+		}
+	}
+	return "sso-user"
+}
+
+// maxDisplayName is the longest display name kept, in characters.
+const maxDisplayName = 64
+
+// displayName cleans the name claim: invalid UTF-8 is dropped and long
+// names are cut at a character boundary.
+func displayName(name string) string {
+	name = strings.TrimSpace(strings.ToValidUTF8(name, ""))
+	if utf8.RuneCountInString(name) <= maxDisplayName {
+		return name
+	}
+	return strings.TrimSpace(string([]rune(name)[:maxDisplayName]))
+}
+
+// recordLogin audits a login attempt. Failed attempts are attributed to the
+// name that was tried, since there is no authenticated user.
+func (s *Service) recordLogin(ctx context.Context, action, attempted string, u model.User, err error) {
+	var e audit.Event
+	if err == nil {
+		e = principalOf(u).AuditEvent(ctx, action, "user", u.Username)
+	} else {
+		name := strings.ToLower(strings.TrimSpace(attempted))
+		e = Principal{Username: name}.AuditEvent(ctx, action, "user", name)
+	}
+	s.record(ctx, e, err)
+}
+
+func (s *Service) record(ctx context.Context, e audit.Event, err error) {
+	if s.audit == nil {
+		return
+	}
+	if err != nil {
+		e.Outcome = audit.Failed
+		if errors.Is(err, ErrInvalidCredentials) || errors.Is(err, ErrRoleNotAllowed) {
+			e.Outcome = audit.Denied
+		}
+		if e.Detail == nil {
+			e.Detail = map[string]string{}
+		}
+		e.Detail["error"] = err.Error()
+	}
+	s.audit.Record(ctx, e)
+}
+
+func principalOf(u model.User) Principal {
+	return Principal{UserID: u.ID, Username: u.Username, Role: u.Role, Region: u.HomeRegion}
+}
+
+var (
+	dummyOnce sync.Once
+	dummy     []byte
+)
+
+// dummyHash is compared against when the user does not exist.
+func dummyHash() []byte {
+	dummyOnce.Do(func() {
+		dummy, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+	})
+	return dummy
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDisplayName(t *testing.T) {
+	long := strings.Repeat("ş", 70)
+	tests := []struct {
+		in, want string
+	}{
+		{"  Ayşe Yılmaz ", "Ayşe Yılmaz"},
+		{long, strings.Repeat("ş", maxDisplayName)},
+		// 63 ASCII bytes followed by a two-byte rune used to be cut in half
+		{strings.Repeat("a", 63) + "şx", strings.Repeat("a", 63) + "ş"},
+		{"bad\xffname", "badname"},
+	}
+	for _, tt := range tests {
+		got := displayName(tt.in)
+		if got != tt.want {
+			t.Errorf("displayName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("displayName(%q) is not valid UTF-8", tt.in)
+		}
+	}
+}
+
+func TestRegisterRole(t *testing.T) {
+	anonymous := Principal{}
+	writer := Principal{UserID: 2, Role: RoleWriter}
+	admin := Principal{UserID: 1, Role: RoleAdmin}
+	tests := []struct {
+		requested string
+		caller    Principal
+		want      string
+		err       error
+	}{
+		{"", anonymous, RoleReader, nil},
+		{" Reader ", anonymous, RoleReader, nil},
+		{RoleWriter, anonymous, "", ErrRoleNotAllowed},
+		{RoleEditor, anonymous, "", ErrRoleNotAllowed},
+		{RoleAdmin, anonymous, "", ErrRoleNotAllowed},
+		{RoleWriter, writer, "", ErrRoleNotAllowed},
+		{RoleWriter, admin, RoleWriter, nil},
+		{RoleAdmin, admin, RoleAdmin, nil},
+		{"root", admin, "", ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		got, err := registerRole(tt.requested, tt.caller)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("registerRole(%q, %s) = %q, %v; want %q, %v", tt.requested, tt.caller.Role, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+// Package blob stores attachment contents. Every topology node has its own
+// store; the master's store receives uploads and the replicator copies each
+// blob to the regional stores.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for a key the store does not hold.
+	ErrNotFound = errors.New("blob not found")
+	// ErrInvalidKey is returned for keys that are empty, absolute or
+	// contain path traversal or unexpected characters.
+	ErrInvalidKey = errors.New("invalid blob key")
+	// ErrChecksum is returned by Copy when the copied bytes do not match.
+	ErrChecksum = errors.New("blob checksum mismatch")
+)
+
+// Info describes a stored blob. SHA256 is only filled in by Put.
+type Info struct {
+	Size    int64
+	SHA256  string
+	ModTime time.Time
+}
+
+// Store holds blobs under slash-separated keys such as "articles/7/ab12".
+// Put must be atomic: a reader never sees a partially written blob.
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader) (Info, error)
+	Open(ctx context.Context, key string) (io.ReadCloser, Info, error)
+	Stat(ctx context.Context, key string) (Info, error)
+	// Delete removes a blob; a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// DeletePrefix removes every blob whose key starts with prefix + "/".
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// Factory creates the store of the named node.
+type Factory func(node string) (Store, error)
+
+// Stores maps node names to their stores. Stores are created on first use,
+// so nodes added by a topology reload get one without a restart.
+type Stores struct {
+	factory Factory
+
+	mu     sync.Mutex
+	stores map[string]Store
+}
+
+// Open returns the stores of the configured kind.
+// kind: "fs" (one directory per node under root).
+func Open(kind, root string) (*Stores, error) {
+	switch strings.ToLower(kind) {
+	case "", "fs":
+		return NewStores(func(node string) (Store, error) { return NewFSStore(root, node) }), nil
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", kind)
+	}
+}
+
+// NewStores returns stores created by factory.
+func NewStores(factory Factory) *Stores {
+	return &Stores{factory: factory, stores: map[string]Store{}}
+}
+
+// For returns the store of the named node.
+func (s *Stores) For(node string) (Store, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st, ok := s.stores[node]; ok {
+		return st, nil
+	}
+	st, err := s.factory(node)
+	if err != nil {
+		return nil, fmt.Errorf("blob store %s: %w", node, err)
+	}
+	s.stores[node] = st
+	return st, nil
+}
+
+// Copy copies key from src to dst and checks the written bytes against
+// sha256 (hex); on mismatch the copy is removed again.
+func Copy(ctx context.Context, dst, src Store, key, sha256 string) error {
+	rc, _, err := src.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	info, err := dst.Put(ctx, key, rc)
+	if err != nil {
+		return err
+	}
+	if sha256 != "" && info.SHA256 != sha256 {
+		_ = dst.Delete(ctx, key)
+		return fmt.Errorf("%w: %s", ErrChecksum, key)
+	}
+	return nil
+}
+
+// ValidKey reports whether key is a relative, slash-separated path made of
+// lowercase letters, digits, '-', '_' and '.' without "." or ".." segments.
+func ValidKey(key string) bool {
+	if key == "" || len(key) > 512 {
+		return false
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return false
+		}
+		for _, r := range seg {
+			ok := r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.'
+			if !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
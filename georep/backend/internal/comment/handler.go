@@ -0,0 +1,82 @@
+package comment
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"geo-repl-demo/internal/article"
+	"geo-repl-demo/internal/auth"
+	"geo-repl-demo/internal/model"
+)
+
+type Handler struct {
+	svc *Service
+}
+
+func NewHandler(svc *Service) *Handler {
+	return &Handler{svc: svc}
+}
+
+func RegisterRoutes(r *gin.Engine, h *Handler) {
+	api := r.Group("/api")
+	{
+		api.GET("/articles/:id/comments", h.list)
+		api.POST("/articles/:id/comments", auth.Require(auth.PermComment), h.create)
+	}
+}
+
+func (h *Handler) list(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	cs, err := h.svc.List(c.Request.Context(), c.GetString("region"), c.GetString("country"), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, cs)
+}
+
+func (h *Handler) create(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var in model.CommentInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p, _ := auth.FromContext(c)
+	cm, err := h.svc.Create(c.Request.Context(), p, c.GetString("region"), c.GetString("country"), id, in)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, cm)
+}
+
+func writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrParentNotFound):
+		// Üst yorum bu bölgeye henüz ulaşmamış olabilir
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, article.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, article.ErrGeoBlocked):
+		c.JSON(http.StatusUnavailableForLegalReasons, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNoAuthor):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": auth.CodeForbidden})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,61 @@
+package comment
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// mergeBatch bounds how many comments are taken from one node per round.
+const mergeBatch = 500
+
+// RunMerger bölgelerde kabul edilen yorumları periyodik olarak master'a taşır
+func (s *Service) RunMerger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		s.Merge(ctx)
+	}
+}
+
+// Merge her replikadaki birleştirilmemiş yorumları clock sırasıyla master'a
+// yazar, kaynağında işaretler ve tüm replikalara dağıtır. Bir yanıtın üst
+// yorumu ya master'da zaten vardır ya da aynı partide ondan önce gelir.
+func (s *Service) Merge(ctx context.Context) {
+	for _, rep := range s.repo.Replicas() {
+		name := rep.Node.Name
+		pending, err := s.repo.Unmerged(ctx, rep.Pool, mergeBatch)
+		if err != nil {
+			log.Printf("⚠️ Yorumlar okunamadı (%s): %v", name, err)
+			continue
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		merged, err := s.repo.MergeMaster(ctx, pending)
+		if err != nil {
+			log.Printf("⚠️ Yorumlar master'a yazılamadı (%s): %v", name, err)
+			continue
+		}
+		ids := make([]string, 0, len(pending))
+		for _, c := range pending {
+			ids = append(ids, c.ID)
+		}
+		// İşaretlenemezse sonraki tur tekrar dener; master'daki ekleme idempotenttir
+		if err := s.repo.MarkMerged(ctx, rep.Pool, ids); err != nil {
+			log.Printf("⚠️ Yorumlar işaretlenemedi (%s): %v", name, err)
+		}
+		log.Printf("💬 %d yorum birleştirildi: %s → master", len(merged), name)
+
+		if s.replicator != nil {
+			go s.replicator.ScheduleComments(merged)
+		}
+	}
+}
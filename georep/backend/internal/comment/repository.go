@@ -0,0 +1,166 @@
+package comment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"geo-repl-demo/internal/db"
+	"geo-repl-demo/internal/model"
+	"geo-repl-demo/internal/region"
+)
+
+type Repository struct {
+	master   *db.Master
+	replicas *db.ReplicaSet
+	regions  *region.Registry
+}
+
+func NewRepository(master *db.Master, replicas *db.ReplicaSet, regions *region.Registry) *Repository {
+	return &Repository{master: master, replicas: replicas, regions: regions}
+}
+
+// node returns the node serving region: its replica if that is bootstrapped
+// and healthy, otherwise the master.
+func (r *Repository) node(region string) (string, *pgxpool.Pool) {
+	node := r.regions.NodeFor(region)
+	if r.replicas != nil {
+		if rep, ok := r.replicas.Get(node.Name); ok && rep.Healthy() {
+			return rep.Node.Name, rep.Pool
+		}
+	}
+	return r.master.Node.Name, r.master.Pool
+}
+
+// Insert writes a new comment on pool. The clock is one more than the
+// highest clock the node has seen for the article, which is at least the
+// parent's, so a reply always sorts after its parent. A parent must already
+// be present on the node.
+func (r *Repository) Insert(ctx context.Context, pool *pgxpool.Pool, c model.Comment) (model.Comment, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return model.Comment{}, fmt.Errorf("insert comment: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if c.ParentID != "" {
+		var exists bool
+		if err := tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM comments WHERE id=$1 AND article_id=$2)
+		`, c.ParentID, c.ArticleID).Scan(&exists); err != nil {
+			return model.Comment{}, fmt.Errorf("insert comment: %w", err)
+		}
+		if !exists {
+			return model.Comment{}, ErrParentNotFound
+		}
+	}
+
+	// The author's display name comes from the node's replicated users
+	// table; a user not replicated there yet is shown by username.
+	var out model.Comment
+	err = db.ScanComment(tx.QueryRow(ctx, `
+		INSERT INTO comments (id, article_id, parent_id, author_id, author, body, origin, region, clock, merged_at)
+		SELECT $1, $2, NULLIF($3, ''), $4,
+			COALESCE((SELECT NULLIF(display_name, '') FROM users WHERE id = $4), $5),
+			$6, $7, $8,
+			COALESCE((SELECT MAX(clock) FROM comments WHERE article_id = $2), 0) + 1,
+			CASE WHEN $9 THEN NOW() END
+		RETURNING `+db.CommentColumns,
+		c.ID, c.ArticleID, c.ParentID, c.AuthorID, c.Author, c.Body, c.Origin, c.Region,
+		c.Origin == r.master.Node.Name), &out)
+	if err != nil {
+		return model.Comment{}, fmt.Errorf("insert comment: %w", err)
+	}
+	return out, tx.Commit(ctx)
+}
+
+// List returns the comments of an article on the node serving region in
+// causal order.
+func (r *Repository) List(ctx context.Context, region string, articleID int64) ([]model.Comment, error) {
+	_, pool := r.node(region)
+	return queryComments(ctx, pool, `
+		SELECT `+db.CommentColumns+` FROM comments
+		WHERE article_id=$1
+		ORDER BY clock, id`, articleID)
+}
+
+// Unmerged returns up to limit comments a node accepted that the master
+// has not merged yet, in causal order.
+func (r *Repository) Unmerged(ctx context.Context, pool *pgxpool.Pool, limit int) ([]model.Comment, error) {
+	return queryComments(ctx, pool, `
+		SELECT `+db.CommentColumns+` FROM comments
+		WHERE merged_at IS NULL
+		ORDER BY clock, id
+		LIMIT $1`, limit)
+}
+
+// MergeMaster inserts comments from a node into the master in one
+// transaction and returns the ones that were new. Comments on articles the
+// master no longer has are dropped.
+func (r *Repository) MergeMaster(ctx context.Context, cs []model.Comment) ([]model.Comment, error) {
+	tx, err := r.master.Pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var merged []model.Comment
+	for _, c := range cs {
+		var out model.Comment
+		err := db.ScanComment(tx.QueryRow(ctx, `
+			INSERT INTO comments (id, article_id, parent_id, author_id, author, body,
+				origin, region, clock, created_at, merged_at)
+			SELECT $1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10, NOW()
+			WHERE EXISTS (SELECT 1 FROM articles WHERE id = $2)
+			ON CONFLICT (id) DO NOTHING
+			RETURNING `+db.CommentColumns,
+			c.ID, c.ArticleID, c.ParentID, c.AuthorID, c.Author, c.Body,
+			c.Origin, c.Region, c.Clock, c.CreatedAt), &out)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		merged = append(merged, out)
+	}
+	return merged, tx.Commit(ctx)
+}
+
+// MarkMerged records on the origin node that the master has the comments,
+// so they are not sent again.
+func (r *Repository) MarkMerged(ctx context.Context, pool *pgxpool.Pool, ids []string) error {
+	_, err := pool.Exec(ctx, `
+		UPDATE comments SET merged_at=NOW() WHERE id = ANY($1) AND merged_at IS NULL
+	`, ids)
+	return err
+}
+
+// Replicas returns the current replica set.
+func (r *Repository) Replicas() []*db.Replica {
+	if r.replicas == nil {
+		return nil
+	}
+	return r.replicas.All()
+}
+
+func queryComments(ctx context.Context, pool *pgxpool.Pool, query string, args ...any) ([]model.Comment, error) {
+	rows, err := pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cs := []model.Comment{}
+	for rows.Next() {
+		var c model.Comment
+		if err := db.ScanComment(rows, &c); err != nil {
+			return nil, err
+		}
+		cs = append(cs, c)
+	}
+	return cs, rows.Err()
+}
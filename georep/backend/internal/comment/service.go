@@ -0,0 +1,140 @@
+// Package comment accepts reader comments at the reader's regional node and
+// merges them into the master and the other regions asynchronously.
+package comment
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"geo-repl-demo/internal/audit"
+	"geo-repl-demo/internal/auth"
+	"geo-repl-demo/internal/model"
+	"geo-repl-demo/internal/replication"
+)
+
+var (
+	ErrInvalidInput = errors.New("invalid input")
+	ErrNoAuthor     = errors.New("token is not bound to a user account")
+	// ErrParentNotFound is returned for a reply to a comment the regional
+	// node does not have (yet).
+	ErrParentNotFound = errors.New("parent comment not found")
+)
+
+const maxBodyLen = 2000
+
+// Articles checks that an article is visible to a reader; it is
+// implemented by article.Service.
+type Articles interface {
+	Get(ctx context.Context, region, country string, id int64) (*model.Article, error)
+}
+
+type Service struct {
+	repo       *Repository
+	articles   Articles
+	replicator *replication.Replicator
+	audit      audit.Sink
+}
+
+func NewService(repo *Repository, articles Articles, replicator *replication.Replicator, sink audit.Sink) *Service {
+	return &Service{repo: repo, articles: articles, replicator: replicator, audit: sink}
+}
+
+// List returns the comments of an article as the reader's regional node
+// has them, in causal order. A reply whose parent has not reached the node
+// yet is held back until it has.
+func (s *Service) List(ctx context.Context, region, country string, articleID int64) ([]model.Comment, error) {
+	if _, err := s.articles.Get(ctx, region, country, articleID); err != nil {
+		return nil, err
+	}
+	cs, err := s.repo.List(ctx, region, articleID)
+	if err != nil {
+		return nil, err
+	}
+	return causal(cs), nil
+}
+
+// Create writes a comment to the reader's regional node. It reaches the
+// master and the other regions through the merger.
+func (s *Service) Create(ctx context.Context, p auth.Principal, region, country string, articleID int64, in model.CommentInput) (*model.Comment, error) {
+	c, err := s.create(ctx, p, region, country, articleID, in)
+	if s.audit != nil {
+		e := p.AuditEvent(ctx, "comment.create", "comment", c.ID)
+		e.Detail = map[string]string{"article_id": fmt.Sprint(articleID), "origin": c.Origin}
+		if err != nil {
+			e.Outcome = audit.Failed
+			e.Detail["error"] = err.Error()
+		}
+		s.audit.Record(ctx, e)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (s *Service) create(ctx context.Context, p auth.Principal, region, country string, articleID int64, in model.CommentInput) (model.Comment, error) {
+	if p.UserID == 0 {
+		return model.Comment{}, ErrNoAuthor
+	}
+	body := strings.TrimSpace(in.Body)
+	if body == "" || utf8.RuneCountInString(body) > maxBodyLen {
+		return model.Comment{}, fmt.Errorf("%w: body must be 1-%d characters", ErrInvalidInput, maxBodyLen)
+	}
+	if _, err := s.articles.Get(ctx, region, country, articleID); err != nil {
+		return model.Comment{}, err
+	}
+
+	origin, pool := s.repo.node(region)
+	c, err := s.repo.Insert(ctx, pool, model.Comment{
+		ID:        newID(time.Now()),
+		ArticleID: articleID,
+		ParentID:  strings.TrimSpace(in.ParentID),
+		AuthorID:  p.UserID,
+		Author:    p.Username,
+		Body:      body,
+		Origin:    origin,
+		Region:    s.repo.regions.Resolve(region).ID,
+	})
+	if err != nil {
+		return model.Comment{}, err
+	}
+	// Master'a yazılan yorum birleştirme beklemeden dağıtılır
+	if c.MergedAt != nil && s.replicator != nil {
+		go s.replicator.ScheduleComments([]model.Comment{c})
+	}
+	return c, nil
+}
+
+// causal drops comments whose parent is not in the list. cs is sorted by
+// clock, so a parent always comes before its replies.
+func causal(cs []model.Comment) []model.Comment {
+	seen := make(map[string]bool, len(cs))
+	out := cs[:0]
+	for _, c := range cs {
+		if c.ParentID != "" && !seen[c.ParentID] {
+			continue
+		}
+		seen[c.ID] = true
+		out = append(out, c)
+	}
+	return out
+}
+
+// newID returns a UUIDv7: a 48-bit millisecond timestamp followed by random
+// bits, unique across nodes without coordination.
+func newID(now time.Time) string {
+	var b [16]byte
+	_, _ = rand.Read(b[6:])
+	ms := uint64(now.UnixMilli())
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*i))
+	}
+	b[6] = b[6]&0x0f | 0x70 // version 7
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -2,63 +2,226 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds application configuration loaded from environment.
 type Config struct {
-	MasterDSN   string
-	Replica1DSN string
-	Replica2DSN string
-	Replica3DSN string
-	Replica4DSN string
-	Replica5DSN string
-	APIPort            string
+	APIPort       string
+	TopologyFile  string
+	GeoIPDB       string
+	GeoIPProvider string
+	GeoIPCache    int
+	// TrustedProxies lists the reverse proxies whose X-Forwarded-For and
+	// Forwarded headers are believed. Empty means the TCP peer is the client.
+	TrustedProxies []netip.Prefix
+	// SessionSecret signs login tokens and region-preference cookies.
+	SessionSecret string
+	// AdminTokens are static bearer tokens that authenticate as admin.
+	AdminTokens []string
+	// DevMode allows anyone to override the region with ?region=.
+	DevMode bool
+	// DeleteRetention is how long a deleted article can be restored before
+	// the purger removes it everywhere.
+	DeleteRetention time.Duration
+	// PurgeInterval is how often the purger runs.
+	PurgeInterval time.Duration
+	// BlobStore selects the attachment blob store kind ("fs").
+	BlobStore string
+	// BlobRoot is where the "fs" store keeps one directory per node.
+	BlobRoot string
+	// MaxAttachmentBytes caps the size of a single uploaded attachment.
+	MaxAttachmentBytes int64
+	// OIDC configures single sign-on; disabled when neither an issuer nor
+	// the mock IdP is configured.
+	OIDC     OIDC
+	Topology Topology
 }
 
-// Load reads environment variables and returns Config.
+// OIDC configures the OpenID Connect authorization-code login.
+type OIDC struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is this backend's callback, registered at the IdP.
+	RedirectURL string
+	// PostLoginURL is where the browser is sent with the issued tokens.
+	PostLoginURL string
+	// RoleClaim names the ID-token claim (string or list) mapped to roles.
+	RoleClaim string
+	// RoleMap maps RoleClaim values to roles, e.g. "geo-admins=admin".
+	RoleMap     map[string]string
+	DefaultRole string
+	// RegionClaim names the claim holding the user's home region.
+	RegionClaim string
+	// Mock serves an embedded IdP under /mock-idp and logs in against it.
+	// It is refused unless DevMode is set.
+	Mock bool
+}
+
+// Enabled reports whether OIDC login is configured.
+func (o OIDC) Enabled() bool {
+	return o.Mock || o.Issuer != ""
+}
+
+// Load reads environment variables and the topology file and returns Config.
 func Load() (Config, error) {
 	cfg := Config{
-		MasterDSN:   os.Getenv("MASTER_DSN"),
-		Replica1DSN: os.Getenv("REPLICA1_DSN"),
-		Replica2DSN: os.Getenv("REPLICA2_DSN"),
-		Replica3DSN: os.Getenv("REPLICA3_DSN"),
-		Replica4DSN: os.Getenv("REPLICA4_DSN"),
-		Replica5DSN: os.Getenv("REPLICA5_DSN"),
-		APIPort:            getenvDefault("API_PORT", "8080"),
+		APIPort:       getenvDefault("API_PORT", "8080"),
+		TopologyFile:  getenvDefault("TOPOLOGY_FILE", "topology.yaml"),
+		GeoIPDB:       getenvDefault("GEOIP_DB", "/app/GeoLite2-Country.mmdb"),
+		GeoIPProvider: getenvDefault("GEOIP_PROVIDER", "mmdb"),
+		BlobStore:     getenvDefault("BLOB_STORE", "fs"),
+		BlobRoot:      getenvDefault("BLOB_ROOT", "/app/data/blobs"),
+	}
+
+	cacheSize, err := strconv.Atoi(getenvDefault("GEOIP_CACHE_SIZE", "10000"))
+	if err != nil {
+		return cfg, fmt.Errorf("GEOIP_CACHE_SIZE: %w", err)
+	}
+	cfg.GeoIPCache = cacheSize
+
+	proxies, err := ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		return cfg, fmt.Errorf("TRUSTED_PROXIES: %w", err)
 	}
+	cfg.TrustedProxies = proxies
 
-	if cfg.MasterDSN == "" {
-		return cfg, fmt.Errorf("MASTER_DSN is required")
+	cfg.SessionSecret = os.Getenv("SESSION_SECRET")
+	for _, tok := range strings.Split(os.Getenv("ADMIN_TOKENS"), ",") {
+		if tok = strings.TrimSpace(tok); tok != "" {
+			cfg.AdminTokens = append(cfg.AdminTokens, tok)
+		}
 	}
-	if cfg.Replica1DSN == "" {
-		return cfg, fmt.Errorf("REPLICA1_DSN is required")
+	if v := os.Getenv("DEV_MODE"); v != "" {
+		dev, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("DEV_MODE: %w", err)
+		}
+		cfg.DevMode = dev
 	}
-	if cfg.Replica2DSN == "" {
-		return cfg, fmt.Errorf("REPLICA2_DSN is required")
+
+	if cfg.DeleteRetention, err = parseDuration("DELETE_RETENTION", "720h"); err != nil {
+		return cfg, err
+	}
+	if cfg.PurgeInterval, err = parseDuration("PURGE_INTERVAL", "1m"); err != nil {
+		return cfg, err
+	}
+
+	maxBytes, err := strconv.ParseInt(getenvDefault("MAX_ATTACHMENT_BYTES", "10485760"), 10, 64)
+	if err != nil || maxBytes <= 0 {
+		return cfg, fmt.Errorf("MAX_ATTACHMENT_BYTES: must be a positive byte count")
 	}
-	if cfg.Replica3DSN == "" {
-		return cfg, fmt.Errorf("REPLICA3_DSN is required")
+	cfg.MaxAttachmentBytes = maxBytes
+
+	oidc, err := loadOIDC(cfg.APIPort)
+	if err != nil {
+		return cfg, err
 	}
-	if cfg.Replica4DSN == "" {
-		return cfg, fmt.Errorf("REPLICA4_DSN is required")
+	// The mock IdP logs anyone in as any of its users, admins included.
+	if oidc.Mock && !cfg.DevMode {
+		return cfg, fmt.Errorf("OIDC_MOCK: the mock IdP logs in without a password and needs DEV_MODE=true")
 	}
-	if cfg.Replica5DSN == "" {
-		return cfg, fmt.Errorf("REPLICA5_DSN is required")
+	cfg.OIDC = oidc
+
+	topo, err := LoadTopology(cfg.TopologyFile)
+	if err != nil {
+		return cfg, fmt.Errorf("load topology: %w", err)
 	}
+	cfg.Topology = topo
 
 	return cfg, nil
 }
 
-// ReplicaDSNs returns the ordered list of replica DSNs.
-func (c Config) ReplicaDSNs() []string {
-	return []string{
-		c.Replica1DSN,
-		c.Replica2DSN,
-		c.Replica3DSN,
-		c.Replica4DSN,
-		c.Replica5DSN,
+// ParseTrustedProxies parses a comma-separated list of CIDRs or single
+// addresses, e.g. "10.0.0.0/8, 172.16.0.0/12, ::1".
+func ParseTrustedProxies(s string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if strings.Contains(field, "/") {
+			p, err := netip.ParsePrefix(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cidr %q: %w", field, err)
+			}
+			out = append(out, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", field, err)
+		}
+		addr = addr.Unmap()
+		out = append(out, netip.PrefixFrom(addr, addr.BitLen()))
 	}
+	return out, nil
+}
+
+func loadOIDC(port string) (OIDC, error) {
+	o := OIDC{
+		Issuer:       os.Getenv("OIDC_ISSUER"),
+		ClientID:     getenvDefault("OIDC_CLIENT_ID", "geo-writer-ui"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  getenvDefault("OIDC_REDIRECT_URL", "http://localhost:"+port+"/api/oidc/callback"),
+		PostLoginURL: getenvDefault("OIDC_POST_LOGIN_URL", "http://localhost:5173/"),
+		RoleClaim:    getenvDefault("OIDC_ROLE_CLAIM", "groups"),
+		DefaultRole:  getenvDefault("OIDC_DEFAULT_ROLE", "reader"),
+		RegionClaim:  getenvDefault("OIDC_REGION_CLAIM", "region"),
+	}
+	if v := os.Getenv("OIDC_MOCK"); v != "" {
+		mock, err := strconv.ParseBool(v)
+		if err != nil {
+			return o, fmt.Errorf("OIDC_MOCK: %w", err)
+		}
+		o.Mock = mock
+	}
+	if o.Mock && o.Issuer == "" {
+		o.Issuer = "http://localhost:" + port + "/mock-idp"
+	}
+
+	roleMap, err := ParseRoleMap(getenvDefault("OIDC_ROLE_MAP", "geo-admins=admin,geo-editors=editor,geo-writers=writer"))
+	if err != nil {
+		return o, fmt.Errorf("OIDC_ROLE_MAP: %w", err)
+	}
+	o.RoleMap = roleMap
+	return o, nil
+}
+
+// ParseRoleMap parses "claim-value=role" pairs separated by commas.
+func ParseRoleMap(s string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		value, role, ok := strings.Cut(field, "=")
+		value, role = strings.TrimSpace(value), strings.TrimSpace(role)
+		if !ok || value == "" || role == "" {
+			return nil, fmt.Errorf("invalid entry %q, want value=role", field)
+		}
+		out[value] = role
+	}
+	return out, nil
+}
+
+// parseDuration reads a positive Go duration such as "72h" from key.
+func parseDuration(key, def string) (time.Duration, error) {
+	d, err := time.ParseDuration(getenvDefault(key, def))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s: must be positive", key)
+	}
+	return d, nil
 }
 
 func getenvDefault(key, def string) string {
@@ -67,6 +230,3 @@ func getenvDefault(key, def string) string {
 	}
 	return def
 }
-
-
-
@@ -0,0 +1,275 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gopkg.in/yaml.v3"
+
+	"geo-repl-demo/internal/geo"
+	"geo-repl-demo/internal/locale"
+)
+
+// Node describes a single database node (the master or a replica).
+type Node struct {
+	Name   string  `yaml:"name" json:"name"`
+	Region string  `yaml:"region" json:"region"`
+	Label  string  `yaml:"label" json:"label"`
+	Lat    float64 `yaml:"lat" json:"lat"`
+	Lon    float64 `yaml:"lon" json:"lon"`
+	DSN    string  `yaml:"dsn" json:"-"`
+}
+
+// Region describes a logical routing region and the node that serves it.
+// Several regions may share a node; regions without an explicit entry are
+// derived from the nodes themselves.
+type Region struct {
+	ID      string   `yaml:"id" json:"id"`
+	Label   string   `yaml:"label" json:"label"`
+	Aliases []string `yaml:"aliases" json:"aliases,omitempty"`
+	Node    string   `yaml:"node" json:"node"`
+	// Timezone is the IANA zone used for region-local publish times;
+	// empty means UTC.
+	Timezone string `yaml:"timezone" json:"timezone,omitempty"`
+	// Locale is the language served to readers whose Accept-Language
+	// matches no translation; empty means the topology's DefaultLocale.
+	Locale string `yaml:"locale" json:"locale,omitempty"`
+}
+
+// Topology lists the master and an arbitrary number of replicas.
+type Topology struct {
+	Master        Node     `yaml:"master" json:"master"`
+	Replicas      []Node   `yaml:"replicas" json:"replicas"`
+	Regions       []Region `yaml:"regions" json:"regions,omitempty"`
+	DefaultRegion string   `yaml:"default_region" json:"default_region,omitempty"`
+	// DefaultLocale is the locale of new articles and of regions without
+	// their own; defaults to "tr", the language of the seed content.
+	DefaultLocale string `yaml:"default_locale" json:"default_locale,omitempty"`
+	// CountryMap is the country → region data file; relative paths are
+	// resolved against the topology file. Empty selects the built-in map.
+	CountryMap string `yaml:"country_map" json:"country_map,omitempty"`
+	// RateLimits are the request budgets; absent means unlimited.
+	RateLimits RateLimits `yaml:"rate_limits" json:"rate_limits"`
+}
+
+// Rate is a token bucket refilled at Rate tokens per second and holding at
+// most Burst tokens. A zero Rate means unlimited.
+type Rate struct {
+	Rate  float64 `yaml:"rate" json:"rate"`
+	Burst int     `yaml:"burst" json:"burst"`
+}
+
+// Limited reports whether the bucket limits anything.
+func (r Rate) Limited() bool {
+	return r.Rate > 0
+}
+
+// RateBudget holds the buckets applied to a request. Read and Write are per
+// caller (API key, user or client IP); RegionWrite is shared by all writes
+// routed to a region and protects the master and the replication fan-out.
+type RateBudget struct {
+	Read        Rate `yaml:"read" json:"read"`
+	Write       Rate `yaml:"write" json:"write"`
+	RegionWrite Rate `yaml:"region_write" json:"region_write"`
+}
+
+// RateLimits are the default budget plus per-region overrides. Fields left
+// out of an override inherit the default.
+type RateLimits struct {
+	RateBudget `yaml:",inline"`
+	Regions    map[string]RateBudget `yaml:"regions" json:"regions,omitempty"`
+}
+
+// For returns the budget of region.
+func (l RateLimits) For(region string) RateBudget {
+	b := l.RateBudget
+	o, ok := l.Regions[region]
+	if !ok {
+		return b
+	}
+	if o.Read.Limited() {
+		b.Read = o.Read
+	}
+	if o.Write.Limited() {
+		b.Write = o.Write
+	}
+	if o.RegionWrite.Limited() {
+		b.RegionWrite = o.RegionWrite
+	}
+	return b
+}
+
+// LoadTopology reads a YAML or JSON topology file. ${VAR} references are
+// expanded from the environment so DSN secrets can stay out of the file.
+func LoadTopology(path string) (Topology, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Topology{}, err
+	}
+	t, err := ParseTopology(raw)
+	if err != nil {
+		return Topology{}, err
+	}
+	t.ResolvePaths(filepath.Dir(path))
+	return t, nil
+}
+
+// ResolvePaths makes relative file references absolute against dir.
+func (t *Topology) ResolvePaths(dir string) {
+	if t.CountryMap != "" && !filepath.IsAbs(t.CountryMap) {
+		t.CountryMap = filepath.Join(dir, t.CountryMap)
+	}
+}
+
+// ParseTopology decodes and validates a topology document.
+// JSON is a subset of YAML, so a single decoder handles both formats.
+func ParseTopology(raw []byte) (Topology, error) {
+	var t Topology
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(raw))), &t); err != nil {
+		return Topology{}, fmt.Errorf("parse topology: %w", err)
+	}
+	if err := t.normalize(); err != nil {
+		return Topology{}, err
+	}
+	return t, nil
+}
+
+// Nodes returns the master followed by all replicas.
+func (t Topology) Nodes() []Node {
+	nodes := make([]Node, 0, len(t.Replicas)+1)
+	nodes = append(nodes, t.Master)
+	return append(nodes, t.Replicas...)
+}
+
+// ReplicaDSNs returns the ordered list of replica DSNs.
+func (t Topology) ReplicaDSNs() []string {
+	dsns := make([]string, 0, len(t.Replicas))
+	for _, n := range t.Replicas {
+		dsns = append(dsns, n.DSN)
+	}
+	return dsns
+}
+
+// Point returns the node coordinates.
+func (n Node) Point() geo.Point {
+	return geo.Point{Lat: n.Lat, Lon: n.Lon}
+}
+
+// Addr returns the host:port part of the node DSN, or the node name if the
+// DSN cannot be parsed.
+func (n Node) Addr() string {
+	cfg, err := pgconn.ParseConfig(n.DSN)
+	if err != nil {
+		return n.Name
+	}
+	return fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+}
+
+func (t *Topology) normalize() error {
+	if err := t.Master.normalize("master"); err != nil {
+		return err
+	}
+	if err := t.RateLimits.normalize(); err != nil {
+		return err
+	}
+	seen := map[string]bool{t.Master.Name: true}
+	for i := range t.Replicas {
+		n := &t.Replicas[i]
+		if err := n.normalize(fmt.Sprintf("replicas[%d]", i)); err != nil {
+			return err
+		}
+		if seen[n.Name] {
+			return fmt.Errorf("duplicate node name %q", n.Name)
+		}
+		seen[n.Name] = true
+	}
+
+	// Override'lar bölge ID'si ile verilir (alias değil)
+	known := map[string]bool{}
+	for _, n := range t.Nodes() {
+		known[n.Region] = true
+	}
+	if t.DefaultLocale == "" {
+		t.DefaultLocale = "tr"
+	}
+	def, ok := locale.Normalize(t.DefaultLocale)
+	if !ok {
+		return fmt.Errorf("default_locale: invalid language tag %q", t.DefaultLocale)
+	}
+	t.DefaultLocale = def
+	for i := range t.Regions {
+		r := &t.Regions[i]
+		known[strings.ToLower(strings.TrimSpace(r.ID))] = true
+		if _, err := time.LoadLocation(r.Timezone); err != nil {
+			return fmt.Errorf("region %q: timezone: %w", r.ID, err)
+		}
+		if r.Locale != "" {
+			tag, ok := locale.Normalize(r.Locale)
+			if !ok {
+				return fmt.Errorf("region %q: invalid locale %q", r.ID, r.Locale)
+			}
+			r.Locale = tag
+		}
+	}
+	for id := range t.RateLimits.Regions {
+		if !known[id] {
+			return fmt.Errorf("rate_limits.regions: unknown region %q", id)
+		}
+	}
+	return nil
+}
+
+func (l *RateLimits) normalize() error {
+	if err := l.RateBudget.normalize("rate_limits"); err != nil {
+		return err
+	}
+	regions := make(map[string]RateBudget, len(l.Regions))
+	for id, b := range l.Regions {
+		key := strings.ToLower(strings.TrimSpace(id))
+		if err := b.normalize("rate_limits.regions." + key); err != nil {
+			return err
+		}
+		regions[key] = b
+	}
+	l.Regions = regions
+	return nil
+}
+
+func (b *RateBudget) normalize(path string) error {
+	for name, r := range map[string]*Rate{"read": &b.Read, "write": &b.Write, "region_write": &b.RegionWrite} {
+		if r.Rate < 0 || r.Burst < 0 {
+			return fmt.Errorf("%s.%s: rate and burst must not be negative", path, name)
+		}
+		// Burst verilmezse en az bir saniyelik kapasite
+		if r.Limited() && r.Burst == 0 {
+			r.Burst = int(math.Ceil(r.Rate))
+		}
+	}
+	return nil
+}
+
+func (n *Node) normalize(path string) error {
+	n.Region = strings.ToLower(strings.TrimSpace(n.Region))
+	n.Name = strings.TrimSpace(n.Name)
+	if n.Region == "" {
+		return fmt.Errorf("%s: region is required", path)
+	}
+	if n.DSN == "" {
+		return fmt.Errorf("%s: dsn is required", path)
+	}
+	if n.Name == "" {
+		n.Name = n.Region
+	}
+	if n.Label == "" {
+		n.Label = strings.ToUpper(n.Region)
+	}
+	if !n.Point().Valid() {
+		return fmt.Errorf("%s: coordinates out of range", path)
+	}
+	return nil
+}
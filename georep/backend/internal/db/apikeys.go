@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"geo-repl-demo/internal/model"
+)
+
+// APIKeyColumns is the column list matching ScanAPIKey.
+const APIKeyColumns = `id, key_id, name, owner_id, scopes, region, quota_per_hour,
+	secret_hash, previous_hash, previous_expires_at, created_at, updated_at, revoked_at`
+
+// ScanAPIKey scans a row selected with APIKeyColumns.
+func ScanAPIKey(row pgx.Row, k *model.APIKey) error {
+	return row.Scan(&k.ID, &k.KeyID, &k.Name, &k.OwnerID, &k.Scopes, &k.Region, &k.QuotaPerHour,
+		&k.SecretHash, &k.PreviousHash, &k.PreviousExpiresAt, &k.CreatedAt, &k.UpdatedAt, &k.RevokedAt)
+}
+
+// UpsertAPIKey writes a copy of a master API key to a replica. Hashes are
+// replicated so that every region can validate keys without the master;
+// revocations travel the same way.
+func UpsertAPIKey(ctx context.Context, pool *pgxpool.Pool, k model.APIKey) error {
+	_, err := pool.Exec(ctx, `
+		INSERT INTO api_keys (id, key_id, name, owner_id, scopes, region, quota_per_hour,
+			secret_hash, previous_hash, previous_expires_at, created_at, updated_at, revoked_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+		ON CONFLICT (id) DO UPDATE SET
+			key_id=EXCLUDED.key_id,
+			name=EXCLUDED.name,
+			owner_id=EXCLUDED.owner_id,
+			scopes=EXCLUDED.scopes,
+			region=EXCLUDED.region,
+			quota_per_hour=EXCLUDED.quota_per_hour,
+			secret_hash=EXCLUDED.secret_hash,
+			previous_hash=EXCLUDED.previous_hash,
+			previous_expires_at=EXCLUDED.previous_expires_at,
+			created_at=EXCLUDED.created_at,
+			updated_at=EXCLUDED.updated_at,
+			revoked_at=EXCLUDED.revoked_at
+	`, k.ID, k.KeyID, k.Name, k.OwnerID, k.Scopes, k.Region, k.QuotaPerHour,
+		k.SecretHash, k.PreviousHash, k.PreviousExpiresAt, k.CreatedAt, k.UpdatedAt, k.RevokedAt)
+	return err
+}
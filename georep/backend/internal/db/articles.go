@@ -0,0 +1,254 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"geo-repl-demo/internal/model"
+)
+
+// ArticleColumns is the column list matching ScanArticle.
+const ArticleColumns = `id, title, summary, content_long, author, region, created_at,
+	allowed_countries, denied_countries, COALESCE(author_id, 0), revision, updated_at, deleted_at,
+	status, publish_at, region_publish_at,
+	ARRAY(SELECT tag FROM article_tags WHERE article_id = articles.id ORDER BY tag),
+	ARRAY(SELECT category FROM article_categories WHERE article_id = articles.id ORDER BY category),
+	locale, translations, content_html, toc`
+
+// ScanArticle scans a row selected with ArticleColumns.
+func ScanArticle(row pgx.Row, a *model.Article) error {
+	return ScanArticleWith(row, a)
+}
+
+// ScanArticleWith scans a row selected with ArticleColumns followed by
+// extra columns into a and extra.
+func ScanArticleWith(row pgx.Row, a *model.Article, extra ...any) error {
+	return row.Scan(append([]any{&a.ID, &a.Title, &a.Summary, &a.ContentLong, &a.Author, &a.Region, &a.CreatedAt,
+		&a.AllowedCountries, &a.DeniedCountries, &a.AuthorID, &a.Revision, &a.UpdatedAt, &a.DeletedAt,
+		&a.Status, &a.PublishAt, &a.RegionPublishAt, &a.Tags, &a.Categories, &a.Locale, &a.Translations,
+		&a.ContentHTML, &a.TOC}, extra...)...)
+}
+
+// PublishedSQL is the condition for articles released in the region given
+// by the SQL expression region at the time given by at, e.g.
+// PublishedSQL("$1", "NOW()"). It mirrors model.Article.PublishedIn.
+func PublishedSQL(region, at string) string {
+	return fmt.Sprintf(`(status = 'published' OR (status = 'scheduled'
+		AND COALESCE((region_publish_at->>%s)::timestamptz, publish_at) <= %s))`, region, at)
+}
+
+// publishTimes avoids writing NULL into the NOT NULL JSONB column.
+func publishTimes(m map[string]time.Time) map[string]time.Time {
+	if m == nil {
+		return map[string]time.Time{}
+	}
+	return m
+}
+
+// Translations avoids writing NULL into the NOT NULL JSONB column.
+func Translations(m map[string]model.Translation) map[string]model.Translation {
+	if m == nil {
+		return map[string]model.Translation{}
+	}
+	return m
+}
+
+// TOC avoids writing NULL into the NOT NULL JSONB column.
+func TOC(h []model.Heading) []model.Heading {
+	if h == nil {
+		return []model.Heading{}
+	}
+	return h
+}
+
+// AvailableSQL is the condition for articles licensed in the country given
+// by the SQL expression country. It mirrors model.Article.AvailableIn.
+func AvailableSQL(country string) string {
+	return fmt.Sprintf(`(NOT (%[1]s = ANY(denied_countries))
+		AND (cardinality(allowed_countries) = 0 OR %[1]s = ANY(allowed_countries)))`, country)
+}
+
+// TaxonomySQL is the condition for articles carrying any of the tags and
+// any of the categories given by the text[] expressions tags and
+// categories. An empty list does not filter.
+func TaxonomySQL(tags, categories string) string {
+	return fmt.Sprintf(`(cardinality(%[1]s::text[]) = 0 OR EXISTS (
+			SELECT 1 FROM article_tags WHERE article_id = articles.id AND tag = ANY(%[1]s)))
+		AND (cardinality(%[2]s::text[]) = 0 OR EXISTS (
+			SELECT 1 FROM article_categories WHERE article_id = articles.id AND category = ANY(%[2]s)))`,
+		tags, categories)
+}
+
+// UpsertArticle writes a copy of a master article to a replica, keeping the
+// master's ID. Soft deletes and restores travel as changes of deleted_at;
+// an older revision never overwrites a newer one. Tags, categories and
+// translations are replaced together with the row they belong to.
+func UpsertArticle(ctx context.Context, pool *pgxpool.Pool, a model.Article) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO articles (id, title, summary, content_long, author, region, created_at,
+			allowed_countries, denied_countries, author_id, revision, updated_at, deleted_at,
+			status, publish_at, region_publish_at, locale, translations, content_html, toc)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,NULLIF($10::bigint, 0),$11,$12,$13,$14,$15,$16,$17,$18,$19,$20)
+		ON CONFLICT (id) DO UPDATE SET
+			title=EXCLUDED.title,
+			summary=EXCLUDED.summary,
+			content_long=EXCLUDED.content_long,
+			author=EXCLUDED.author,
+			region=EXCLUDED.region,
+			created_at=EXCLUDED.created_at,
+			allowed_countries=EXCLUDED.allowed_countries,
+			denied_countries=EXCLUDED.denied_countries,
+			author_id=EXCLUDED.author_id,
+			revision=EXCLUDED.revision,
+			updated_at=EXCLUDED.updated_at,
+			deleted_at=EXCLUDED.deleted_at,
+			status=EXCLUDED.status,
+			publish_at=EXCLUDED.publish_at,
+			region_publish_at=EXCLUDED.region_publish_at,
+			locale=EXCLUDED.locale,
+			translations=EXCLUDED.translations,
+			content_html=EXCLUDED.content_html,
+			toc=EXCLUDED.toc
+		WHERE articles.revision <= EXCLUDED.revision
+	`, a.ID, a.Title, a.Summary, a.ContentLong, a.Author, a.Region, a.CreatedAt,
+		countryList(a.AllowedCountries), countryList(a.DeniedCountries), a.AuthorID, a.Revision, a.UpdatedAt, a.DeletedAt,
+		a.Status, a.PublishAt, publishTimes(a.RegionPublishAt), a.Locale, Translations(a.Translations),
+		a.ContentHTML, TOC(a.TOC))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		// Replikada daha yeni bir revizyon var; etiketleri de onundur
+		return nil
+	}
+	if err := SetArticleTaxonomy(ctx, tx, a.ID, a.Tags, a.Categories); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// SetArticleTaxonomy replaces the tags and categories of an article within
+// tx. Tags that do not exist yet are created.
+func SetArticleTaxonomy(ctx context.Context, tx pgx.Tx, id int64, tags, categories []string) error {
+	tags, categories = slugList(tags), slugList(categories)
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO tags (slug) SELECT unnest($1::text[]) ON CONFLICT (slug) DO NOTHING
+	`, tags); err != nil {
+		return fmt.Errorf("tags: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM article_tags WHERE article_id=$1 AND NOT (tag = ANY($2))
+	`, id, tags); err != nil {
+		return fmt.Errorf("tags: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO article_tags (article_id, tag) SELECT $1, unnest($2::text[])
+		ON CONFLICT DO NOTHING
+	`, id, tags); err != nil {
+		return fmt.Errorf("tags: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM article_categories WHERE article_id=$1 AND NOT (category = ANY($2))
+	`, id, categories); err != nil {
+		return fmt.Errorf("categories: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO article_categories (article_id, category) SELECT $1, unnest($2::text[])
+		ON CONFLICT DO NOTHING
+	`, id, categories); err != nil {
+		return fmt.Errorf("categories: %w", err)
+	}
+	return nil
+}
+
+// PurgeArticle removes an article and its history from pool and reports
+// whether any trace of it is left.
+func PurgeArticle(ctx context.Context, pool *pgxpool.Pool, id int64) (remaining bool, err error) {
+	if _, err := pool.Exec(ctx, `DELETE FROM articles WHERE id=$1`, id); err != nil {
+		return true, err
+	}
+	if _, err := pool.Exec(ctx, `DELETE FROM article_revisions WHERE article_id=$1`, id); err != nil {
+		return true, err
+	}
+	// Etiketlerin kendisi kalır; başka makalelerle paylaşılır
+	if _, err := pool.Exec(ctx, `DELETE FROM article_tags WHERE article_id=$1`, id); err != nil {
+		return true, err
+	}
+	if _, err := pool.Exec(ctx, `DELETE FROM article_categories WHERE article_id=$1`, id); err != nil {
+		return true, err
+	}
+	if _, err := pool.Exec(ctx, `DELETE FROM comments WHERE article_id=$1`, id); err != nil {
+		return true, err
+	}
+	if _, err := pool.Exec(ctx, `DELETE FROM attachments WHERE article_id=$1`, id); err != nil {
+		return true, err
+	}
+	err = pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM articles WHERE id=$1)
+			OR EXISTS (SELECT 1 FROM article_revisions WHERE article_id=$1)
+			OR EXISTS (SELECT 1 FROM article_tags WHERE article_id=$1)
+			OR EXISTS (SELECT 1 FROM article_categories WHERE article_id=$1)
+			OR EXISTS (SELECT 1 FROM comments WHERE article_id=$1)
+			OR EXISTS (SELECT 1 FROM attachments WHERE article_id=$1)
+	`, id).Scan(&remaining)
+	return remaining, err
+}
+
+// RevisionColumns is the column list matching ScanRevision.
+const RevisionColumns = `article_id, title, summary, content_long, author, region, created_at,
+	allowed_countries, denied_countries, author_id, revision, recorded_at,
+	editor, editor_id, deleted, status, publish_at, region_publish_at, tags, categories,
+	locale, translations, content_html, toc, seq`
+
+// ScanRevision scans a row selected with RevisionColumns.
+func ScanRevision(row pgx.Row, r *model.ArticleRevision) error {
+	return row.Scan(&r.ID, &r.Title, &r.Summary, &r.ContentLong, &r.Author, &r.Region, &r.CreatedAt,
+		&r.AllowedCountries, &r.DeniedCountries, &r.AuthorID, &r.Revision, &r.RecordedAt,
+		&r.Editor, &r.EditorID, &r.Deleted, &r.Status, &r.PublishAt, &r.RegionPublishAt, &r.Tags, &r.Categories,
+		&r.Locale, &r.Translations, &r.ContentHTML, &r.TOC, &r.Seq)
+}
+
+// CopyRevision writes a master revision to a replica. Revisions never
+// change, so an existing row is left alone apart from its seq: rows that
+// got a replica-local seq when the column was added take the master's.
+func CopyRevision(ctx context.Context, pool *pgxpool.Pool, r model.ArticleRevision) error {
+	_, err := pool.Exec(ctx, `
+		INSERT INTO article_revisions (article_id, title, summary, content_long, author, region, created_at,
+			allowed_countries, denied_countries, author_id, revision, recorded_at, editor, editor_id, deleted,
+			status, publish_at, region_publish_at, tags, categories, locale, translations, content_html, toc, seq)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24,$25)
+		ON CONFLICT (article_id, revision) DO UPDATE SET seq = EXCLUDED.seq
+		WHERE article_revisions.seq <> EXCLUDED.seq
+	`, r.ID, r.Title, r.Summary, r.ContentLong, r.Author, r.Region, r.CreatedAt,
+		countryList(r.AllowedCountries), countryList(r.DeniedCountries), r.AuthorID, r.Revision, r.RecordedAt,
+		r.Editor, r.EditorID, r.Deleted, r.Status, r.PublishAt, publishTimes(r.RegionPublishAt),
+		slugList(r.Tags), slugList(r.Categories), r.Locale, Translations(r.Translations),
+		r.ContentHTML, TOC(r.TOC), r.Seq)
+	return err
+}
+
+// slugList avoids writing NULL for an empty tag or category list.
+func slugList(slugs []string) []string {
+	if slugs == nil {
+		return []string{}
+	}
+	return slugs
+}
+
+// countryList avoids writing NULL into the NOT NULL array columns.
+func countryList(codes []string) []string {
+	if codes == nil {
+		return []string{}
+	}
+	return codes
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"geo-repl-demo/internal/model"
+)
+
+// AttachmentColumns is the column list matching ScanAttachment.
+const AttachmentColumns = `id, article_id, blob_key, filename, content_type, size, sha256,
+	uploaded_by, created_at`
+
+// ScanAttachment scans a row selected with AttachmentColumns.
+func ScanAttachment(row pgx.Row, a *model.Attachment) error {
+	return row.Scan(&a.ID, &a.ArticleID, &a.Key, &a.Filename, &a.ContentType, &a.Size, &a.SHA256,
+		&a.UploadedBy, &a.CreatedAt)
+}
+
+// CopyAttachment writes a master attachment row to a replica. Attachments
+// never change, so an existing row is left alone.
+func CopyAttachment(ctx context.Context, pool *pgxpool.Pool, a model.Attachment) error {
+	_, err := pool.Exec(ctx, `
+		INSERT INTO attachments (id, article_id, blob_key, filename, content_type, size, sha256,
+			uploaded_by, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+		ON CONFLICT (id) DO NOTHING
+	`, a.ID, a.ArticleID, a.Key, a.Filename, a.ContentType, a.Size, a.SHA256,
+		a.UploadedBy, a.CreatedAt)
+	return err
+}
+
+// RecordBlobCopy notes on the master that node's blob store holds the
+// contents of an attachment.
+func RecordBlobCopy(ctx context.Context, master *pgxpool.Pool, attachmentID int64, node string) error {
+	_, err := master.Exec(ctx, `
+		INSERT INTO attachment_copies (attachment_id, node) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, attachmentID, node)
+	return err
+}
+
+// BlobLag returns how many attachments node's blob store is missing
+// according to the master, and how long the oldest has been waiting.
+func BlobLag(ctx context.Context, master *pgxpool.Pool, node string) (int, time.Duration, error) {
+	var pending int
+	var oldest *time.Time
+	err := master.QueryRow(ctx, `
+		SELECT COUNT(*), MIN(a.created_at) FROM attachments a
+		WHERE NOT EXISTS (
+			SELECT 1 FROM attachment_copies c WHERE c.attachment_id = a.id AND c.node = $1
+		)
+	`, node).Scan(&pending, &oldest)
+	if err != nil || oldest == nil {
+		return pending, 0, err
+	}
+	return pending, time.Since(*oldest), nil
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"geo-repl-demo/internal/model"
+)
+
+// AuditColumns is the column list matching ScanAudit.
+const AuditColumns = `id, at, actor, actor_id, role, action, entity, target, client_ip, region,
+	outcome, before_hash, after_hash, detail`
+
+// ScanAudit scans a row selected with AuditColumns.
+func ScanAudit(row pgx.Row, e *model.AuditEntry) error {
+	return row.Scan(&e.ID, &e.Time, &e.Actor, &e.ActorID, &e.Role, &e.Action, &e.Entity, &e.Target,
+		&e.ClientIP, &e.Region, &e.Outcome, &e.BeforeHash, &e.AfterHash, &e.Detail)
+}
+
+// InsertAudit appends an entry on the master and returns it with its ID.
+func InsertAudit(ctx context.Context, pool *pgxpool.Pool, e model.AuditEntry) (model.AuditEntry, error) {
+	var out model.AuditEntry
+	err := ScanAudit(pool.QueryRow(ctx, `
+		INSERT INTO audit_log (at, actor, actor_id, role, action, entity, target, client_ip, region,
+			outcome, before_hash, after_hash, detail)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+		RETURNING `+AuditColumns,
+		e.Time, e.Actor, e.ActorID, e.Role, e.Action, e.Entity, e.Target, e.ClientIP, e.Region,
+		e.Outcome, e.BeforeHash, e.AfterHash, detailOrEmpty(e.Detail)), &out)
+	return out, err
+}
+
+// CopyAudit writes a master entry to a replica. Entries never change, so an
+// existing row is left alone.
+func CopyAudit(ctx context.Context, pool *pgxpool.Pool, e model.AuditEntry) error {
+	_, err := pool.Exec(ctx, `
+		INSERT INTO audit_log (id, at, actor, actor_id, role, action, entity, target, client_ip, region,
+			outcome, before_hash, after_hash, detail)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)
+		ON CONFLICT (id) DO NOTHING
+	`, e.ID, e.Time, e.Actor, e.ActorID, e.Role, e.Action, e.Entity, e.Target, e.ClientIP, e.Region,
+		e.Outcome, e.BeforeHash, e.AfterHash, detailOrEmpty(e.Detail))
+	return err
+}
+
+func detailOrEmpty(d map[string]string) map[string]string {
+	if d == nil {
+		return map[string]string{}
+	}
+	return d
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"geo-repl-demo/internal/model"
+)
+
+// CommentColumns is the column list matching ScanComment.
+const CommentColumns = `id, article_id, COALESCE(parent_id, ''), author_id, author, body,
+	origin, region, clock, created_at, merged_at`
+
+// ScanComment scans a row selected with CommentColumns.
+func ScanComment(row pgx.Row, c *model.Comment) error {
+	return row.Scan(&c.ID, &c.ArticleID, &c.ParentID, &c.AuthorID, &c.Author, &c.Body,
+		&c.Origin, &c.Region, &c.Clock, &c.CreatedAt, &c.MergedAt)
+}
+
+// CopyComment writes a merged master comment to a node. Comments never
+// change; the only update is marking the origin's own copy as merged.
+func CopyComment(ctx context.Context, pool *pgxpool.Pool, c model.Comment) error {
+	_, err := pool.Exec(ctx, `
+		INSERT INTO comments (id, article_id, parent_id, author_id, author, body,
+			origin, region, clock, created_at, merged_at)
+		VALUES ($1,$2,NULLIF($3, ''),$4,$5,$6,$7,$8,$9,$10,$11)
+		ON CONFLICT (id) DO UPDATE SET merged_at=EXCLUDED.merged_at
+		WHERE comments.merged_at IS NULL
+	`, c.ID, c.ArticleID, c.ParentID, c.AuthorID, c.Author, c.Body,
+		c.Origin, c.Region, c.Clock, c.CreatedAt, c.MergedAt)
+	return err
+}
@@ -5,16 +5,19 @@ import (
 	"fmt"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"geo-repl-demo/internal/config"
 )
 
 // Master wraps a pgx connection pool for the master database.
 type Master struct {
 	Pool *pgxpool.Pool
+	Node config.Node
 }
 
 // NewMaster connects to the master database.
-func NewMaster(dsn string) (*Master, error) {
-	cfg, err := pgxpool.ParseConfig(dsn)
+func NewMaster(node config.Node) (*Master, error) {
+	cfg, err := pgxpool.ParseConfig(node.DSN)
 	if err != nil {
 		return nil, fmt.Errorf("parse master dsn: %w", err)
 	}
@@ -22,29 +25,10 @@ func NewMaster(dsn string) (*Master, error) {
 	if err != nil {
 		return nil, fmt.Errorf("connect master: %w", err)
 	}
-	return &Master{Pool: pool}, nil
+	return &Master{Pool: pool, Node: node}, nil
 }
 
 // Close closes the underlying pool.
 func (m *Master) Close() {
 	m.Pool.Close()
 }
-
-// EnsureSchema creates the articles table if it does not exist.
-// This is a safeguard in addition to the SQL init script.
-func EnsureSchema(m *Master) error {
-	_, err := m.Pool.Exec(context.Background(), `
-CREATE TABLE IF NOT EXISTS articles (
-    id SERIAL PRIMARY KEY,
-    title TEXT NOT NULL,
-    content TEXT NOT NULL,
-    author TEXT NOT NULL,
-    region TEXT NOT NULL,
-    created_at TIMESTAMP NOT NULL DEFAULT NOW()
-);
-`)
-	return err
-}
-
-
-
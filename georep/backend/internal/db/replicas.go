@@ -2,40 +2,213 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"geo-repl-demo/internal/config"
 )
 
+// Replica is a single replica node and its connection pool.
+// A replica only takes reads once it has been bootstrapped.
+type Replica struct {
+	Node    config.Node
+	Pool    *pgxpool.Pool
+	ready   atomic.Bool
+	healthy atomic.Bool
+}
+
+// Ready reports whether the replica has been bootstrapped.
+func (r *Replica) Ready() bool {
+	return r.ready.Load()
+}
+
+// Healthy reports whether the replica is ready and answered the last
+// health check.
+func (r *Replica) Healthy() bool {
+	return r.ready.Load() && r.healthy.Load()
+}
+
+func (r *Replica) markReady() {
+	r.healthy.Store(true)
+	r.ready.Store(true)
+}
+
+// BootstrapFunc prepares a freshly connected replica (schema + initial copy)
+// before it is allowed to serve reads.
+type BootstrapFunc func(ctx context.Context, r *Replica) error
+
 // ReplicaSet holds connections to all replica databases.
 // We simulate replication in application code, not at the Postgres level.
+// The set can be changed at runtime with Apply; callers should always go
+// through All/Get instead of keeping their own references.
 type ReplicaSet struct {
-	Pools []*pgxpool.Pool
+	mu       sync.RWMutex
+	replicas []*Replica
 }
 
-// NewReplicas connects to the given replica DSNs.
-func NewReplicas(dsns []string) (*ReplicaSet, error) {
-	pools := make([]*pgxpool.Pool, 0, len(dsns))
-	for i, dsn := range dsns {
-		cfg, err := pgxpool.ParseConfig(dsn)
+// NewReplicas connects to the given replica nodes.
+func NewReplicas(nodes []config.Node) (*ReplicaSet, error) {
+	set := &ReplicaSet{}
+	for i, n := range nodes {
+		rep, err := connectReplica(n)
 		if err != nil {
-			return nil, fmt.Errorf("parse replica %d dsn: %w", i+1, err)
+			set.Close()
+			return nil, fmt.Errorf("replica %d: %w", i+1, err)
 		}
-		pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
-		if err != nil {
-			return nil, fmt.Errorf("connect replica %d: %w", i+1, err)
+		rep.markReady()
+		set.replicas = append(set.replicas, rep)
+	}
+	return set, nil
+}
+
+func connectReplica(n config.Node) (*Replica, error) {
+	cfg, err := pgxpool.ParseConfig(n.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s dsn: %w", n.Name, err)
+	}
+	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("connect %s: %w", n.Name, err)
+	}
+	return &Replica{Node: n, Pool: pool}, nil
+}
+
+// All returns a snapshot of all replicas in topology order,
+// including ones that are still bootstrapping.
+func (r *ReplicaSet) All() []*Replica {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]*Replica, len(r.replicas))
+	copy(out, r.replicas)
+	return out
+}
+
+// Get returns the named replica if it exists and is ready for reads.
+func (r *ReplicaSet) Get(name string) (*Replica, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, rep := range r.replicas {
+		if rep.Node.Name == name {
+			return rep, rep.Ready()
 		}
-		pools = append(pools, pool)
 	}
-	return &ReplicaSet{Pools: pools}, nil
+	return nil, false
 }
 
-// Close closes all replica pools.
-func (r *ReplicaSet) Close() {
-	for _, p := range r.Pools {
-		p.Close()
+// Healthy reports whether the named replica can currently serve reads.
+func (r *ReplicaSet) Healthy(name string) bool {
+	rep, ok := r.Get(name)
+	return ok && rep.Healthy()
+}
+
+// CheckHealth pings every replica and records the result.
+func (r *ReplicaSet) CheckHealth(ctx context.Context) {
+	for _, rep := range r.All() {
+		ok := rep.Pool.Ping(ctx) == nil
+		if was := rep.healthy.Swap(ok); was != ok {
+			log.Printf("🩺 Replika %s sağlık durumu değişti: healthy=%v", rep.Node.Name, ok)
+		}
 	}
 }
 
+// Len returns the number of replicas.
+func (r *ReplicaSet) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.replicas)
+}
+
+// Apply reconciles the set with the given nodes. New nodes (or nodes whose
+// DSN changed) join the set immediately so replication writes reach them,
+// but only become ready for reads after bootstrap succeeds; a node whose
+// bootstrap fails is dropped again. Unchanged nodes keep their pools.
+// Replicas that are no longer part of the topology are removed from the set
+// and returned so the caller can drain them once routing no longer points
+// at them.
+func (r *ReplicaSet) Apply(ctx context.Context, nodes []config.Node, bootstrap BootstrapFunc) ([]*Replica, error) {
+	r.mu.Lock()
+	current := map[string]*Replica{}
+	for _, rep := range r.replicas {
+		current[rep.Node.Name] = rep
+	}
+
+	next := make([]*Replica, 0, len(nodes))
+	var added []*Replica
+	var errs []error
+	for _, n := range nodes {
+		if rep, ok := current[n.Name]; ok && rep.Node.DSN == n.DSN {
+			// Etiket/koordinat gibi metadata değişiklikleri havuzu etkilemez
+			kept := &Replica{Node: n, Pool: rep.Pool}
+			kept.ready.Store(rep.Ready())
+			kept.healthy.Store(rep.healthy.Load())
+			next = append(next, kept)
+			delete(current, n.Name)
+			continue
+		}
+		rep, err := connectReplica(n)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		added = append(added, rep)
+		next = append(next, rep)
+	}
+	r.replicas = next
+	r.mu.Unlock()
+
+	// Yeni node'lar okuma almadan önce bootstrap edilir
+	for _, rep := range added {
+		if bootstrap != nil {
+			if err := bootstrap(ctx, rep); err != nil {
+				errs = append(errs, fmt.Errorf("bootstrap %s: %w", rep.Node.Name, err))
+				r.remove(rep)
+				rep.Pool.Close()
+				continue
+			}
+		}
+		rep.markReady()
+	}
+
+	removed := make([]*Replica, 0, len(current))
+	for _, rep := range current {
+		removed = append(removed, rep)
+	}
+	return removed, errors.Join(errs...)
+}
+
+func (r *ReplicaSet) remove(target *Replica) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, rep := range r.replicas {
+		if rep == target {
+			r.replicas = append(r.replicas[:i:i], r.replicas[i+1:]...)
+			return
+		}
+	}
+}
 
+// Drain closes the pools of removed replicas after a grace period so that
+// requests which already picked the pool can finish. Close itself blocks
+// until every acquired connection has been released.
+func Drain(removed []*Replica, grace time.Duration) {
+	for _, rep := range removed {
+		go func(rep *Replica) {
+			time.Sleep(grace)
+			rep.Pool.Close()
+			log.Printf("🔌 Replika %s kapatıldı (drain tamamlandı)", rep.Node.Name)
+		}(rep)
+	}
+}
 
+// Close closes all replica pools.
+func (r *ReplicaSet) Close() {
+	for _, rep := range r.All() {
+		rep.Pool.Close()
+	}
+}
@@ -0,0 +1,259 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// articlesTable is the articles table shared by the master and every replica.
+const articlesTable = `
+CREATE TABLE IF NOT EXISTS articles (
+    id SERIAL PRIMARY KEY,
+    title TEXT NOT NULL,
+    summary TEXT NOT NULL,
+    content_long TEXT NOT NULL,
+    author TEXT NOT NULL,
+    region TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+)`
+
+// migrations bring databases created from an older schema up to date. They
+// run in order on the master and on every replica and must be idempotent.
+var migrations = []string{
+	articlesTable,
+	`ALTER TABLE articles ADD COLUMN IF NOT EXISTS allowed_countries TEXT[] NOT NULL DEFAULT '{}'`,
+	`ALTER TABLE articles ADD COLUMN IF NOT EXISTS denied_countries TEXT[] NOT NULL DEFAULT '{}'`,
+	`CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    username TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    role TEXT NOT NULL DEFAULT 'reader',
+    home_region TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+)`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE articles ADD COLUMN IF NOT EXISTS author_id BIGINT`,
+	`CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    key_id TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    owner_id BIGINT NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    region TEXT NOT NULL,
+    quota_per_hour INT NOT NULL DEFAULT 0,
+    secret_hash TEXT NOT NULL,
+    previous_hash TEXT NOT NULL DEFAULT '',
+    previous_expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMP
+)`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_subject TEXT`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS users_oidc_subject_key ON users (oidc_subject)`,
+	`CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    actor TEXT NOT NULL,
+    actor_id BIGINT NOT NULL DEFAULT 0,
+    role TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL,
+    entity TEXT NOT NULL DEFAULT '',
+    target TEXT NOT NULL DEFAULT '',
+    client_ip TEXT NOT NULL DEFAULT '',
+    region TEXT NOT NULL DEFAULT '',
+    outcome TEXT NOT NULL,
+    before_hash TEXT NOT NULL DEFAULT '',
+    after_hash TEXT NOT NULL DEFAULT '',
+    detail JSONB NOT NULL DEFAULT '{}'
+)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_entity_idx ON audit_log (entity, target)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log (actor)`,
+	// Audit kayıtları yalnızca eklenir: UPDATE / DELETE / TRUNCATE reddedilir
+	`CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END
+$$ LANGUAGE plpgsql`,
+	`CREATE OR REPLACE TRIGGER audit_log_no_update
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only()`,
+	`CREATE OR REPLACE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only()`,
+	`ALTER TABLE articles ADD COLUMN IF NOT EXISTS revision INT NOT NULL DEFAULT 1`,
+	`ALTER TABLE articles ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT NOW()`,
+	`CREATE TABLE IF NOT EXISTS article_revisions (
+    article_id BIGINT NOT NULL,
+    revision INT NOT NULL,
+    title TEXT NOT NULL,
+    summary TEXT NOT NULL,
+    content_long TEXT NOT NULL,
+    author TEXT NOT NULL,
+    author_id BIGINT NOT NULL DEFAULT 0,
+    region TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    allowed_countries TEXT[] NOT NULL DEFAULT '{}',
+    denied_countries TEXT[] NOT NULL DEFAULT '{}',
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    editor TEXT NOT NULL DEFAULT '',
+    editor_id BIGINT NOT NULL DEFAULT 0,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (article_id, revision)
+)`,
+	`CREATE INDEX IF NOT EXISTS article_revisions_recorded_idx ON article_revisions (recorded_at)`,
+	`ALTER TABLE articles ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP`,
+	// Yalnızca master'da doldurulur: süresi dolup kalıcı silinen makaleler ve onaylayan replikalar
+	`CREATE TABLE IF NOT EXISTS article_purges (
+    article_id BIGINT PRIMARY KEY,
+    purged_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    confirmed_at TIMESTAMPTZ,
+    confirmed_replicas TEXT[] NOT NULL DEFAULT '{}'
+)`,
+	`ALTER TABLE articles ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'published'`,
+	`ALTER TABLE articles ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ`,
+	`ALTER TABLE articles ADD COLUMN IF NOT EXISTS region_publish_at JSONB NOT NULL DEFAULT '{}'`,
+	`ALTER TABLE article_revisions ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'published'`,
+	`ALTER TABLE article_revisions ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ`,
+	`ALTER TABLE article_revisions ADD COLUMN IF NOT EXISTS region_publish_at JSONB NOT NULL DEFAULT '{}'`,
+	`CREATE INDEX IF NOT EXISTS articles_scheduled_idx ON articles (publish_at) WHERE status = 'scheduled'`,
+	// Etiketler serbesttir (ilk kullanımda oluşur), kategoriler editörlerce tanımlanır
+	`CREATE TABLE IF NOT EXISTS tags (
+    slug TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+)`,
+	`CREATE TABLE IF NOT EXISTS categories (
+    slug TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+)`,
+	`CREATE TABLE IF NOT EXISTS article_tags (
+    article_id BIGINT NOT NULL,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (article_id, tag)
+)`,
+	`CREATE INDEX IF NOT EXISTS article_tags_tag_idx ON article_tags (tag)`,
+	`CREATE TABLE IF NOT EXISTS article_categories (
+    article_id BIGINT NOT NULL,
+    category TEXT NOT NULL,
+    PRIMARY KEY (article_id, category)
+)`,
+	`CREATE INDEX IF NOT EXISTS article_categories_category_idx ON article_categories (category)`,
+	`ALTER TABLE article_revisions ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}'`,
+	`ALTER TABLE article_revisions ADD COLUMN IF NOT EXISTS categories TEXT[] NOT NULL DEFAULT '{}'`,
+	// Yorumlar okuyucunun bölge node'una yazılır ve master'da birleştirilir;
+	// id UUIDv7'dir, clock yanıtın her zaman üst yorumdan sonra sıralanmasını sağlar
+	`CREATE TABLE IF NOT EXISTS comments (
+    id TEXT PRIMARY KEY,
+    article_id BIGINT NOT NULL,
+    parent_id TEXT,
+    author_id BIGINT NOT NULL,
+    author TEXT NOT NULL,
+    body TEXT NOT NULL,
+    origin TEXT NOT NULL,
+    region TEXT NOT NULL DEFAULT '',
+    clock BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    merged_at TIMESTAMPTZ
+)`,
+	`CREATE INDEX IF NOT EXISTS comments_article_idx ON comments (article_id, clock, id)`,
+	`CREATE INDEX IF NOT EXISTS comments_unmerged_idx ON comments (clock, id) WHERE merged_at IS NULL`,
+	// Ek dosyaların içeriği blob store'dadır; satır yalnızca üstveriyi taşır
+	`CREATE TABLE IF NOT EXISTS attachments (
+    id BIGSERIAL PRIMARY KEY,
+    article_id BIGINT NOT NULL,
+    blob_key TEXT NOT NULL UNIQUE,
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 TEXT NOT NULL,
+    uploaded_by BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+)`,
+	`CREATE INDEX IF NOT EXISTS attachments_article_idx ON attachments (article_id, id)`,
+	// Çeviriler makale satırında tutulur; böylece makaleyle birlikte tek parça replike olur
+	`ALTER TABLE articles ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT 'tr'`,
+	`ALTER TABLE articles ADD COLUMN IF NOT EXISTS translations JSONB NOT NULL DEFAULT '{}'`,
+	`ALTER TABLE article_revisions ADD COLUMN IF NOT EXISTS locale TEXT NOT NULL DEFAULT 'tr'`,
+	`ALTER TABLE article_revisions ADD COLUMN IF NOT EXISTS translations JSONB NOT NULL DEFAULT '{}'`,
+	// Yalnızca master'da doldurulur: hangi blob hangi node'un store'una kopyalandı (blob gecikmesi için)
+	`CREATE TABLE IF NOT EXISTS attachment_copies (
+    attachment_id BIGINT NOT NULL,
+    node TEXT NOT NULL,
+    copied_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (attachment_id, node)
+)`,
+	// content_long Markdown'dır; HTML ve içindekiler yazarken üretilip satırla birlikte replike olur
+	`ALTER TABLE articles ADD COLUMN IF NOT EXISTS content_html TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE articles ADD COLUMN IF NOT EXISTS toc JSONB NOT NULL DEFAULT '[]'`,
+	`ALTER TABLE article_revisions ADD COLUMN IF NOT EXISTS content_html TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE article_revisions ADD COLUMN IF NOT EXISTS toc JSONB NOT NULL DEFAULT '[]'`,
+	// Master'ın ekleme sırası; replikalar revizyonları bu sıradan artımlı eşitler
+	`ALTER TABLE article_revisions ADD COLUMN IF NOT EXISTS seq BIGSERIAL`,
+	`CREATE INDEX IF NOT EXISTS article_revisions_seq_idx ON article_revisions (seq)`,
+}
+
+// SystemUsername owns content that predates user accounts, such as the
+// "Seed Bot" articles of the init script. It has no usable password.
+const SystemUsername = "system"
+
+// EnsureSchema creates or upgrades the tables on the master.
+// This is a safeguard in addition to the SQL init script.
+func EnsureSchema(m *Master) error {
+	ctx := context.Background()
+	if err := migrate(ctx, m.Pool); err != nil {
+		return err
+	}
+	return migrateMasterData(ctx, m)
+}
+
+// migrateMasterData runs data migrations that only make sense on the
+// master; their results reach the replicas through normal replication.
+func migrateMasterData(ctx context.Context, m *Master) error {
+	// '!' hiçbir bcrypt hash'i ile eşleşmez: sistem kullanıcısı giriş yapamaz
+	if _, err := m.Pool.Exec(ctx, `
+		INSERT INTO users (username, password_hash, role, home_region, display_name)
+		VALUES ($1, '!', 'editor', $2, 'Seed Bot')
+		ON CONFLICT (username) DO NOTHING
+	`, SystemUsername, m.Node.Region); err != nil {
+		return fmt.Errorf("system user: %w", err)
+	}
+	// Kullanıcı hesaplarından önceki makaleler ("Seed Bot") sistem kullanıcısına geçer
+	if _, err := m.Pool.Exec(ctx, `
+		UPDATE articles SET author_id = (SELECT id FROM users WHERE username = $1)
+		WHERE author_id IS NULL
+	`, SystemUsername); err != nil {
+		return fmt.Errorf("article owners: %w", err)
+	}
+	// Geçmişi olmayan makaleler için mevcut hali ilk revizyon olarak kaydedilir
+	if _, err := m.Pool.Exec(ctx, `
+		INSERT INTO article_revisions (article_id, revision, title, summary, content_long, author, author_id,
+			region, created_at, allowed_countries, denied_countries, recorded_at, editor, editor_id)
+		SELECT a.id, a.revision, a.title, COALESCE(a.summary, ''), COALESCE(a.content_long, ''), a.author,
+			COALESCE(a.author_id, 0), a.region, a.created_at, a.allowed_countries, a.denied_countries,
+			a.created_at, a.author, COALESCE(a.author_id, 0)
+		FROM articles a
+		WHERE NOT EXISTS (SELECT 1 FROM article_revisions r WHERE r.article_id = a.id)
+	`); err != nil {
+		return fmt.Errorf("article revisions: %w", err)
+	}
+	return nil
+}
+
+// EnsureReplicaSchema creates or upgrades the tables on a replica.
+func EnsureReplicaSchema(ctx context.Context, pool *pgxpool.Pool) error {
+	return migrate(ctx, pool)
+}
+
+func migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	for i, stmt := range migrations {
+		if _, err := pool.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("migration %d: %w", i, err)
+		}
+	}
+	return nil
+}
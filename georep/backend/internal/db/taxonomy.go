@@ -0,0 +1,34 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"geo-repl-demo/internal/model"
+)
+
+// CategoryColumns is the column list matching ScanCategory.
+const CategoryColumns = `slug, name, description, created_at, updated_at`
+
+// ScanCategory scans a row selected with CategoryColumns.
+func ScanCategory(row pgx.Row, c *model.Category) error {
+	return row.Scan(&c.Slug, &c.Name, &c.Description, &c.CreatedAt, &c.UpdatedAt)
+}
+
+// UpsertCategory writes a copy of a master category to a replica. The
+// category links of articles travel with the articles themselves.
+func UpsertCategory(ctx context.Context, pool *pgxpool.Pool, c model.Category) error {
+	_, err := pool.Exec(ctx, `
+		INSERT INTO categories (slug, name, description, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$5)
+		ON CONFLICT (slug) DO UPDATE SET
+			name=EXCLUDED.name,
+			description=EXCLUDED.description,
+			created_at=EXCLUDED.created_at,
+			updated_at=EXCLUDED.updated_at
+		WHERE categories.updated_at <= EXCLUDED.updated_at
+	`, c.Slug, c.Name, c.Description, c.CreatedAt, c.UpdatedAt)
+	return err
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"geo-repl-demo/internal/model"
+)
+
+// UserColumns is the column list matching ScanUser.
+const UserColumns = `id, username, password_hash, role, home_region, created_at, updated_at, display_name,
+	COALESCE(oidc_subject, '')`
+
+// ScanUser scans a row selected with UserColumns.
+func ScanUser(row pgx.Row, u *model.User) error {
+	return row.Scan(&u.ID, &u.Username, &u.PasswordHash, &u.Role, &u.HomeRegion, &u.CreatedAt, &u.UpdatedAt, &u.DisplayName, &u.OIDCSubject)
+}
+
+// UpsertUser writes a copy of a master user to a replica. Password hashes
+// are replicated so that logins can be served at any region.
+func UpsertUser(ctx context.Context, pool *pgxpool.Pool, u model.User) error {
+	_, err := pool.Exec(ctx, `
+		INSERT INTO users (id, username, password_hash, role, home_region, created_at, updated_at, display_name, oidc_subject)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,NULLIF($9, ''))
+		ON CONFLICT (id) DO UPDATE SET
+			username=EXCLUDED.username,
+			password_hash=EXCLUDED.password_hash,
+			role=EXCLUDED.role,
+			home_region=EXCLUDED.home_region,
+			created_at=EXCLUDED.created_at,
+			updated_at=EXCLUDED.updated_at,
+			display_name=EXCLUDED.display_name,
+			oidc_subject=EXCLUDED.oidc_subject
+	`, u.ID, u.Username, u.PasswordHash, u.Role, u.HomeRegion, u.CreatedAt, u.UpdatedAt, u.DisplayName, u.OIDCSubject)
+	return err
+}
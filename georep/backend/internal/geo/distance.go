@@ -0,0 +1,51 @@
+package geo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// EarthRadiusKm is the mean Earth radius used for great-circle distances.
+const EarthRadiusKm = 6371.0
+
+// Point is a WGS84 coordinate in decimal degrees.
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Valid reports whether the point lies within the WGS84 coordinate ranges.
+func (p Point) Valid() bool {
+	return p.Lat >= -90 && p.Lat <= 90 && p.Lon >= -180 && p.Lon <= 180
+}
+
+// DistanceKm returns the great-circle distance between two points
+// using the haversine formula.
+func DistanceKm(a, b Point) float64 {
+	lat1 := a.Lat * math.Pi / 180
+	lat2 := b.Lat * math.Pi / 180
+	dLat := (b.Lat - a.Lat) * math.Pi / 180
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// ParsePoint parses lat/lon strings (e.g. query parameters).
+func ParsePoint(lat, lon string) (Point, error) {
+	la, err := strconv.ParseFloat(lat, 64)
+	if err != nil {
+		return Point{}, fmt.Errorf("invalid lat: %w", err)
+	}
+	lo, err := strconv.ParseFloat(lon, 64)
+	if err != nil {
+		return Point{}, fmt.Errorf("invalid lon: %w", err)
+	}
+	p := Point{Lat: la, Lon: lo}
+	if !p.Valid() {
+		return Point{}, fmt.Errorf("coordinates out of range")
+	}
+	return p, nil
+}
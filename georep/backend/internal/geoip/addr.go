@@ -0,0 +1,45 @@
+package geoip
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// ParseAddr parses an IPv4 or IPv6 address as it appears in headers and
+// RemoteAddr: "192.0.2.1", "192.0.2.1:443", "2001:db8::1",
+// "[2001:db8::1]" or "[2001:db8::1]:443". Zones are dropped and
+// IPv4-mapped IPv6 addresses are unmapped.
+func ParseAddr(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return netip.Addr{}, false
+	}
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return normalizeAddr(ap.Addr()), true
+	}
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		s = s[1 : len(s)-1]
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return normalizeAddr(addr), true
+}
+
+// IsPublic reports whether addr is globally routable, i.e. worth a GeoIP
+// lookup. Loopback, private (RFC 1918 / RFC 4193), link-local,
+// unspecified and multicast addresses are not.
+func IsPublic(addr netip.Addr) bool {
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified()
+}
+
+func normalizeAddr(addr netip.Addr) netip.Addr {
+	return addr.WithZone("").Unmap()
+}
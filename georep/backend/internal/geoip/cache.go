@@ -0,0 +1,126 @@
+package geoip
+
+import (
+	"container/list"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// CacheStats reports cache effectiveness.
+type CacheStats struct {
+	Hits     uint64 `json:"hits"`
+	Misses   uint64 `json:"misses"`
+	Size     int    `json:"size"`
+	Capacity int    `json:"capacity"`
+}
+
+// Cache is an LRU lookup cache in front of another provider. Results are
+// cached per address, including "not found" answers. If the underlying
+// provider reports a Generation (see Reloading), the cache is purged when
+// the generation changes.
+type Cache struct {
+	next     Provider
+	capacity int
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+	gen   uint64
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+type cacheEntry struct {
+	key string
+	rec Record
+	err error
+}
+
+type generational interface {
+	Generation() uint64
+}
+
+// NewCache wraps next with an LRU cache holding up to capacity entries.
+func NewCache(next Provider, capacity int) *Cache {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &Cache{
+		next:     next,
+		capacity: capacity,
+		ll:       list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
+
+// Lookup implements Provider.
+func (c *Cache) Lookup(ip net.IP) (Record, error) {
+	key := string(ip.To16())
+
+	c.mu.Lock()
+	c.checkGeneration()
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		e := el.Value.(*cacheEntry)
+		c.mu.Unlock()
+		c.hits.Add(1)
+		return e.rec, e.err
+	}
+	gen := c.gen
+	c.mu.Unlock()
+
+	c.misses.Add(1)
+	rec, err := c.next.Lookup(ip)
+	// Geçici durumları (henüz yüklenmemiş DB) cache'lemeyelim
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return rec, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Sorgu sürerken veritabanı yeniden yüklendiyse cevap eski DB'dendir:
+	// temizlenmiş cache'e yazılırsa bir sonraki yüklemeye kadar kalır
+	c.checkGeneration()
+	if c.gen != gen {
+		return rec, err
+	}
+	if _, ok := c.items[key]; !ok {
+		c.items[key] = c.ll.PushFront(&cacheEntry{key: key, rec: rec, err: err})
+		if c.ll.Len() > c.capacity {
+			oldest := c.ll.Back()
+			c.ll.Remove(oldest)
+			delete(c.items, oldest.Value.(*cacheEntry).key)
+		}
+	}
+	return rec, err
+}
+
+// checkGeneration purges the cache if the underlying database changed.
+// Must be called with c.mu held.
+func (c *Cache) checkGeneration() {
+	g, ok := c.next.(generational)
+	if !ok {
+		return
+	}
+	if cur := g.Generation(); cur != c.gen {
+		c.gen = cur
+		c.ll.Init()
+		c.items = map[string]*list.Element{}
+	}
+}
+
+// Stats returns hit/miss counters and the current size.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	size := c.ll.Len()
+	c.mu.Unlock()
+	return CacheStats{
+		Hits:     c.hits.Load(),
+		Misses:   c.misses.Load(),
+		Size:     size,
+		Capacity: c.capacity,
+	}
+}
@@ -0,0 +1,73 @@
+package geoip
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+)
+
+// swapping answers with the country of its current database; hook runs
+// inside Lookup, between reading the database and returning.
+type swapping struct {
+	gen     atomic.Uint64
+	country atomic.Value
+	lookups atomic.Int32
+	hook    func()
+}
+
+func (s *swapping) Lookup(net.IP) (Record, error) {
+	s.lookups.Add(1)
+	rec := Record{Country: s.country.Load().(string)}
+	if s.hook != nil {
+		s.hook()
+	}
+	return rec, nil
+}
+
+func (s *swapping) Generation() uint64 { return s.gen.Load() }
+
+func (s *swapping) swap(country string) {
+	s.country.Store(country)
+	s.gen.Add(1)
+}
+
+func TestCacheDropsAnswerOfReloadedDatabase(t *testing.T) {
+	src := &swapping{}
+	src.swap("DE")
+	c := NewCache(src, 10)
+	ip := net.ParseIP("198.51.100.1")
+
+	// Sorgu eski veritabanını okuduktan sonra yeni veritabanı yüklenir
+	src.hook = func() {
+		src.hook = nil
+		src.swap("TR")
+	}
+	if rec, _ := c.Lookup(ip); rec.Country != "DE" {
+		t.Fatalf("in-flight lookup = %q, want the old database's DE", rec.Country)
+	}
+	if rec, _ := c.Lookup(ip); rec.Country != "TR" {
+		t.Errorf("lookup after reload = %q, want TR", rec.Country)
+	}
+	if rec, _ := c.Lookup(ip); rec.Country != "TR" || src.lookups.Load() != 2 {
+		t.Errorf("lookup = %q after %d database lookups, want cached TR after 2", rec.Country, src.lookups.Load())
+	}
+}
+
+func TestCachePurgesOnReload(t *testing.T) {
+	src := &swapping{}
+	src.swap("DE")
+	c := NewCache(src, 10)
+	ip := net.ParseIP("198.51.100.1")
+
+	c.Lookup(ip)
+	if rec, _ := c.Lookup(ip); rec.Country != "DE" || src.lookups.Load() != 1 {
+		t.Fatalf("second lookup not cached: %q after %d lookups", rec.Country, src.lookups.Load())
+	}
+	src.swap("TR")
+	if rec, _ := c.Lookup(ip); rec.Country != "TR" {
+		t.Errorf("lookup after reload = %q, want TR", rec.Country)
+	}
+	if s := c.Stats(); s.Hits != 1 || s.Misses != 2 || s.Size != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+}
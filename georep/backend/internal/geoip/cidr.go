@@ -0,0 +1,115 @@
+package geoip
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CIDRTable is a provider backed by a list of CIDR ranges, loaded from a
+// CSV file with the columns
+//
+//	cidr,country[,continent[,lat,lon]]
+//
+// Lines starting with # and a leading "cidr" header are ignored.
+type CIDRTable struct {
+	entries []cidrEntry // most specific prefix first
+}
+
+type cidrEntry struct {
+	prefix netip.Prefix
+	rec    Record
+}
+
+// OpenCIDRFile loads a CSV CIDR table from path.
+func OpenCIDRFile(path string) (*CIDRTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseCIDR(f)
+}
+
+// ParseCIDR reads a CSV CIDR table.
+func ParseCIDR(r io.Reader) (*CIDRTable, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	t := &CIDRTable{}
+	line := 0
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, err
+		}
+		if line == 1 && strings.EqualFold(row[0], "cidr") {
+			continue
+		}
+		if len(row) < 2 {
+			return nil, fmt.Errorf("line %d: expected cidr,country", line)
+		}
+		prefix, err := parsePrefix(row[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rec := Record{Country: strings.ToUpper(strings.TrimSpace(row[1]))}
+		if len(row) > 2 {
+			rec.Continent = strings.ToUpper(strings.TrimSpace(row[2]))
+		}
+		if len(row) > 4 && row[3] != "" && row[4] != "" {
+			lat, err1 := strconv.ParseFloat(row[3], 64)
+			lon, err2 := strconv.ParseFloat(row[4], 64)
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("line %d: invalid coordinates", line)
+			}
+			rec.Point.Lat, rec.Point.Lon, rec.HasPoint = lat, lon, true
+		}
+		t.add(prefix, rec)
+	}
+	t.sort()
+	return t, nil
+}
+
+func (t *CIDRTable) add(prefix netip.Prefix, rec Record) {
+	t.entries = append(t.entries, cidrEntry{prefix: prefix, rec: rec})
+}
+
+// sort orders entries most specific first so Lookup finds the longest match.
+func (t *CIDRTable) sort() {
+	sort.SliceStable(t.entries, func(i, j int) bool {
+		return t.entries[i].prefix.Bits() > t.entries[j].prefix.Bits()
+	})
+}
+
+// Len returns the number of ranges in the table.
+func (t *CIDRTable) Len() int {
+	return len(t.entries)
+}
+
+// Lookup implements Provider with longest-prefix matching.
+func (t *CIDRTable) Lookup(ip net.IP) (Record, error) {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+	addr = addr.Unmap()
+	for _, e := range t.entries {
+		if e.prefix.Contains(addr) {
+			return e.rec, nil
+		}
+	}
+	return Record{}, ErrNotFound
+}
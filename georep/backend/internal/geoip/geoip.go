@@ -1,108 +1,110 @@
 package geoip
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net"
 	"strings"
 
-	"github.com/oschwald/geoip2-golang"
+	"geo-repl-demo/internal/geo"
+	"geo-repl-demo/internal/region"
 )
 
-var db *geoip2.Reader
-
-// 🌍 GeoLite2 veritabanını başlat
-func Init(path string) error {
-	var err error
-	db, err = geoip2.Open(path)
-	if err != nil {
-		return err
+// 🌍 Yapılandırılan GeoIP sağlayıcısını aç: dosyadan hot-reload + LRU cache.
+// kind: "mmdb" (MaxMind Country/City) veya "csv" (CIDR tablosu).
+// Dosya açılamazsa hata döner ama sağlayıcı yine kullanılabilir; dosya
+// düzeldiğinde otomatik olarak yüklenir.
+func Open(kind, path string, cacheSize int) (*Cache, *Reloading, error) {
+	var open OpenFunc
+	switch strings.ToLower(kind) {
+	case "", "mmdb":
+		open = func(p string) (Provider, error) { return OpenMMDB(p) }
+	case "csv":
+		open = func(p string) (Provider, error) { return OpenCIDRFile(p) }
+	default:
+		return nil, nil, fmt.Errorf("unknown geoip provider %q", kind)
 	}
-	return nil
+
+	src, err := NewReloading(path, open)
+	return NewCache(src, cacheSize), src, err
 }
 
-// 🌐 IP adresinden otomatik bölge belirle
-func RegionFromIP(ip string) string {
-	if db == nil {
-		log.Printf("⚠️ GeoIP veritabanı yüklenmemiş, varsayılan bölge 'eu' kullanılıyor")
-		return "eu" // varsayılan
-	}
+// 🌐 IP adresinden otomatik bölge belirle (ülke eşleme dosyası + bölge kayıt defteri)
+func RegionFromIP(p Provider, reg *region.Registry, ip string) string {
+	def := reg.Default().ID
 
-	// IP adresini temizle (port varsa kaldır)
-	if idx := strings.Index(ip, ":"); idx != -1 {
-		ip = ip[:idx]
+	if p == nil {
+		log.Printf("⚠️ GeoIP sağlayıcısı yok, varsayılan bölge '%s' kullanılıyor", def)
+		return def // varsayılan
 	}
 
-	parsed := net.ParseIP(ip)
-	if parsed == nil {
-		log.Printf("⚠️ Geçersiz IP adresi: %s, varsayılan bölge 'eu' kullanılıyor", ip)
-		return "eu"
+	// IPv4, IPv6 ve port'lu biçimleri ("[2001:db8::1]:443") doğru ayrıştır
+	addr, ok := ParseAddr(ip)
+	if !ok {
+		log.Printf("⚠️ Geçersiz IP adresi: %s, varsayılan bölge '%s' kullanılıyor", ip, def)
+		return def
 	}
 
 	// Private IP'ler için GeoIP lookup yapma (spam'i önle)
-	if parsed.IsPrivate() || parsed.IsLoopback() || parsed.IsLinkLocalUnicast() {
-		return "eu" // Sessizce varsayılan dön
+	if !IsPublic(addr) {
+		return def // Sessizce varsayılan dön
 	}
 
-	record, err := db.Country(parsed)
-	if err != nil {
+	record, err := p.Lookup(net.IP(addr.AsSlice()))
+	switch {
+	case errors.Is(err, ErrNotLoaded):
+		log.Printf("⚠️ GeoIP veritabanı yüklenmemiş, varsayılan bölge '%s' kullanılıyor", def)
+		return def
+	case errors.Is(err, ErrNotFound) || (err == nil && record.Country == ""):
+		log.Printf("⚠️ Ülke kodu bulunamadı (IP: %s)", ip)
+		return def
+	case err != nil:
 		// Sadece gerçek hatalarda log (private IP'ler için değil)
 		log.Printf("⚠️ GeoIP lookup hatası (IP: %s): %v", ip, err)
-		return "eu"
-	}
-
-	country := strings.ToUpper(record.Country.IsoCode)
-	if country == "" {
-		log.Printf("⚠️ Ülke kodu bulunamadı (IP: %s)", ip)
-		return "eu"
+		return def
 	}
 
-	log.Printf("🌍 IP: %s → Ülke: %s", ip, country)
-
-	// 🌎 Ülke koduna göre replikasyon bölgesi
-	switch country {
-	case "US", "CA", "MX":
-		return "us"
-
-	case "CN", "JP", "KR", "IN", "ID", "SG", "PH", "TH", "VN", "MY", "TW", "HK":
-		return "asia"
+	log.Printf("🌍 IP: %s → Ülke: %s", ip, record.Country)
 
-	case "TR", "DE", "FR", "IT", "ES", "GB", "NL", "PL", "SE", "NO", "FI", "DK", 
-		 "BE", "AT", "CH", "PT", "GR", "CZ", "HU", "RO", "BG", "HR", "SK", "SI",
-		 "IE", "IS", "LU", "EE", "LV", "LT", "MT", "CY", "RS", "BA", "MK", "AL",
-		 "ME", "XK", "MD", "UA", "BY", "RU", "GE", "AM", "AZ", "KZ", "UZ", "KG",
-		 "TJ", "TM", "AE", "SA", "IL", "QA", "KW", "BH", "OM", "YE", "JO", "LB",
-		 "IQ", "IR", "PS", "SY":
-		return "eu"
-
-	case "BR", "AR", "CL", "CO", "PE", "VE", "EC", "BO", "PY", "UY", "GY", "SR":
-		return "sa"
-
-	case "ZA", "NG", "EG", "KE", "ET", "GH", "TZ", "UG", "DZ", "MA", "TN", "LY",
-		 "SD", "SS", "CM", "CI", "SN", "BF", "ML", "NE", "TD", "MR", "DJ", "SO",
-		 "ER", "RW", "BI", "MW", "ZM", "ZW", "BW", "NA", "LS", "SZ", "MG", "MU",
-		 "SC", "KM", "AO", "MZ", "CD", "CF", "CG", "GA", "GQ", "ST", "CV", "GW",
-		 "GN", "SL", "LR", "TG", "BJ":
-		return "africa"
+	// 🌎 Ülke → bölge: override, kıta varsayılanı, kayıt defteri varsayılanı
+	return reg.RegionForCountry(record.Country, record.Continent).ID
+}
 
-	default:
-		return "eu"
+// 🏳️ IP adresinden ISO ülke kodu (bilinmiyorsa "")
+func CountryFromIP(p Provider, ip string) string {
+	if p == nil {
+		return ""
+	}
+	addr, ok := ParseAddr(ip)
+	if !ok || !IsPublic(addr) {
+		return ""
+	}
+	record, err := p.Lookup(net.IP(addr.AsSlice()))
+	if err != nil {
+		return ""
 	}
+	return record.Country
 }
 
-// 🌐 Bölgeye göre replika adresi
-func ReplicaForRegion(region string) string {
-	switch region {
-	case "us":
-		return "postgres-replica1:5432"
-	case "eu":
-		return "postgres-replica2:5432"
-	case "asia":
-		return "postgres-replica3:5432"
-	case "sa":
-		return "postgres-replica4:5432"
-	case "africa":
-		return "postgres-replica5:5432"
-	default:
-		return "postgres-master:5432"
+// 📍 IP adresinden yaklaşık koordinat (City veritabanı veya koordinatlı CSV ile)
+func PointFromIP(p Provider, ip string) (geo.Point, bool) {
+	if p == nil {
+		return geo.Point{}, false
+	}
+	addr, ok := ParseAddr(ip)
+	if !ok || !IsPublic(addr) {
+		return geo.Point{}, false
 	}
+	// Country veritabanında konum yoktur
+	record, err := p.Lookup(net.IP(addr.AsSlice()))
+	if err != nil || !record.HasPoint {
+		return geo.Point{}, false
+	}
+	return record.Point, true
+}
+
+// 🌐 Bölgeye göre replika adresi (bölge kayıt defterinden)
+func ReplicaForRegion(reg *region.Registry, name string) string {
+	return reg.NodeFor(name).Addr()
 }
@@ -0,0 +1,42 @@
+package geoip
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"geo-repl-demo/internal/auth"
+)
+
+// Handler exposes GeoIP cache and database statistics.
+type Handler struct {
+	cache *Cache
+	src   *Reloading
+}
+
+func NewHandler(cache *Cache, src *Reloading) *Handler {
+	return &Handler{cache: cache, src: src}
+}
+
+// RegisterRoutes mounts the GeoIP routes on a Gin router (admin only).
+func RegisterRoutes(r *gin.Engine, h *Handler) {
+	api := r.Group("/api")
+	{
+		api.GET("/geoip/stats", auth.Require(auth.PermAdmin), h.stats)
+	}
+}
+
+func (h *Handler) stats(c *gin.Context) {
+	resp := gin.H{
+		"cache":      h.cache.Stats(),
+		"path":       h.src.Path(),
+		"generation": h.src.Generation(),
+		"loaded":     false,
+	}
+	if at := h.src.LoadedAt(); !at.IsZero() {
+		resp["loaded"] = true
+		resp["loaded_at"] = at.Format(time.RFC3339)
+	}
+	c.JSON(http.StatusOK, resp)
+}
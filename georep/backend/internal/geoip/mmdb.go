@@ -0,0 +1,62 @@
+package geoip
+
+import (
+	"net"
+	"strings"
+
+	"github.com/oschwald/geoip2-golang"
+
+	"geo-repl-demo/internal/geo"
+)
+
+// MMDB is a provider backed by a MaxMind GeoLite2/GeoIP2 Country or City
+// database. City databases additionally yield coordinates.
+type MMDB struct {
+	reader *geoip2.Reader
+	city   bool
+}
+
+// OpenMMDB opens a MaxMind database file.
+func OpenMMDB(path string) (*MMDB, error) {
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	city := strings.Contains(reader.Metadata().DatabaseType, "City")
+	return &MMDB{reader: reader, city: city}, nil
+}
+
+// Lookup implements Provider.
+func (m *MMDB) Lookup(ip net.IP) (Record, error) {
+	if m.city {
+		c, err := m.reader.City(ip)
+		if err != nil {
+			return Record{}, err
+		}
+		rec := Record{Country: strings.ToUpper(c.Country.IsoCode), Continent: c.Continent.Code}
+		if c.Location.Latitude != 0 || c.Location.Longitude != 0 {
+			rec.Point = geo.Point{Lat: c.Location.Latitude, Lon: c.Location.Longitude}
+			rec.HasPoint = true
+		}
+		return rec, notFoundIfEmpty(rec)
+	}
+
+	c, err := m.reader.Country(ip)
+	if err != nil {
+		return Record{}, err
+	}
+	rec := Record{Country: strings.ToUpper(c.Country.IsoCode), Continent: c.Continent.Code}
+	return rec, notFoundIfEmpty(rec)
+}
+
+// Close releases the memory-mapped database.
+func (m *MMDB) Close() error {
+	return m.reader.Close()
+}
+
+func notFoundIfEmpty(rec Record) error {
+	if rec.Country == "" && !rec.HasPoint {
+		return ErrNotFound
+	}
+	return nil
+}
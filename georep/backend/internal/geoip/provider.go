@@ -0,0 +1,73 @@
+package geoip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"geo-repl-demo/internal/geo"
+)
+
+// ErrNotFound is returned when a provider has no data for an address.
+var ErrNotFound = errors.New("geoip: address not found")
+
+// ErrNotLoaded is returned while no database could be loaded yet.
+var ErrNotLoaded = errors.New("geoip: database not loaded")
+
+// Record is the result of a GeoIP lookup.
+type Record struct {
+	Country   string    `json:"country"`
+	Continent string    `json:"continent,omitempty"`
+	Point     geo.Point `json:"point"`
+	HasPoint  bool      `json:"has_point"`
+}
+
+// Provider resolves an IP address to a country (and optionally a location).
+type Provider interface {
+	Lookup(ip net.IP) (Record, error)
+}
+
+// Static is an in-memory provider keyed by IP address or CIDR, intended for
+// tests and local development.
+type Static struct {
+	table *CIDRTable
+}
+
+// NewStatic builds a static provider. Keys are single addresses or CIDRs;
+// the most specific match wins.
+func NewStatic(entries map[string]Record) (*Static, error) {
+	t := &CIDRTable{}
+	for key, rec := range entries {
+		prefix, err := parsePrefix(key)
+		if err != nil {
+			return nil, err
+		}
+		t.add(prefix, rec)
+	}
+	t.sort()
+	return &Static{table: t}, nil
+}
+
+// Lookup implements Provider.
+func (s *Static) Lookup(ip net.IP) (Record, error) {
+	return s.table.Lookup(ip)
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid cidr %q: %w", s, err)
+		}
+		return p.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid ip %q: %w", s, err)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
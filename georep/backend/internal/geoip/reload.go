@@ -0,0 +1,132 @@
+package geoip
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OpenFunc opens a provider from a database file.
+type OpenFunc func(path string) (Provider, error)
+
+// Reloading serves lookups from a database file and atomically swaps in a
+// fresh provider whenever the file changes on disk. Lookups that already
+// hold the previous provider finish against it; it is closed after a grace
+// period.
+type Reloading struct {
+	path string
+	open OpenFunc
+
+	cur        atomic.Pointer[loaded]
+	generation atomic.Uint64
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+}
+
+type loaded struct {
+	p        Provider
+	loadedAt time.Time
+}
+
+// NewReloading loads path with open. A missing or broken file is not fatal:
+// lookups return ErrNotLoaded until a later reload succeeds.
+func NewReloading(path string, open OpenFunc) (*Reloading, error) {
+	r := &Reloading{path: path, open: open}
+	return r, r.Reload()
+}
+
+// Lookup implements Provider.
+func (r *Reloading) Lookup(ip net.IP) (Record, error) {
+	cur := r.cur.Load()
+	if cur == nil {
+		return Record{}, ErrNotLoaded
+	}
+	return cur.p.Lookup(ip)
+}
+
+// Generation increases every time a new database is swapped in.
+func (r *Reloading) Generation() uint64 {
+	return r.generation.Load()
+}
+
+// LoadedAt returns when the active database was loaded (zero if none).
+func (r *Reloading) LoadedAt() time.Time {
+	if cur := r.cur.Load(); cur != nil {
+		return cur.loadedAt
+	}
+	return time.Time{}
+}
+
+// Path returns the watched database file.
+func (r *Reloading) Path() string {
+	return r.path
+}
+
+// Reload opens the database file and swaps it in.
+func (r *Reloading) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+	p, err := r.open(r.path)
+	if err != nil {
+		return err
+	}
+	r.modTime, r.size = st.ModTime(), st.Size()
+
+	prev := r.cur.Swap(&loaded{p: p, loadedAt: time.Now()})
+	r.generation.Add(1)
+
+	if prev != nil {
+		if c, ok := prev.p.(io.Closer); ok {
+			time.AfterFunc(30*time.Second, func() { _ = c.Close() })
+		}
+	}
+	return nil
+}
+
+// Watch polls the database file and reloads it when its size or
+// modification time changes.
+func (r *Reloading) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		st, err := os.Stat(r.path)
+		if err != nil {
+			continue
+		}
+		r.mu.Lock()
+		changed := !st.ModTime().Equal(r.modTime) || st.Size() != r.size
+		r.mu.Unlock()
+		if !changed {
+			continue
+		}
+
+		if err := r.Reload(); err != nil {
+			log.Printf("⚠️ GeoIP veritabanı yeniden yüklenemedi: %v", err)
+			// Aynı bozuk dosyayı her turda tekrar denemeyelim
+			r.mu.Lock()
+			r.modTime, r.size = st.ModTime(), st.Size()
+			r.mu.Unlock()
+			continue
+		}
+		log.Printf("🌍 GeoIP veritabanı yeniden yüklendi: %s", r.path)
+	}
+}
@@ -0,0 +1,119 @@
+// Package locale normalizes language tags such as "tr", "en" or "pt-BR"
+// and picks the best available translation for a reader.
+package locale
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Normalize returns the canonical form of a language tag: a 2-3 letter
+// language, an optional 4 letter script and an optional 2 letter or 3
+// digit region, e.g. "EN_us" → "en-US", "zh-hant-tw" → "zh-Hant-TW".
+// Other subtags are not supported.
+func Normalize(tag string) (string, bool) {
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"), "-")
+	if len(parts) > 3 || !alpha(parts[0], 2, 3) {
+		return "", false
+	}
+	out := []string{strings.ToLower(parts[0])}
+	rest := parts[1:]
+	if len(rest) > 0 && alpha(rest[0], 4, 4) {
+		s := strings.ToLower(rest[0])
+		out = append(out, strings.ToUpper(s[:1])+s[1:])
+		rest = rest[1:]
+	}
+	if len(rest) > 0 && (alpha(rest[0], 2, 2) || digits(rest[0], 3)) {
+		out = append(out, strings.ToUpper(rest[0]))
+		rest = rest[1:]
+	}
+	if len(rest) > 0 {
+		return "", false
+	}
+	return strings.Join(out, "-"), true
+}
+
+// Language returns the language subtag of a normalized tag.
+func Language(tag string) string {
+	lang, _, _ := strings.Cut(tag, "-")
+	return lang
+}
+
+// ParseAcceptLanguage returns the tags of an Accept-Language header in
+// order of preference. Wildcards, invalid tags and q=0 entries are
+// dropped; equal weights keep their header order.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var ws []weighted
+	for _, field := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(field, ";")
+		norm, ok := Normalize(tag)
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if v, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		if q > 0 {
+			ws = append(ws, weighted{norm, q})
+		}
+	}
+	sort.SliceStable(ws, func(i, j int) bool { return ws[i].q > ws[j].q })
+	out := make([]string, 0, len(ws))
+	for _, w := range ws {
+		out = append(out, w.tag)
+	}
+	return out
+}
+
+// Negotiate returns the available tag that best serves prefs, tried in
+// order. A preference matches its exact tag first, then any available tag
+// of the same language ("en-US" → "en", "en" → "en-GB").
+func Negotiate(prefs, available []string) (string, bool) {
+	for _, p := range prefs {
+		for _, a := range available {
+			if a == p {
+				return a, true
+			}
+		}
+		for _, a := range available {
+			if Language(a) == Language(p) {
+				return a, true
+			}
+		}
+	}
+	return "", false
+}
+
+func alpha(s string, min, max int) bool {
+	if len(s) < min || len(s) > max {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func digits(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -5,6 +5,8 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+
+	"geo-repl-demo/internal/geo"
 )
 
 // Handler exposes HTTP endpoints for locations.
@@ -68,49 +70,34 @@ func (h *Handler) listReplica(c *gin.Context) {
 }
 
 // listClosest returns locations from the node that would be considered
-// "closest" to the user, based on a simple region hint.
-// Regions:
-//   - eu   -> master (single primary in EU)
-//   - us   -> replica1 (US replica)
-//   - apac -> replica2 (APAC replica)
+// "closest" to the user, based on the routed region. Region IDs, aliases and
+// the node assigned to each region come from the region registry; without
+// a hint the default region is used.
 func (h *Handler) listClosest(c *gin.Context) {
-	region := c.Query("region")
-	if region == "" {
-		region = "eu"
-	}
+	// The region comes from RegionMiddleware only, so ?region= is subject
+	// to the same dev-mode/admin policy as every other route.
+	name := c.GetString("region")
 
-	var (
-		locs   []Location
-		err    error
-		source string
-	)
-
-	switch region {
-	case "eu":
-		// EU users talk directly to the master (same region).
-		locs, err = h.svc.ListFromMaster(c.Request.Context())
-		source = "master"
-	case "us":
-		// US users read from the US replica (replica #1 in this demo).
-		locs, err = h.svc.ListFromReplica(c.Request.Context(), 0)
-		source = "replica1"
-	case "apac":
-		// APAC users read from the APAC replica (replica #2 in this demo).
-		locs, err = h.svc.ListFromReplica(c.Request.Context(), 1)
-		source = "replica2"
-	default:
+	reg, ok := h.svc.LookupRegion(name)
+	if !ok && name != "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown region"})
 		return
 	}
 
+	locs, source, err := h.svc.ListForRegion(c.Request.Context(), reg.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	// With explicit coordinates the stories are ordered nearest first.
+	if p, err := geo.ParsePoint(c.Query("lat"), c.Query("lon")); err == nil {
+		SortByDistance(locs, p)
+	}
+
 	// For the frontend we only return the list of stories here; which physical
-	// node served the request is explained in the UI copy based on region.
-	_ = source
+	// node served the request is exposed as a header for debugging.
+	c.Header("X-Served-By", source)
 	c.JSON(http.StatusOK, locs)
 }
 
@@ -1,14 +1,37 @@
 package location
 
-import "time"
+import (
+	"sort"
+	"time"
+
+	"geo-repl-demo/internal/geo"
+)
 
 // Location represents a stored geographical point.
 type Location struct {
-	ID        int64     `json:"id"`
-	City      string    `json:"city"`
-	Lat       float64   `json:"lat"`
-	Lon       float64   `json:"lon"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         int64     `json:"id"`
+	City       string    `json:"city"`
+	Lat        float64   `json:"lat"`
+	Lon        float64   `json:"lon"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	DistanceKm *float64  `json:"distance_km,omitempty"`
+}
+
+// Point returns the location coordinates.
+func (l Location) Point() geo.Point {
+	return geo.Point{Lat: l.Lat, Lon: l.Lon}
+}
+
+// SortByDistance annotates locations with their great-circle distance
+// from p and orders them nearest first.
+func SortByDistance(locs []Location, p geo.Point) {
+	for i := range locs {
+		d := geo.DistanceKm(p, locs[i].Point())
+		locs[i].DistanceKm = &d
+	}
+	sort.SliceStable(locs, func(i, j int) bool {
+		return *locs[i].DistanceKm < *locs[j].DistanceKm
+	})
 }
 
 // CreateLocationInput is the payload for creating a location.
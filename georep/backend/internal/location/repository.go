@@ -7,18 +7,21 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"geo-repl-demo/internal/db"
+	"geo-repl-demo/internal/region"
 )
 
 // Repository coordinates reads and writes across master and replicas.
 type Repository struct {
 	master   *db.Master
 	replicas *db.ReplicaSet
+	regions  *region.Registry
 }
 
-func NewRepository(master *db.Master, replicas *db.ReplicaSet) *Repository {
+func NewRepository(master *db.Master, replicas *db.ReplicaSet, regions *region.Registry) *Repository {
 	return &Repository{
 		master:   master,
 		replicas: replicas,
+		regions:  regions,
 	}
 }
 
@@ -37,13 +40,18 @@ func (r *Repository) InsertToMaster(ctx context.Context, in CreateLocationInput)
 	return loc, nil
 }
 
-// CopyToReplica inserts a location row into a specific replica.
+// CopyToReplica inserts a location row into the named replica.
 // This is called asynchronously to simulate eventual consistency.
-func (r *Repository) CopyToReplica(ctx context.Context, replicaIndex int, loc Location) error {
-	if replicaIndex < 0 || replicaIndex >= len(r.replicas.Pools) {
-		return fmt.Errorf("invalid replica index %d", replicaIndex)
+func (r *Repository) CopyToReplica(ctx context.Context, replica string, loc Location) error {
+	var pool *pgxpool.Pool
+	for _, rep := range r.replicas.All() {
+		if rep.Node.Name == replica {
+			pool = rep.Pool
+		}
+	}
+	if pool == nil {
+		return fmt.Errorf("unknown replica %q", replica)
 	}
-	pool := r.replicas.Pools[replicaIndex]
 
 	_, err := pool.Exec(ctx,
 		`INSERT INTO locations (id, city, lat, lon, updated_at)
@@ -56,7 +64,7 @@ func (r *Repository) CopyToReplica(ctx context.Context, replicaIndex int, loc Lo
 		loc.ID, loc.City, loc.Lat, loc.Lon, loc.UpdatedAt,
 	)
 	if err != nil {
-		return fmt.Errorf("copy to replica %d: %w", replicaIndex+1, err)
+		return fmt.Errorf("copy to %s: %w", replica, err)
 	}
 	return nil
 }
@@ -66,12 +74,27 @@ func (r *Repository) ListFromMaster(ctx context.Context) ([]Location, error) {
 	return queryLocations(ctx, r.master.Pool)
 }
 
-// ListFromReplica returns all locations from a given replica.
+// ListFromReplica returns all locations from a given replica
+// (position in the current topology).
 func (r *Repository) ListFromReplica(ctx context.Context, replicaIndex int) ([]Location, error) {
-	if replicaIndex < 0 || replicaIndex >= len(r.replicas.Pools) {
+	replicas := r.replicas.All()
+	if replicaIndex < 0 || replicaIndex >= len(replicas) {
 		return nil, fmt.Errorf("invalid replica index %d", replicaIndex)
 	}
-	return queryLocations(ctx, r.replicas.Pools[replicaIndex])
+	return queryLocations(ctx, replicas[replicaIndex].Pool)
+}
+
+// ListForRegion returns all locations from the node serving the region,
+// together with the name of that node. Replicas that are not ready yet or
+// failed their last health check are skipped in favour of the master.
+func (r *Repository) ListForRegion(ctx context.Context, regionID string) ([]Location, string, error) {
+	node := r.regions.NodeFor(regionID)
+	if rep, ok := r.replicas.Get(node.Name); ok && rep.Healthy() {
+		locs, err := queryLocations(ctx, rep.Pool)
+		return locs, node.Name, err
+	}
+	locs, err := r.ListFromMaster(ctx)
+	return locs, r.master.Node.Name, err
 }
 
 // queryLocations is a helper shared by master/replica queries.
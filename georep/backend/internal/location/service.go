@@ -4,6 +4,8 @@ import (
 	"context"
 	"math/rand"
 	"time"
+
+	"geo-repl-demo/internal/region"
 )
 
 // Service implements business logic around locations and replication.
@@ -24,13 +26,13 @@ func (s *Service) CreateLocation(ctx context.Context, in CreateLocationInput) (L
 	}
 
 	// Fire-and-forget goroutines simulating asynchronous replication.
-	for i := range s.repo.replicas.Pools {
-		i := i
+	for _, rep := range s.repo.replicas.All() {
+		name := rep.Node.Name
 		go func() {
 			// Random delay between 0.5s and 3s to simulate replication lag.
 			delay := 500*time.Millisecond + time.Duration(rand.Intn(2500))*time.Millisecond
 			time.Sleep(delay)
-			_ = s.repo.CopyToReplica(context.Background(), i, loc)
+			_ = s.repo.CopyToReplica(context.Background(), name, loc)
 		}()
 	}
 
@@ -41,6 +43,16 @@ func (s *Service) ListFromMaster(ctx context.Context) ([]Location, error) {
 	return s.repo.ListFromMaster(ctx)
 }
 
+// LookupRegion resolves a region ID or alias via the region registry.
+func (s *Service) LookupRegion(name string) (region.Region, bool) {
+	return s.repo.regions.Lookup(name)
+}
+
+// ListForRegion reads from the node assigned to the region.
+func (s *Service) ListForRegion(ctx context.Context, regionID string) ([]Location, string, error) {
+	return s.repo.ListForRegion(ctx, regionID)
+}
+
 func (s *Service) ListFromReplica(ctx context.Context, replicaIndex int) ([]Location, error) {
 	return s.repo.ListFromReplica(ctx, replicaIndex)
 }
@@ -0,0 +1,360 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	rawTag   = regexp.MustCompile(`^</?[A-Za-z][A-Za-z0-9-]*(?:\s+[A-Za-z_:][A-Za-z0-9_.:-]*(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'=<>` + "`" + `]+))?)*\s*/?>`)
+	autolink = regexp.MustCompile(`^<((?:https?|mailto):[^\s<>]+)>`)
+	entity   = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+)
+
+// inline renders the inline content of a paragraph or heading.
+func inline(s string) string {
+	var b strings.Builder
+	inlineTo(&b, s, true)
+	return b.String()
+}
+
+// unmatched remembers delimiters that have no closer in the rest of the
+// text; a later opener of the same kind cannot have one either. Without it
+// runs of unmatched delimiters would take quadratic time.
+type unmatched struct {
+	emphasis map[string]bool
+	code     map[int]bool
+	comment  bool
+}
+
+// inlineTo writes s as inline HTML. Links are not allowed inside links.
+func inlineTo(b *strings.Builder, s string, links bool) {
+	seen := unmatched{emphasis: map[string]bool{}, code: map[int]bool{}}
+	closers := brackets(s)
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			b.WriteString("<br>\n")
+			i += 2
+			continue
+
+		case c == '\\' && i+1 < len(s) && isPunct(s[i+1]):
+			b.WriteString(escape(s[i+1 : i+2]))
+			i += 2
+			continue
+
+		case c == ' ':
+			// Trailing spaces are dropped; two or more make a hard line break.
+			n := run(s[i:], ' ')
+			if i+n < len(s) && s[i+n] == '\n' {
+				if n >= 2 {
+					b.WriteString("<br>")
+				}
+				i += n
+				continue
+			}
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+
+		case c == '`':
+			n := run(s[i:], '`')
+			if !seen.code[n] {
+				if k := codeSpan(b, s[i:]); k > 0 {
+					i += k
+					continue
+				}
+				seen.code[n] = true
+			}
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+
+		case c == '!' && i+1 < len(s) && s[i+1] == '[':
+			if text, dest, title, n := linkAt(s[i+1:], closers[i+1]-i-1); n > 0 {
+				b.WriteString(`<img src="` + escape(dest) + `" alt="` + escape(plainText(inline(text))) + `"`)
+				if title != "" {
+					b.WriteString(` title="` + escape(title) + `"`)
+				}
+				b.WriteString(">")
+				i += 1 + n
+				continue
+			}
+
+		case c == '[' && links:
+			if text, dest, title, n := linkAt(s[i:], closers[i]-i); n > 0 {
+				b.WriteString(`<a href="` + escape(dest) + `"`)
+				if title != "" {
+					b.WriteString(` title="` + escape(title) + `"`)
+				}
+				b.WriteString(">")
+				inlineTo(b, text, false)
+				b.WriteString("</a>")
+				i += n
+				continue
+			}
+
+		case c == '<':
+			if m := autolink.FindStringSubmatch(s[i:]); m != nil && links {
+				b.WriteString(`<a href="` + escape(m[1]) + `">` + escape(m[1]) + "</a>")
+				i += len(m[0])
+				continue
+			}
+			if strings.HasPrefix(s[i:], "<!--") && !seen.comment {
+				if k := strings.Index(s[i+4:], "-->"); k >= 0 {
+					i += 4 + k + 3
+					continue
+				}
+				seen.comment = true
+			}
+			// Inline HTML is left to the sanitizer.
+			if m := rawTag.FindString(s[i:]); m != "" {
+				b.WriteString(m)
+				i += len(m)
+				continue
+			}
+
+		case c == '&':
+			if m := entity.FindString(s[i:]); m != "" {
+				b.WriteString(m)
+				i += len(m)
+				continue
+			}
+
+		case c == '*' || c == '_' || c == '~':
+			n := run(s[i:], c)
+			if delim := s[i : i+n]; !seen.emphasis[delim] {
+				k, closed := emphasis(b, s, i, links)
+				if k > 0 {
+					i += k
+					continue
+				}
+				seen.emphasis[delim] = !closed
+			}
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+		}
+
+		_, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(escape(s[i : i+size]))
+		i += size
+	}
+}
+
+// codeSpan writes the code span at the start of s and returns its length,
+// or 0 if the backtick run is not closed.
+func codeSpan(b *strings.Builder, s string) int {
+	n := run(s, '`')
+	for j := n; j < len(s); {
+		k := strings.IndexByte(s[j:], '`')
+		if k < 0 {
+			return 0
+		}
+		j += k
+		m := run(s[j:], '`')
+		if m == n {
+			code := strings.ReplaceAll(s[n:j], "\n", " ")
+			if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+				code = code[1 : len(code)-1]
+			}
+			b.WriteString("<code>" + escape(code) + "</code>")
+			return j + m
+		}
+		j += m
+	}
+	return 0
+}
+
+// brackets maps the position of each '[' in s to the position of its
+// matching ']'. Escaped brackets do not count.
+func brackets(s string) map[int]int {
+	closers := map[int]int{}
+	var stack []int
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			stack = append(stack, i)
+		case ']':
+			if len(stack) > 0 {
+				closers[stack[len(stack)-1]] = i
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+	return closers
+}
+
+// linkAt parses `[text](dest "title")` at the start of s, whose text ends
+// at s[end], and returns its parts and length, or n == 0.
+func linkAt(s string, end int) (text, dest, title string, n int) {
+	if end <= 0 || end+1 >= len(s) || s[end+1] != '(' {
+		return "", "", "", 0
+	}
+	text = s[1:end]
+
+	rest := s[end+2:]
+	i := skipSpace(rest, 0)
+	if i < len(rest) && rest[i] == '<' {
+		k := strings.IndexAny(rest[i+1:], "<>\n") + 1
+		if k == 0 || rest[i+k] != '>' {
+			return "", "", "", 0
+		}
+		dest = rest[i+1 : i+k]
+		i += k + 1
+	} else {
+		start, parens := i, 0
+		for ; i < len(rest); i++ {
+			c := rest[i]
+			if c == '\\' && i+1 < len(rest) {
+				i++
+				continue
+			}
+			if c == '(' {
+				// Deeper nesting is not a destination (as in CommonMark).
+				if parens++; parens > 32 {
+					return "", "", "", 0
+				}
+			} else if c == ')' {
+				if parens == 0 {
+					break
+				}
+				parens--
+			} else if c <= ' ' {
+				break
+			}
+		}
+		dest = rest[start:i]
+	}
+	i = skipSpace(rest, i)
+	if i < len(rest) && (rest[i] == '"' || rest[i] == '\'') {
+		q := rest[i]
+		k := strings.IndexByte(rest[i+1:], q)
+		if k < 0 {
+			return "", "", "", 0
+		}
+		title = rest[i+1 : i+1+k]
+		i = skipSpace(rest, i+k+2)
+	}
+	if i >= len(rest) || rest[i] != ')' {
+		return "", "", "", 0
+	}
+	return text, unescapePunct(dest), unescapePunct(title), end + 2 + i + 1
+}
+
+// emphasis writes the emphasis, strong emphasis or strikethrough opening
+// at s[i] and returns the number of bytes consumed, or 0. closed is false
+// when the search for a closer reached the end of s.
+func emphasis(b *strings.Builder, s string, i int, links bool) (n int, closed bool) {
+	c := s[i]
+	n = run(s[i:], c)
+	if (c == '~' && n != 2) || n > 3 {
+		return 0, true
+	}
+	before, after := runeBefore(s, i), runeAfter(s, i+n)
+	if unicode.IsSpace(after) || after == utf8.RuneError {
+		return 0, true
+	}
+	// An underscore does not open emphasis inside a word.
+	if c == '_' && isWord(before) {
+		return 0, true
+	}
+
+	// The closer is the next run of the same length that follows text.
+	for j := i + n; j < len(s); {
+		k := strings.IndexByte(s[j:], c)
+		if k < 0 {
+			return 0, false
+		}
+		j += k
+		if s[j-1] == '\\' {
+			j++
+			continue
+		}
+		m := run(s[j:], c)
+		if m == n && !unicode.IsSpace(runeBefore(s, j)) && !(c == '_' && isWord(runeAfter(s, j+m))) {
+			open, close := "", ""
+			switch {
+			case c == '~':
+				open, close = "<del>", "</del>"
+			case n == 1:
+				open, close = "<em>", "</em>"
+			case n == 2:
+				open, close = "<strong>", "</strong>"
+			default:
+				open, close = "<em><strong>", "</strong></em>"
+			}
+			b.WriteString(open)
+			inlineTo(b, s[i+n:j], links)
+			b.WriteString(close)
+			return j + m - i, true
+		}
+		j += m
+	}
+	return 0, false
+}
+
+func run(s string, c byte) int {
+	n := 0
+	for n < len(s) && s[n] == c {
+		n++
+	}
+	return n
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+func runeBefore(s string, i int) rune {
+	if i == 0 {
+		return ' '
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return r
+}
+
+func runeAfter(s string, i int) rune {
+	if i >= len(s) {
+		return utf8.RuneError
+	}
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return r
+}
+
+func isWord(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isPunct(c byte) bool {
+	return c < 0x80 && unicode.IsPunct(rune(c)) || strings.IndexByte("$+<=>^`|~", c) >= 0
+}
+
+func unescapePunct(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// escape escapes text for use in HTML content and quoted attributes.
+func escape(s string) string {
+	return htmlEscaper.Replace(s)
+}
+
+var htmlEscaper = strings.NewReplacer(`&`, "&amp;", `<`, "&lt;", `>`, "&gt;", `"`, "&quot;", `'`, "&#39;")
@@ -0,0 +1,323 @@
+// Package markdown renders article bodies written in Markdown to sanitized
+// HTML. It supports the common subset of CommonMark used by authors:
+// headings, paragraphs, emphasis, code, links, images, lists, block quotes
+// and rules. Inline HTML is passed through to the sanitizer, which keeps an
+// allow-list of tags and attributes.
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Heading is an entry of the table of contents. ID is the anchor of the
+// heading in the rendered HTML.
+type Heading struct {
+	Level int
+	ID    string
+	Text  string
+}
+
+// Document is the result of rendering a Markdown source.
+type Document struct {
+	// HTML is sanitized and safe to embed in a page.
+	HTML string
+	// TOC lists the headings in document order.
+	TOC []Heading
+	// Text is the prose of the document as plain text, without headings
+	// and code blocks; see Excerpt.
+	Text string
+}
+
+// Render converts src to sanitized HTML and collects its headings.
+func Render(src string) Document {
+	r := &renderer{ids: map[string]int{}}
+	var b strings.Builder
+	r.blocks(&b, splitLines(src), false, true)
+	html := Sanitize(b.String())
+	return Document{HTML: html, TOC: r.toc, Text: prose(html)}
+}
+
+// renderer carries state shared by nested blocks: heading anchors must be
+// unique across the whole document.
+type renderer struct {
+	toc []Heading
+	ids map[string]int
+}
+
+var (
+	atxHeading = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	rule       = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fenceOpen  = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*([^`\\s]*)")
+	setextLine = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	listItem   = regexp.MustCompile(`^( {0,3})([-*+]|\d{1,9}[.)])([ \t]+|$)`)
+	quoteLine  = regexp.MustCompile(`^ {0,3}> ?`)
+	langName   = regexp.MustCompile(`^[A-Za-z0-9_+#-]{1,32}$`)
+)
+
+func splitLines(src string) []string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+	src = strings.ReplaceAll(src, "\t", "    ")
+	return strings.Split(src, "\n")
+}
+
+func blank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// blocks renders lines as block elements. Tight list items render their
+// paragraphs without <p>; headings inside quotes and lists do not enter the
+// table of contents.
+func (r *renderer) blocks(b *strings.Builder, lines []string, tight, toc bool) {
+	var para []string
+	flush := func() {
+		if len(para) == 0 {
+			return
+		}
+		text := inline(strings.Join(para, "\n"))
+		if tight {
+			b.WriteString(text + "\n")
+		} else {
+			b.WriteString("<p>" + text + "</p>\n")
+		}
+		para = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case blank(line):
+			flush()
+
+		case len(para) > 0 && setextLine.MatchString(line):
+			level := 2
+			if strings.TrimSpace(line)[0] == '=' {
+				level = 1
+			}
+			text := strings.Join(para, "\n")
+			para = nil
+			r.heading(b, level, text, toc)
+
+		case rule.MatchString(line):
+			flush()
+			b.WriteString("<hr>\n")
+
+		case atxHeading.MatchString(line):
+			flush()
+			m := atxHeading.FindStringSubmatch(line)
+			r.heading(b, len(m[1]), m[2], toc)
+
+		case fenceOpen.MatchString(line):
+			flush()
+			m := fenceOpen.FindStringSubmatch(line)
+			indent, fence := len(m[1]), m[2]
+			var code []string
+			for i++; i < len(lines); i++ {
+				t := strings.TrimSpace(lines[i])
+				if strings.HasPrefix(t, fence) && strings.Trim(t, fence[:1]) == "" {
+					break
+				}
+				code = append(code, trimIndent(lines[i], indent))
+			}
+			codeBlock(b, code, m[3])
+
+		case len(para) == 0 && strings.HasPrefix(line, "    "):
+			var code []string
+			for ; i < len(lines) && (strings.HasPrefix(lines[i], "    ") || blank(lines[i])); i++ {
+				code = append(code, trimIndent(lines[i], 4))
+			}
+			i--
+			for len(code) > 0 && blank(code[len(code)-1]) {
+				code = code[:len(code)-1]
+			}
+			codeBlock(b, code, "")
+
+		case quoteLine.MatchString(line):
+			flush()
+			var inner []string
+			for ; i < len(lines) && !blank(lines[i]); i++ {
+				inner = append(inner, quoteLine.ReplaceAllString(lines[i], ""))
+			}
+			i--
+			b.WriteString("<blockquote>\n")
+			r.blocks(b, inner, false, false)
+			b.WriteString("</blockquote>\n")
+
+		case listItem.MatchString(line) && (len(para) == 0 || startsList(line)):
+			flush()
+			i = r.list(b, lines, i) - 1
+
+		default:
+			para = append(para, line)
+		}
+	}
+	flush()
+}
+
+// startsList reports whether a list item may interrupt a paragraph: only
+// bullets and lists starting at 1, as in CommonMark.
+func startsList(line string) bool {
+	m := listItem.FindStringSubmatch(line)
+	if m[3] == "" {
+		return false
+	}
+	marker := m[2]
+	return strings.ContainsAny(marker[:1], "-*+") || marker[:len(marker)-1] == "1"
+}
+
+// list renders the list starting at lines[start] and returns the index of
+// the first line after it.
+func (r *renderer) list(b *strings.Builder, lines []string, start int) int {
+	first := listItem.FindStringSubmatch(lines[start])
+	ordered := !strings.ContainsAny(first[2][:1], "-*+")
+	delim := first[2][len(first[2])-1:]
+
+	var items [][]string
+	loose := false
+	i := start
+	for i < len(lines) {
+		m := listItem.FindStringSubmatch(lines[i])
+		if m == nil || (m[2][len(m[2])-1:] != delim) || (!ordered && m[2] != first[2]) {
+			break
+		}
+		width := len(m[0])
+		if m[3] == "" {
+			width++
+		}
+		item := []string{lines[i][len(m[0]):]}
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if blank(line) {
+				// After a blank line the list goes on only with an indented
+				// continuation or a new item.
+				if i+1 < len(lines) && (indentOf(lines[i+1]) >= width || sameList(lines[i+1], delim, ordered, first[2])) {
+					loose = true
+					item = append(item, "")
+					continue
+				}
+				break
+			}
+			if indentOf(line) >= width {
+				item = append(item, trimIndent(line, width))
+				continue
+			}
+			if listItem.MatchString(line) || item[len(item)-1] == "" {
+				break
+			}
+			// A lazy continuation line belongs to the paragraph.
+			item = append(item, strings.TrimLeft(line, " "))
+		}
+		for len(item) > 0 && blank(item[len(item)-1]) {
+			item = item[:len(item)-1]
+		}
+		items = append(items, item)
+		if i < len(lines) && blank(lines[i]) {
+			break
+		}
+	}
+
+	if ordered {
+		n, _ := strconv.Atoi(first[2][:len(first[2])-1])
+		if n != 1 {
+			b.WriteString(`<ol start="` + strconv.Itoa(n) + `">` + "\n")
+		} else {
+			b.WriteString("<ol>\n")
+		}
+	} else {
+		b.WriteString("<ul>\n")
+	}
+	for _, item := range items {
+		var li strings.Builder
+		r.blocks(&li, item, !loose, false)
+		b.WriteString("<li>" + strings.TrimSuffix(li.String(), "\n") + "</li>\n")
+	}
+	if ordered {
+		b.WriteString("</ol>\n")
+	} else {
+		b.WriteString("</ul>\n")
+	}
+	return i
+}
+
+func sameList(line, delim string, ordered bool, marker string) bool {
+	m := listItem.FindStringSubmatch(line)
+	if m == nil || m[2][len(m[2])-1:] != delim {
+		return false
+	}
+	return ordered || m[2] == marker
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func trimIndent(line string, n int) string {
+	if k := indentOf(line); k < n {
+		n = k
+	}
+	return line[n:]
+}
+
+func codeBlock(b *strings.Builder, code []string, lang string) {
+	if langName.MatchString(lang) {
+		b.WriteString(`<pre><code class="language-` + strings.ToLower(lang) + `">`)
+	} else {
+		b.WriteString("<pre><code>")
+	}
+	for _, line := range code {
+		b.WriteString(escape(line) + "\n")
+	}
+	b.WriteString("</code></pre>\n")
+}
+
+// heading writes a heading with a unique anchor derived from its text.
+func (r *renderer) heading(b *strings.Builder, level int, src string, toc bool) {
+	html := inline(strings.TrimSpace(src))
+	text := plainText(Sanitize(html))
+	id := r.anchor(text)
+	tag := "h" + strconv.Itoa(level)
+	b.WriteString("<" + tag + ` id="` + id + `">` + html + "</" + tag + ">\n")
+	if toc {
+		r.toc = append(r.toc, Heading{Level: level, ID: id, Text: text})
+	}
+}
+
+// anchor slugs text ("Hızlı Başlangıç" → "hızlı-başlangıç") and numbers
+// repeated slugs ("kurulum", "kurulum-1", ...).
+func (r *renderer) anchor(text string) string {
+	var s strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_':
+			if dash && s.Len() > 0 {
+				s.WriteByte('-')
+			}
+			dash = false
+			s.WriteRune(c)
+		case unicode.IsSpace(c) || c == '-':
+			dash = true
+		}
+	}
+	slug := s.String()
+	if slug == "" {
+		slug = "section"
+	}
+	n := r.ids[slug]
+	r.ids[slug] = n + 1
+	if n > 0 {
+		// A numbered slug may be taken by another heading's text.
+		for {
+			cand := slug + "-" + strconv.Itoa(n)
+			if r.ids[cand] == 0 {
+				r.ids[cand] = 1
+				return cand
+			}
+			n++
+		}
+	}
+	return slug
+}
@@ -0,0 +1,88 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderXSS(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"javascript link", `[x](javascript:alert(1))`},
+		{"javascript link case", `[x](JaVaScRiPt:alert(1))`},
+		{"javascript link entity", `[x](&#106;avascript:alert(1))`},
+		{"javascript link tab entity", `[x](java&#x09;script:alert(1))`},
+		{"javascript link escaped colon", `[x](javascript\:alert(1))`},
+		{"javascript link angle", `[x](<javascript:alert(1)>)`},
+		{"javascript link leading space", `[x](   javascript:alert(1))`},
+		{"javascript image", `![x](javascript:alert(1))`},
+		{"javascript autolink", `<javascript:alert(1)>`},
+		{"data image", `![x](data:image/svg+xml;base64,PHN2Zz4=)`},
+		{"link title quote", `[x](https://example.com "a\" onmouseover=\"alert(1)")`},
+		{"link title single quote", `[x](https://example.com 'a" onmouseover="alert(1)')`},
+		{"image alt quote", `![a" onerror="alert(1)](x.png)`},
+		{"image title quote", `![a](x.png "\" onerror=\"alert(1)")`},
+		{"heading html", `# <img src=x onerror=alert(1)>`},
+		{"heading quote", `# "><script>alert(1)</script>`},
+		{"heading attribute", `## x" onclick="alert(1)`},
+		{"setext heading", "<svg onload=alert(1)>\n===="},
+		{"svg", `<svg><script>alert(1)</script></svg>`},
+		{"math", `<math><mtext><img src=x onerror=alert(1)></mtext></math>`},
+		{"unclosed tag", `text <a href="https://example.com" title="x`},
+		{"unclosed script", "para\n\n<script>alert(1)"},
+		{"code fence lang", "```go\" onclick=\"alert(1)\nx\n```"},
+		{"code span", "`<script>alert(1)</script>`"},
+		{"list item", `- <img src=x onerror=alert(1)>`},
+		{"quote", `> <a href="javascript:alert(1)">x</a>`},
+		{"link in link text", `[<a href="javascript:alert(1)">x</a>](https://example.com)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := Render(tt.src)
+			assertSafe(t, tt.src, doc.HTML)
+			for _, h := range doc.TOC {
+				if !anchorID.MatchString(h.ID) {
+					t.Errorf("%q: heading ID %q", tt.src, h.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderKeepsSafeMarkup(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{`[x](https://example.com "T")`,
+			`<p><a href="https://example.com" title="T" rel="nofollow noopener noreferrer">x</a></p>`},
+		{`[x](javascript:alert(1))`, `<p><a rel="nofollow noopener noreferrer">x</a></p>`},
+		{`[x](https://example.com 'a" b')`,
+			`<p><a href="https://example.com" title="a&#34; b" rel="nofollow noopener noreferrer">x</a></p>`},
+		{`![a"b](/x.png)`, `<p><img src="/x.png" alt="a&#34;b"></p>`},
+		{`# "><script>x</script>`, `<h1 id="section">&#34;&gt;</h1>`},
+		{"```go\" onclick=\"x\ny\n```", "<pre><code>y\n</code></pre>"},
+	}
+	for _, tt := range tests {
+		if got := strings.TrimSpace(Render(tt.src).HTML); got != tt.want {
+			t.Errorf("Render(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestHeadingIDs(t *testing.T) {
+	doc := Render("# Kurulum\n# Kurulum\n# Kurulum-1\n# <em>Hızlı</em> Başlangıç!\n# \"><b>\n# ---")
+	want := []string{"kurulum", "kurulum-1", "kurulum-1-1", "hızlı-başlangıç", "section", "section-1"}
+	if len(doc.TOC) != len(want) {
+		t.Fatalf("TOC = %+v", doc.TOC)
+	}
+	for i, h := range doc.TOC {
+		if h.ID != want[i] {
+			t.Errorf("heading %d: ID = %q, want %q", i, h.ID, want[i])
+		}
+		if !strings.Contains(doc.HTML, ` id="`+h.ID+`"`) {
+			t.Errorf("heading %d: id %q missing from %s", i, h.ID, doc.HTML)
+		}
+	}
+}
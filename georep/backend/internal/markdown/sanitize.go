@@ -0,0 +1,228 @@
+package markdown
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// allowed maps the tags kept by Sanitize to the attributes kept on them.
+// Everything else is dropped; the text inside unknown tags is kept.
+var allowed = map[string][]string{
+	"p": nil, "br": nil, "hr": nil,
+	"h1": {"id"}, "h2": {"id"}, "h3": {"id"}, "h4": {"id"}, "h5": {"id"}, "h6": {"id"},
+	"strong": nil, "b": nil, "em": nil, "i": nil, "del": nil, "s": nil,
+	"sub": nil, "sup": nil, "kbd": nil, "mark": nil,
+	"code": {"class"}, "pre": nil, "blockquote": nil,
+	"ul": nil, "ol": {"start"}, "li": nil,
+	"a":   {"href", "title"},
+	"img": {"src", "alt", "title"},
+}
+
+// dropped are tags whose contents are removed together with them.
+var dropped = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true,
+	"noscript": true, "noembed": true, "noframes": true, "template": true,
+	"textarea": true, "title": true, "xmp": true, "plaintext": true,
+	"svg": true, "math": true, "select": true, "head": true,
+}
+
+var void = map[string]bool{"br": true, "hr": true, "img": true}
+
+var (
+	anchorID  = regexp.MustCompile(`^[\p{L}\p{N}_-]{1,128}$`)
+	codeClass = regexp.MustCompile(`^language-[a-z0-9_+#-]{1,32}$`)
+	digits    = regexp.MustCompile(`^[0-9]{1,9}$`)
+)
+
+// Sanitize rewrites src keeping only allow-listed tags and attributes.
+// Links and images must use http(s) or a relative URL (links may also use
+// mailto); links get rel="nofollow noopener noreferrer". Unclosed tags are
+// closed and stray end tags are dropped, so the result is well-formed.
+func Sanitize(src string) string {
+	var b strings.Builder
+	var open []string
+	skip := 0
+
+	z := html.NewTokenizer(strings.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		tok := z.Token()
+		name := tok.Data
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if dropped[name] {
+				if tt == html.StartTagToken {
+					skip++
+				}
+				continue
+			}
+			if _, ok := allowed[name]; !ok || skip > 0 {
+				continue
+			}
+			b.WriteString("<" + name)
+			for _, a := range tok.Attr {
+				if v, ok := attribute(name, a); ok {
+					b.WriteString(" " + a.Key + `="` + html.EscapeString(v) + `"`)
+				}
+			}
+			if name == "a" {
+				b.WriteString(` rel="nofollow noopener noreferrer"`)
+			}
+			b.WriteString(">")
+			if !void[name] && tt == html.StartTagToken {
+				open = append(open, name)
+			}
+
+		case html.EndTagToken:
+			if dropped[name] {
+				if skip > 0 {
+					skip--
+				}
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			for k := len(open) - 1; k >= 0; k-- {
+				if open[k] == name {
+					for len(open) > k {
+						b.WriteString("</" + open[len(open)-1] + ">")
+						open = open[:len(open)-1]
+					}
+					break
+				}
+			}
+
+		case html.TextToken:
+			if skip == 0 {
+				b.WriteString(html.EscapeString(tok.Data))
+			}
+		}
+	}
+	for k := len(open) - 1; k >= 0; k-- {
+		b.WriteString("</" + open[k] + ">")
+	}
+	return b.String()
+}
+
+// attribute returns the sanitized value of an attribute of tag, or false
+// if it is not allowed.
+func attribute(tag string, a html.Attribute) (string, bool) {
+	if a.Namespace != "" {
+		return "", false
+	}
+	keep := false
+	for _, k := range allowed[tag] {
+		keep = keep || k == a.Key
+	}
+	if !keep {
+		return "", false
+	}
+	switch a.Key {
+	case "href":
+		return safeURL(a.Val, "http", "https", "mailto")
+	case "src":
+		return safeURL(a.Val, "http", "https")
+	case "id":
+		return a.Val, anchorID.MatchString(a.Val)
+	case "class":
+		return a.Val, codeClass.MatchString(a.Val)
+	case "start":
+		return a.Val, digits.MatchString(a.Val)
+	}
+	return a.Val, true
+}
+
+// safeURL accepts relative URLs and absolute ones with an allowed scheme.
+func safeURL(raw string, schemes ...string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	if u.Scheme == "" {
+		return raw, true
+	}
+	for _, s := range schemes {
+		if strings.EqualFold(u.Scheme, s) {
+			return raw, true
+		}
+	}
+	return "", false
+}
+
+// blocks are tags that separate words in plain text.
+var blocks = map[string]bool{
+	"p": true, "br": true, "hr": true, "li": true, "blockquote": true, "pre": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true,
+}
+
+// plainText returns the text of an HTML fragment with whitespace collapsed.
+func plainText(src string) string {
+	return text(src, nil)
+}
+
+// prose returns the text of rendered HTML without headings and code blocks.
+func prose(src string) string {
+	return text(src, map[string]bool{"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "pre": true})
+}
+
+func text(src string, skipTags map[string]bool) string {
+	var b strings.Builder
+	skip := 0
+	z := html.NewTokenizer(strings.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		tok := z.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			if skipTags[tok.Data] && tt != html.SelfClosingTagToken {
+				if tt == html.StartTagToken {
+					skip++
+				} else if skip > 0 {
+					skip--
+				}
+			}
+			if blocks[tok.Data] {
+				b.WriteByte(' ')
+			}
+		case html.TextToken:
+			if skip == 0 {
+				b.WriteString(tok.Data)
+			}
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// Excerpt shortens plain text to at most max runes, cutting at a word
+// boundary and appending "…" when something was cut. A max below 1 yields
+// "".
+func Excerpt(s string, max int) string {
+	if max < 1 {
+		return ""
+	}
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	cut := max - 1
+	for k := cut; k > max/2; k-- {
+		if r[k] == ' ' {
+			cut = k
+			break
+		}
+	}
+	return strings.TrimRight(string(r[:cut]), " ,;:.-") + "…"
+}
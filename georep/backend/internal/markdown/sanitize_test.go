@@ -0,0 +1,140 @@
+package markdown
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// assertSafe fails unless out only holds allow-listed tags and attributes
+// and every URL has a permitted scheme once entities are decoded.
+func assertSafe(t *testing.T, src, out string) {
+	t.Helper()
+	z := html.NewTokenizer(strings.NewReader(out))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		tok := z.Token()
+		keys, ok := allowed[tok.Data]
+		if !ok {
+			t.Errorf("%q: tag <%s> kept in %q", src, tok.Data, out)
+			continue
+		}
+		for _, a := range tok.Attr {
+			known := a.Key == "rel" && tok.Data == "a"
+			for _, k := range keys {
+				known = known || k == a.Key
+			}
+			if !known {
+				t.Errorf("%q: attribute %s on <%s> kept in %q", src, a.Key, tok.Data, out)
+			}
+			if a.Key != "href" && a.Key != "src" {
+				continue
+			}
+			u, err := url.Parse(a.Val)
+			if err != nil {
+				t.Errorf("%q: unparsable %s=%q kept", src, a.Key, a.Val)
+				continue
+			}
+			switch strings.ToLower(u.Scheme) {
+			case "", "http", "https", "mailto":
+			default:
+				t.Errorf("%q: %s=%q kept in %q", src, a.Key, a.Val, out)
+			}
+		}
+	}
+}
+
+func TestSanitizeXSS(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string // "" only checks that the result is safe
+	}{
+		{"script", `<script>alert(1)</script>ok`, "ok"},
+		{"script case", `<ScRiPt>alert(1)</sCrIpT>ok`, "ok"},
+		{"event handler", `<a href="https://example.com" onclick="alert(1)">x</a>`,
+			`<a href="https://example.com" rel="nofollow noopener noreferrer">x</a>`},
+		{"javascript href", `<a href="javascript:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"javascript mixed case", `<a href="JaVaScRiPt:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"javascript decimal entity", `<a href="&#106;avascript:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"javascript hex entity", `<a href="&#x6A;avascript:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"javascript entity colon", `<a href="javascript&colon;alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"javascript tab entity", `<a href="java&#x09;script:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"javascript newline", "<a href=\"java\nscript:alert(1)\">x</a>", `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"javascript leading space", `<a href="  javascript:alert(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"javascript control char", "<a href=\"\x01javascript:alert(1)\">x</a>", `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"javascript unquoted", `<a href=javascript:alert(1)>x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"vbscript", `<a href="vbscript:msgbox(1)">x</a>`, `<a rel="nofollow noopener noreferrer">x</a>`},
+		{"data img", `<img src="data:image/svg+xml;base64,PHN2Zz4=">`, `<img>`},
+		{"mailto img", `<img src="mailto:a@example.com">`, `<img>`},
+		{"img onerror", `<img src=x onerror=alert(1)>`, `<img src="x">`},
+		{"svg", `<svg onload=alert(1)><circle r="1"></circle></svg>ok`, "ok"},
+		{"svg script", `<svg><script>alert(1)</script></svg>ok`, "ok"},
+		{"svg slash", `<svg/onload=alert(1)></svg>ok`, "ok"},
+		{"math", `<math><mtext><img src=x onerror=alert(1)></mtext></math>ok`, "ok"},
+		{"math xlink", `<math><maction actiontype="statusline" xlink:href="javascript:alert(1)">x</maction></math>`, ""},
+		{"unclosed script", `ok<script>alert(1)`, "ok"},
+		{"unclosed style", `ok<style>body{background:url(javascript:alert(1))}`, "ok"},
+		{"unclosed tags", `<p><strong>bold`, "<p><strong>bold</strong></p>"},
+		{"unterminated tag", `ok<img src=x onerror=alert(1)`, ""},
+		{"stray end tag", `</p></div>ok`, "ok"},
+		{"iframe", `<iframe src="https://evil.example"></iframe>ok`, "ok"},
+		{"style attribute", `<p style="background:url(javascript:alert(1))">x</p>`, "<p>x</p>"},
+		{"title quote", `<a href="/x" title='" onmouseover="alert(1)'>x</a>`,
+			`<a href="/x" title="&#34; onmouseover=&#34;alert(1)" rel="nofollow noopener noreferrer">x</a>`},
+		{"heading id", `<h2 id="x&quot; onclick=&quot;alert(1)">x</h2>`, "<h2>x</h2>"},
+		{"code class", `<code class="language-go onclick">x</code>`, "<code>x</code>"},
+		{"namespaced attribute", `<a xlink:href="javascript:alert(1)" href="/ok">x</a>`,
+			`<a href="/ok" rel="nofollow noopener noreferrer">x</a>`},
+		{"comment", `<!--<script>alert(1)</script>-->ok`, "ok"},
+		{"text escaped", `a < b & "c"`, `a &lt; b &amp; &#34;c&#34;`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := Sanitize(tt.src)
+			assertSafe(t, tt.src, out)
+			if tt.want != "" && out != tt.want {
+				t.Errorf("Sanitize(%q) = %q, want %q", tt.src, out, tt.want)
+			}
+		})
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"", 10, ""},
+		{"short", 10, "short"},
+		{"exact", 5, "exact"},
+		{"hello world foo", 12, "hello world…"},
+		{"abcdefghij", 5, "abcd…"},
+		{"çok güzel bir gün", 10, "çok güzel…"},
+		{"çok güzel bir gün", 17, "çok güzel bir gün"},
+		{"one, two, three", 6, "one…"},
+		{"a b", 2, "a…"},
+		{"ab", 1, "…"},
+		{"ab", 0, ""},
+		{"ab", -1, ""},
+	}
+	for _, tt := range tests {
+		got := Excerpt(tt.in, tt.max)
+		if got != tt.want {
+			t.Errorf("Excerpt(%q, %d) = %q, want %q", tt.in, tt.max, got, tt.want)
+		}
+		if tt.max >= 0 && utf8.RuneCountInString(got) > tt.max {
+			t.Errorf("Excerpt(%q, %d) = %q is longer than %d runes", tt.in, tt.max, got, tt.max)
+		}
+	}
+}
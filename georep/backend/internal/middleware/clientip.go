@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"geo-repl-demo/internal/geoip"
+)
+
+// ProxyChain determines the real client address of a request. Forwarding
+// headers are only believed when they were added by a trusted proxy: the
+// chain is walked right to left starting at the TCP peer, and the first
+// address that is not a trusted proxy is the client.
+type ProxyChain struct {
+	trusted []netip.Prefix
+}
+
+// NewProxyChain returns a ProxyChain trusting the given proxy ranges. With
+// no ranges, forwarding headers are ignored entirely.
+func NewProxyChain(trusted []netip.Prefix) *ProxyChain {
+	return &ProxyChain{trusted: trusted}
+}
+
+// Trusted reports whether addr belongs to a trusted proxy.
+func (pc *ProxyChain) Trusted(addr netip.Addr) bool {
+	for _, p := range pc.trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the client address of r, or false if not even the TCP
+// peer address could be parsed.
+//
+// Forwarded (RFC 7239) takes precedence over X-Forwarded-For; X-Real-IP is
+// only consulted when neither is present. If a hop cannot be parsed
+// ("unknown", obfuscated identifiers, garbage), the walk stops and the last
+// address that could be verified is returned.
+func (pc *ProxyChain) ClientIP(r *http.Request) (netip.Addr, bool) {
+	peer, ok := geoip.ParseAddr(r.RemoteAddr)
+	if !ok {
+		return netip.Addr{}, false
+	}
+	if !pc.Trusted(peer) {
+		return peer, true
+	}
+
+	hops := forwardedFor(r.Header)
+	if len(hops) == 0 {
+		hops = splitList(r.Header.Values("X-Forwarded-For"))
+	}
+	if len(hops) == 0 {
+		if xri, ok := geoip.ParseAddr(r.Header.Get("X-Real-IP")); ok {
+			return xri, true
+		}
+		return peer, true
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := geoip.ParseAddr(hops[i])
+		if !ok {
+			return client, true
+		}
+		client = addr
+		if !pc.Trusted(addr) {
+			return client, true
+		}
+	}
+	// Zincirin tamamı güvenilir proxy'lerden oluşuyor
+	return client, true
+}
+
+// splitList splits comma-separated header values into trimmed entries.
+func splitList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+// forwardedFor returns the for= parameter of every element of the RFC 7239
+// Forwarded header(s), in order. An element without for= yields "" so that
+// it still counts as a (unparseable) hop.
+func forwardedFor(h http.Header) []string {
+	var out []string
+	for _, v := range h.Values("Forwarded") {
+		for _, elem := range splitQuoted(v, ',') {
+			if strings.TrimSpace(elem) == "" {
+				continue
+			}
+			forValue := ""
+			for _, pair := range splitQuoted(elem, ';') {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(strings.TrimSpace(key), "for") {
+					forValue = unquote(strings.TrimSpace(value))
+				}
+			}
+			out = append(out, forValue)
+		}
+	}
+	return out
+}
+
+// splitQuoted splits s on sep, ignoring separators inside quoted strings.
+func splitQuoted(s string, sep byte) []string {
+	var out []string
+	start, quoted := 0, false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			out = append(out, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, s[start:])
+}
+
+// unquote removes the quotes and backslash escapes of an RFC 7230
+// quoted-string; unquoted tokens are returned as is.
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	s = s[1 : len(s)-1]
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
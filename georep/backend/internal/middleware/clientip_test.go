@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	pc := NewProxyChain([]netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("2001:db8:ffff::/48"),
+	})
+
+	tests := []struct {
+		name    string
+		peer    string
+		headers map[string][]string
+		want    string // "" means no address
+	}{
+		{name: "direct", peer: "203.0.113.7:5000", want: "203.0.113.7"},
+		{name: "unparseable peer", peer: "garbage", want: ""},
+		{name: "ipv6 peer", peer: "[2001:db8::7]:5000", want: "2001:db8::7"},
+		{name: "mapped ipv4 peer", peer: "[::ffff:203.0.113.7]:5000", want: "203.0.113.7"},
+
+		// Başlıklar yalnızca güvenilir proxy'den gelince okunur
+		{name: "xff from untrusted peer", peer: "203.0.113.7:5000",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1"}}, want: "203.0.113.7"},
+		{name: "forwarded from untrusted peer", peer: "203.0.113.7:5000",
+			headers: map[string][]string{"Forwarded": {"for=198.51.100.1"}}, want: "203.0.113.7"},
+		{name: "x-real-ip from untrusted peer", peer: "203.0.113.7:5000",
+			headers: map[string][]string{"X-Real-Ip": {"198.51.100.1"}}, want: "203.0.113.7"},
+		{name: "x-real-ip from trusted peer", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"X-Real-Ip": {"198.51.100.1"}}, want: "198.51.100.1"},
+		{name: "unparseable x-real-ip", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"X-Real-Ip": {"unknown"}}, want: "10.0.0.1"},
+		{name: "x-real-ip ignored with xff", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.2"}, "X-Real-Ip": {"198.51.100.1"}}, want: "198.51.100.2"},
+
+		// X-Forwarded-For sağdan sola yürünür
+		{name: "xff single", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1"}}, want: "198.51.100.1"},
+		{name: "xff spoofed left-most", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.1"}}, want: "198.51.100.1"},
+		{name: "xff spoofed trusted-looking left-most", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"X-Forwarded-For": {"10.9.9.9, 198.51.100.1"}}, want: "198.51.100.1"},
+		{name: "xff through trusted hops", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.1, 10.0.0.3, 10.0.0.2"}}, want: "198.51.100.1"},
+		{name: "xff split across headers", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.1", "10.0.0.2"}}, want: "198.51.100.1"},
+		{name: "xff all trusted", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}}, want: "10.0.0.3"},
+		{name: "xff unparseable right-most", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1, unknown"}}, want: "10.0.0.1"},
+		{name: "xff unparseable behind trusted hop", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1, garbage, 10.0.0.2"}}, want: "10.0.0.2"},
+		{name: "xff unparseable left of client", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"X-Forwarded-For": {"unknown, 198.51.100.1"}}, want: "198.51.100.1"},
+		{name: "xff with port", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"X-Forwarded-For": {"198.51.100.1:4711"}}, want: "198.51.100.1"},
+		{name: "xff empty entries", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"X-Forwarded-For": {" , 198.51.100.1 ,"}}, want: "198.51.100.1"},
+
+		// Forwarded (RFC 7239) X-Forwarded-For'dan önce gelir
+		{name: "forwarded", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"Forwarded": {"for=198.51.100.1;proto=https"}}, want: "198.51.100.1"},
+		{name: "forwarded wins over xff", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"Forwarded": {"for=198.51.100.1"}, "X-Forwarded-For": {"198.51.100.2"}}, want: "198.51.100.1"},
+		{name: "forwarded quoted ipv6 with port", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"Forwarded": {`for="[2001:db8:cafe::17]:4711"`}}, want: "2001:db8:cafe::17"},
+		{name: "forwarded quoted ipv6", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"Forwarded": {`For="[2001:db8:cafe::17]"`}}, want: "2001:db8:cafe::17"},
+		{name: "forwarded quoted ipv4 with port", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"Forwarded": {`for="198.51.100.1:8080"`}}, want: "198.51.100.1"},
+		{name: "forwarded trusted ipv6 hop", peer: "[2001:db8:ffff::1]:443",
+			headers: map[string][]string{"Forwarded": {`for=198.51.100.1, for="[2001:db8:ffff::2]:80"`}}, want: "198.51.100.1"},
+		{name: "forwarded spoofed left-most", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"Forwarded": {"for=1.2.3.4, for=198.51.100.1"}}, want: "198.51.100.1"},
+		{name: "forwarded comma inside quotes", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"Forwarded": {`for=198.51.100.1;by="a,b", for=10.0.0.2`}}, want: "198.51.100.1"},
+		{name: "forwarded obfuscated hop", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"Forwarded": {`for=198.51.100.1, for="_hidden", for=10.0.0.2`}}, want: "10.0.0.2"},
+		{name: "forwarded unknown", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"Forwarded": {"for=unknown"}}, want: "10.0.0.1"},
+		{name: "forwarded element without for", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"Forwarded": {"for=198.51.100.1, proto=https"}}, want: "10.0.0.1"},
+		{name: "forwarded unquoted ipv6 is garbage", peer: "10.0.0.1:5000",
+			headers: map[string][]string{"Forwarded": {"for=[2001:db8:cafe::17]:4711:x"}}, want: "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.peer
+			for k, vs := range tt.headers {
+				for _, v := range vs {
+					r.Header.Add(k, v)
+				}
+			}
+			got, ok := pc.ClientIP(r)
+			if tt.want == "" {
+				if ok {
+					t.Fatalf("ClientIP = %s, want none", got)
+				}
+				return
+			}
+			if !ok || got != netip.MustParseAddr(tt.want) {
+				t.Fatalf("ClientIP = %s (%v), want %s", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientIPWithoutTrustedProxies(t *testing.T) {
+	pc := NewProxyChain(nil)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:5000"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	r.Header.Set("Forwarded", "for=198.51.100.1")
+	r.Header.Set("X-Real-IP", "198.51.100.1")
+	if got, _ := pc.ClientIP(r); got != netip.MustParseAddr("10.0.0.1") {
+		t.Errorf("ClientIP = %s, want the peer", got)
+	}
+}
@@ -1,69 +1,202 @@
 package middleware
 
 import (
+	"geo-repl-demo/internal/audit"
+	"geo-repl-demo/internal/auth"
+	"geo-repl-demo/internal/config"
+	"geo-repl-demo/internal/db"
+	"geo-repl-demo/internal/geo"
 	"geo-repl-demo/internal/geoip"
+	"geo-repl-demo/internal/preference"
+	"geo-repl-demo/internal/region"
+	"geo-repl-demo/internal/reqkey"
 	"log"
-	"net"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RegionMiddleware() gin.HandlerFunc {
+// RegionOptions configures RegionMiddleware.
+type RegionOptions struct {
+	// Proxies determines the real client address.
+	Proxies *ProxyChain
+	// DevMode accepts the ?region= and ?lat=&lon= overrides from anyone.
+	// Otherwise only admins may use them.
+	DevMode bool
+	// Preferences verifies signed region-preference cookies (optional).
+	Preferences *preference.Codec
+	// Audit receives every ?region= and ?lat=&lon= override attempt.
+	Audit audit.Sink
+}
+
+func RegionMiddleware(reg *region.Registry, replicas *db.ReplicaSet, geoProvider geoip.Provider, opts RegionOptions) gin.HandlerFunc {
+	proxies := opts.Proxies
+	sink := opts.Audit
+	if sink == nil {
+		sink = audit.LogSink{}
+	}
+
+	// Master her zaman aday; replikalar yalnızca hazır ve sağlıklıysa
+	healthy := func(n config.Node) bool {
+		return n.Name == reg.Master().Name || replicas.Healthy(n.Name)
+	}
+
 	return func(c *gin.Context) {
-		// Query parameter ile manuel bölge override (test için)
+		// Gerçek istemci adresi: yalnızca güvenilir proxy'lerin eklediği
+		// X-Forwarded-For / Forwarded kayıtlarına sağdan sola bakılır
+		addr, ok := proxies.ClientIP(c.Request)
+		clientIP := ""
+		if ok {
+			clientIP = addr.String()
+		}
+		c.Set(ClientAddrKey, clientIP)
+
+		// Ülke, bölge override'ından bağımsız olarak istemcinin gerçek
+		// konumundan gelir (lisans kısıtları bununla uygulanır)
+		if ok && geoip.IsPublic(addr) {
+			if country := geoip.CountryFromIP(geoProvider, clientIP); country != "" {
+				c.Set(CountryKey, country)
+			}
+		}
+
+		// Query parameter ile manuel bölge override: yalnızca dev modunda
+		// veya admin token'ı ile; her deneme audit log'a yazılır
 		if regionParam := c.Query("region"); regionParam != "" {
-			validRegions := map[string]bool{"us": true, "eu": true, "asia": true, "sa": true, "africa": true}
-			if validRegions[strings.ToLower(regionParam)] {
-				c.Set("region", strings.ToLower(regionParam))
+			principal, _ := auth.FromContext(c)
+			allowed := opts.DevMode || principal.IsAdmin()
+			r, known := reg.Lookup(regionParam)
+			outcome := audit.Denied
+			if allowed && known {
+				outcome = audit.Allowed
+			}
+			sink.Record(c.Request.Context(), audit.Event{
+				Actor:    actorName(principal),
+				Action:   "region.override",
+				Target:   regionParam,
+				ClientIP: clientIP,
+				Outcome:  outcome,
+			})
+			if outcome == audit.Allowed {
+				c.Set("region", r.ID)
+				c.Set(RegionSourceKey, "override")
 				c.Set("client_ip", "test-override")
-				log.Printf("🌍 Test modu: Manuel bölge seçildi → %s", strings.ToLower(regionParam))
+				log.Printf("🌍 Test modu: Manuel bölge seçildi → %s", r.ID)
 				c.Next()
 				return
 			}
 		}
 
-		// IP adresini al - önce X-Forwarded-For, sonra X-Real-IP, son olarak ClientIP
-		clientIP := c.GetHeader("X-Forwarded-For")
-		if clientIP == "" {
-			clientIP = c.GetHeader("X-Real-IP")
-		}
-		if clientIP == "" {
-			clientIP = c.ClientIP()
+		// API anahtarları oluşturuldukları bölgeye bağlıdır
+		if principal, ok := auth.FromContext(c); ok && principal.IsAPIKey() {
+			if r, ok := reg.Lookup(principal.Region); ok {
+				c.Set("region", r.ID)
+				c.Set(RegionSourceKey, "api_key")
+				c.Set("client_ip", clientIP)
+				c.Next()
+				return
+			}
 		}
-		
-		// X-Forwarded-For birden fazla IP içerebilir (proxy chain), ilkini al
-		if idx := strings.Index(clientIP, ","); idx != -1 {
-			clientIP = strings.TrimSpace(clientIP[:idx])
+
+		// İmzalı bölge tercihi çerezi (PUT /api/region-preference): yalnızca
+		// çerezin verildiği kullanıcı giriş yapmışsa geçerlidir
+		if opts.Preferences != nil {
+			principal, _ := auth.FromContext(c)
+			if pref, ok := opts.Preferences.FromRequest(c.Request, principal.Username); ok {
+				if r, ok := reg.Lookup(pref.Region); ok {
+					c.Set("region", r.ID)
+					c.Set(RegionSourceKey, "preference")
+					c.Set("client_ip", clientIP)
+					c.Next()
+					return
+				}
+			}
 		}
-		
-		// Private IP kontrolü (Docker network, localhost, vb.)
-		parsedIP := net.ParseIP(clientIP)
-		isPrivate := false
-		if parsedIP != nil {
-			isPrivate = parsedIP.IsLoopback() || parsedIP.IsPrivate() || parsedIP.IsLinkLocalUnicast()
-		} else {
-			isPrivate = clientIP == "::1" || clientIP == "127.0.0.1" || clientIP == "localhost"
+
+		// Açık koordinat da bir override'dır: ?region= gibi yalnızca dev
+		// modunda veya admin token'ı ile, her deneme audit log'a yazılır
+		if lat, lon := c.Query("lat"), c.Query("lon"); lat != "" && lon != "" {
+			principal, _ := auth.FromContext(c)
+			p, err := geo.ParsePoint(lat, lon)
+			outcome := audit.Denied
+			if (opts.DevMode || principal.IsAdmin()) && err == nil {
+				outcome = audit.Allowed
+			}
+			sink.Record(c.Request.Context(), audit.Event{
+				Actor:    actorName(principal),
+				Action:   "region.override.coordinates",
+				Target:   lat + "," + lon,
+				ClientIP: clientIP,
+				Outcome:  outcome,
+			})
+			if outcome == audit.Allowed && setNearest(c, reg, p, healthy) {
+				c.Set(RegionSourceKey, "override")
+				c.Set("client_ip", clientIP)
+				c.Next()
+				return
+			}
 		}
 
 		// Private IP ise sessizce varsayılan bölge kullan (log spam'ini önle)
-		if isPrivate {
-			c.Set("region", "eu")
+		if !ok || !geoip.IsPublic(addr) {
+			c.Set("region", reg.Default().ID)
+			c.Set("client_ip", clientIP)
+			c.Next()
+			return
+		}
+
+		// City veritabanı koordinat verebiliyorsa en yakın node'u seç
+		if p, ok := geoip.PointFromIP(geoProvider, clientIP); ok && setNearest(c, reg, p, healthy) {
 			c.Set("client_ip", clientIP)
 			c.Next()
 			return
 		}
 
-		// Public IP için GeoIP lookup yap
-		region := geoip.RegionFromIP(clientIP)
+		// Public IP için GeoIP lookup yap (ülke → bölge)
+		regionID := geoip.RegionFromIP(geoProvider, reg, clientIP)
 		
 		// Sadece başarılı lookup'larda log (spam'i azalt)
-		if region != "eu" || !isPrivate {
-			log.Printf("🌍 Client IP: %s → Bölge: %s", clientIP, region)
+		if regionID != reg.Default().ID {
+			log.Printf("🌍 Client IP: %s → Bölge: %s", clientIP, regionID)
 		}
 
-		c.Set("region", region)
+		c.Set("region", regionID)
 		c.Set("client_ip", clientIP)
 		c.Next()
 	}
 }
+
+const (
+	// ClientAddrKey is the context key holding the resolved client address,
+	// even when the region was overridden.
+	ClientAddrKey = reqkey.ClientAddr
+	// RegionSourceKey tells how the region was chosen when it was not
+	// derived from the client's location ("override", "api_key" or
+	// "preference").
+	RegionSourceKey = "region_source"
+	// CountryKey is the context key holding the client's ISO 3166-1 country
+	// code. It is unset when the country is unknown.
+	CountryKey = "country"
+)
+
+func actorName(p auth.Principal) string {
+	if p.Username == "" {
+		return "anonymous"
+	}
+	return p.Username
+}
+
+// setNearest picks the nearest healthy node to p by great-circle distance
+// and stores the region it serves in the context.
+func setNearest(c *gin.Context, reg *region.Registry, p geo.Point, healthy func(config.Node) bool) bool {
+	node, dist, ok := reg.Nearest(p, healthy)
+	if !ok {
+		return false
+	}
+	r, ok := reg.RegionForNode(node.Name)
+	if !ok {
+		return false
+	}
+	c.Set("region", r.ID)
+	c.Set("node", node.Name)
+	c.Set("distance_km", dist)
+	return true
+}
@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// APIKey is a machine credential. Only a hash of the secret is stored; the
+// plaintext key is returned once, on creation or rotation.
+type APIKey struct {
+	ID      int64  `json:"id"`
+	KeyID   string `json:"key_id"`
+	Name    string `json:"name"`
+	OwnerID int64  `json:"owner_id"`
+	// Scopes are "read", "write" and "admin".
+	Scopes []string `json:"scopes"`
+	// Region is where the key operates; it is also part of the key itself.
+	Region string `json:"region"`
+	// QuotaPerHour limits requests per key and backend; 0 means unlimited.
+	QuotaPerHour int    `json:"quota_per_hour"`
+	SecretHash   string `json:"-"`
+	// PreviousHash is the secret replaced by the last rotation. It stays
+	// valid until PreviousExpiresAt.
+	PreviousHash      string     `json:"-"`
+	PreviousExpiresAt *time.Time `json:"previous_expires_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Revoked reports whether the key has been revoked.
+func (k APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
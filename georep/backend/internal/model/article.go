@@ -7,20 +7,197 @@ type Article struct {
 	Title       string    `json:"title"`
 	Summary     string    `json:"summary"`
 	ContentLong string    `json:"content_long"`
-	Author      string    `json:"author"`
+	Author      string    `json:"author"` // derived from the author's display name
+	AuthorID    int64     `json:"author_id"`
 	Region      string    `json:"region"`
 	CreatedAt   time.Time `json:"created_at"`
+
+	// Licensing restrictions as ISO 3166-1 alpha-2 codes. An empty allow
+	// list means everywhere; the deny list always wins.
+	AllowedCountries []string `json:"allowed_countries"`
+	DeniedCountries  []string `json:"denied_countries"`
+
+	// Revision starts at 1 and grows with every edit.
+	Revision  int       `json:"revision"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt is set while the article is in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// Status is draft, scheduled or published. A scheduled article becomes
+	// visible in a region at its RegionPublishAt entry, or at PublishAt for
+	// regions without one.
+	Status          string               `json:"status"`
+	PublishAt       *time.Time           `json:"publish_at,omitempty"`
+	RegionPublishAt map[string]time.Time `json:"region_publish_at,omitempty"`
+
+	// Tags and Categories are slugs; see Category.
+	Tags       []string `json:"tags"`
+	Categories []string `json:"categories"`
+
+	// Locale is the language of Title, Summary and ContentLong. Reads serve
+	// the translation that best matches the reader, so it may differ from
+	// the locale the article was written in.
+	Locale string `json:"locale"`
+	// Translations are keyed by locale and replicate with the article.
+	// Reader responses drop them and list the available locales instead.
+	Translations map[string]Translation `json:"translations,omitempty"`
+	Locales      []string               `json:"locales,omitempty"`
+
+	// ContentHTML is ContentLong (Markdown) rendered and sanitized when the
+	// article is written; TOC lists its headings.
+	ContentHTML string    `json:"content_html"`
+	TOC         []Heading `json:"toc"`
 }
 
-type CreateArticleInput struct {
-	Title       string `json:"title" binding:"required"`
-	Summary     string `json:"summary" binding:"required"`
+// Translation is an article's text in another locale. An empty summary is
+// filled from the content; ContentHTML and TOC are rendered on write.
+type Translation struct {
+	Title       string    `json:"title" binding:"required"`
+	Summary     string    `json:"summary"`
+	ContentLong string    `json:"content_long" binding:"required"`
+	ContentHTML string    `json:"content_html,omitempty"`
+	TOC         []Heading `json:"toc,omitempty"`
+}
+
+// Heading is a table of contents entry; ID is its anchor in ContentHTML.
+type Heading struct {
+	Level int    `json:"level"`
+	ID    string `json:"id"`
+	Text  string `json:"text"`
+}
+
+// Article statuses.
+const (
+	StatusDraft     = "draft"
+	StatusScheduled = "scheduled"
+	StatusPublished = "published"
+)
+
+// PublishTimeIn returns when the article is released in region; ok is
+// false for drafts.
+func (a Article) PublishTimeIn(region string) (t time.Time, ok bool) {
+	switch a.Status {
+	case StatusPublished:
+		return time.Time{}, true
+	case StatusScheduled:
+		if rt, found := a.RegionPublishAt[region]; found {
+			return rt, true
+		}
+		if a.PublishAt != nil {
+			return *a.PublishAt, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// PublishedIn reports whether readers in region may see the article at now.
+func (a Article) PublishedIn(region string, now time.Time) bool {
+	t, ok := a.PublishTimeIn(region)
+	return ok && !t.After(now)
+}
+
+// ArticleRevision is an article as it was after one change. Revisions are
+// append-only; a delete is recorded as a final revision with Deleted set.
+type ArticleRevision struct {
+	Article
+	// RecordedAt is the master's commit time of the change.
+	RecordedAt time.Time `json:"recorded_at"`
+	Editor     string    `json:"editor"`
+	EditorID   int64     `json:"editor_id"`
+	Deleted    bool      `json:"deleted"`
+	// Seq is the master's insertion order, used to replicate revisions
+	// incrementally.
+	Seq int64 `json:"-"`
+}
+
+// AvailableIn reports whether the article may be served to a client in the
+// given country. An unknown country ("") only sees articles without an
+// allow list.
+func (a Article) AvailableIn(country string) bool {
+	for _, c := range a.DeniedCountries {
+		if c == country {
+			return false
+		}
+	}
+	if len(a.AllowedCountries) == 0 {
+		return true
+	}
+	for _, c := range a.AllowedCountries {
+		if c == country {
+			return true
+		}
+	}
+	return false
+}
+
+// PublishInput sets the status and release schedule of an article.
+type PublishInput struct {
+	// Status defaults to published on create and to the current status on
+	// update.
+	Status          string               `json:"status"`
+	PublishAt       *time.Time           `json:"publish_at"`
+	RegionPublishAt map[string]time.Time `json:"region_publish_at"`
+	// PublishLocalTime ("2006-01-02T15:04") schedules the article at this
+	// wall-clock time in every region's own time zone.
+	PublishLocalTime string `json:"publish_local_time"`
+}
+
+// ArticleInput is the part of an article written on create and update.
+type ArticleInput struct {
+	Title string `json:"title" binding:"required"`
+	// Summary is filled from ContentLong when left empty. ContentLong is
+	// Markdown.
+	Summary     string `json:"summary"`
 	ContentLong string `json:"content_long" binding:"required"`
-	Author      string `json:"author" binding:"required"`
+
+	// Tags are created on first use; categories must already exist.
+	Tags       []string `json:"tags"`
+	Categories []string `json:"categories"`
+
+	// Locale is the language of the text above; empty keeps the current
+	// one, or the topology's default locale for a new article. Translations
+	// replace all existing ones; leave it out to keep them.
+	Locale       string                 `json:"locale"`
+	Translations map[string]Translation `json:"translations"`
+
+	PublishInput
+
+	// Rendered from ContentLong by the service.
+	ContentHTML string    `json:"-"`
+	TOC         []Heading `json:"-"`
+}
+
+type CreateArticleInput struct {
+	ArticleInput
+
+	AllowedCountries []string `json:"allowed_countries"`
+	DeniedCountries  []string `json:"denied_countries"`
+}
+
+type UpdateArticleInput struct {
+	ArticleInput
+
+	// The country lists replace the current ones. Leave one out to keep
+	// it; send [] to clear it.
+	AllowedCountries *[]string `json:"allowed_countries"`
+	DeniedCountries  *[]string `json:"denied_countries"`
+}
+
+// ArticlePurge tracks the hard delete of an article whose retention has
+// expired, until every replica has confirmed it.
+type ArticlePurge struct {
+	ArticleID         int64      `json:"article_id"`
+	PurgedAt          time.Time  `json:"purged_at"`
+	ConfirmedAt       *time.Time `json:"confirmed_at,omitempty"`
+	ConfirmedReplicas []string   `json:"confirmed_replicas"`
 }
 
 type ReplicationStatus struct {
 	Replica string    `json:"replica"`
 	Status  string    `json:"status"`
 	LastAt  time.Time `json:"last_at"`
+	// BlobPending counts attachments whose contents have not reached the
+	// replica's blob store yet; BlobLagSeconds is the age of the oldest.
+	BlobPending    int     `json:"blob_pending"`
+	BlobLagSeconds float64 `json:"blob_lag_seconds"`
 }
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// Attachment is a file attached to an article. The row replicates like the
+// article itself; the contents live in the blob store of every node under
+// Key and are copied to the regional stores asynchronously.
+type Attachment struct {
+	ID          int64     `json:"id"`
+	ArticleID   int64     `json:"article_id"`
+	Key         string    `json:"-"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	UploadedBy  int64     `json:"uploaded_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	// URL points at the copy in the reader's nearest store; it is set per
+	// request and not stored.
+	URL   string `json:"url"`
+	Store string `json:"store"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// AuditEntry is one row of the append-only audit log.
+type AuditEntry struct {
+	ID      int64     `json:"id,omitempty"`
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor"`
+	ActorID int64     `json:"actor_id,omitempty"`
+	Role    string    `json:"role,omitempty"`
+	Action  string    `json:"action"`
+	// Entity is the kind of the affected object ("article", "user", ...);
+	// Target identifies it.
+	Entity   string `json:"entity,omitempty"`
+	Target   string `json:"target,omitempty"`
+	ClientIP string `json:"client_ip,omitempty"`
+	// Region is the region the request was routed to.
+	Region  string `json:"region,omitempty"`
+	Outcome string `json:"outcome"`
+	// BeforeHash and AfterHash fingerprint the entity around the change;
+	// empty when it did not exist on that side.
+	BeforeHash string            `json:"before_hash,omitempty"`
+	AfterHash  string            `json:"after_hash,omitempty"`
+	Detail     map[string]string `json:"detail,omitempty"`
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// Comment is a reader comment on an article. Comments are written at the
+// reader's regional node and merged into the master asynchronously, so IDs
+// are UUIDv7 strings generated by the accepting node rather than SERIALs.
+type Comment struct {
+	ID        string `json:"id"`
+	ArticleID int64  `json:"article_id"`
+	// ParentID is the comment this one replies to, empty for top-level
+	// comments.
+	ParentID string `json:"parent_id,omitempty"`
+	AuthorID int64  `json:"author_id"`
+	Author   string `json:"author"`
+	Body     string `json:"body"`
+	// Origin is the node that accepted the write; Region is the reader's
+	// region at that time.
+	Origin string `json:"origin"`
+	Region string `json:"region"`
+	// Clock is a Lamport timestamp per article. A reply's clock is always
+	// greater than its parent's, so ordering by (Clock, ID) is causal on
+	// every node.
+	Clock     int64     `json:"clock"`
+	CreatedAt time.Time `json:"created_at"`
+	// MergedAt is when the master accepted the comment; nil while it only
+	// exists on its origin node.
+	MergedAt *time.Time `json:"merged_at,omitempty"`
+}
+
+type CommentInput struct {
+	Body     string `json:"body" binding:"required"`
+	ParentID string `json:"parent_id"`
+}
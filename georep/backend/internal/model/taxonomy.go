@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// Category is an editor-curated section an article can be filed under.
+// Tags, unlike categories, are free-form and need no model of their own.
+type Category struct {
+	Slug        string    `json:"slug"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type CategoryInput struct {
+	Slug        string `json:"slug"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// ArticleFilter narrows an article listing. An article matches when it has
+// any of the tags and any of the categories; empty lists do not filter.
+type ArticleFilter struct {
+	Tags       []string
+	Categories []string
+}
+
+// Facet is the number of visible articles carrying a tag or category.
+type Facet struct {
+	Slug  string `json:"slug"`
+	Name  string `json:"name,omitempty"`
+	Count int    `json:"count"`
+}
+
+// ArticleFacets holds the tag and category counts of a region. Each facet
+// is counted under the filter of the other one, so alternatives stay
+// visible while one is selected.
+type ArticleFacets struct {
+	Tags       []Facet `json:"tags"`
+	Categories []Facet `json:"categories"`
+}
@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+// ArticleRecord is one article in an import or export file. Imports always
+// create new articles at the master: ID, Region and UpdatedAt are written by
+// exports only and ignored on import. A row with DeletedAt is imported into
+// the trash.
+type ArticleRecord struct {
+	ID          int64  `json:"id,omitempty"`
+	Title       string `json:"title"`
+	Summary     string `json:"summary"`
+	ContentLong string `json:"content_long"`
+	// Author is a username on the master. An import row without one is
+	// written by the importing user.
+	Author string `json:"author,omitempty"`
+	Region string `json:"region,omitempty"`
+
+	Locale       string                 `json:"locale,omitempty"`
+	Translations map[string]Translation `json:"translations,omitempty"`
+
+	AllowedCountries []string `json:"allowed_countries,omitempty"`
+	DeniedCountries  []string `json:"denied_countries,omitempty"`
+	Tags             []string `json:"tags,omitempty"`
+	Categories       []string `json:"categories,omitempty"`
+
+	Status          string               `json:"status,omitempty"`
+	PublishAt       *time.Time           `json:"publish_at,omitempty"`
+	RegionPublishAt map[string]time.Time `json:"region_publish_at,omitempty"`
+
+	// CreatedAt is kept on import; empty means now.
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	// DeletedAt is kept on import; the trash retention counts from it.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// ImportReport is the outcome of a bulk import. Nothing is written unless
+// every row is valid; Committed tells whether the rows were written.
+type ImportReport struct {
+	DryRun    bool        `json:"dry_run"`
+	Committed bool        `json:"committed"`
+	Total     int         `json:"total"`
+	Imported  int         `json:"imported"`
+	Failed    int         `json:"failed"`
+	Rows      []ImportRow `json:"rows"`
+}
+
+// ImportRow reports one row of an import. Row counts data rows from 1,
+// not counting the CSV header.
+type ImportRow struct {
+	Row       int    `json:"row"`
+	SourceID  int64  `json:"source_id,omitempty"`
+	ArticleID int64  `json:"article_id,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
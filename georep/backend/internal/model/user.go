@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+type User struct {
+	ID           int64  `json:"id"`
+	Username     string `json:"username"`
+	PasswordHash string `json:"-"`
+	Role         string `json:"role"`
+	HomeRegion   string `json:"home_region"`
+	DisplayName  string `json:"display_name"`
+	// OIDCSubject links the user to an identity provider account
+	// ("issuer|sub"); such users have no usable password.
+	OIDCSubject string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Name is the display name, falling back to the username.
+func (u User) Name() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	return u.Username
+}
@@ -0,0 +1,80 @@
+// Package preference lets authenticated users pin their reads to a region
+// through a signed cookie.
+package preference
+
+import (
+	"net/http"
+	"time"
+
+	"geo-repl-demo/internal/auth"
+)
+
+// CookieName is the name of the region-preference cookie.
+const CookieName = "geo_region"
+
+// Preference is the signed content of the cookie.
+type Preference struct {
+	Region    string    `json:"region"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"exp"`
+}
+
+// Codec signs and verifies preference cookies.
+type Codec struct {
+	signer *auth.Signer
+	ttl    time.Duration
+}
+
+// NewCodec returns a Codec; cookies stay valid for ttl.
+func NewCodec(signer *auth.Signer, ttl time.Duration) *Codec {
+	return &Codec{signer: signer, ttl: ttl}
+}
+
+// Cookie returns a signed cookie pinning username to region.
+func (c *Codec) Cookie(region, username string, secure bool) (*http.Cookie, Preference, error) {
+	p := Preference{Region: region, Username: username, ExpiresAt: time.Now().Add(c.ttl).UTC()}
+	value, err := c.signer.Sign(p)
+	if err != nil {
+		return nil, Preference{}, err
+	}
+	return &http.Cookie{
+		Name:     CookieName,
+		Value:    value,
+		Path:     "/",
+		Expires:  p.ExpiresAt,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	}, p, nil
+}
+
+// Clear returns a cookie that removes the preference.
+func (c *Codec) Clear(secure bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     CookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// FromRequest returns the preference carried by r if its cookie is present,
+// correctly signed, not expired and issued to username. A cookie copied
+// from another user's browser, or sent without logging in, is ignored.
+func (c *Codec) FromRequest(r *http.Request, username string) (Preference, bool) {
+	if username == "" {
+		return Preference{}, false
+	}
+	ck, err := r.Cookie(CookieName)
+	if err != nil || ck.Value == "" {
+		return Preference{}, false
+	}
+	var p Preference
+	if err := c.signer.Verify(ck.Value, &p); err != nil || time.Now().After(p.ExpiresAt) || p.Username != username {
+		return Preference{}, false
+	}
+	return p, true
+}
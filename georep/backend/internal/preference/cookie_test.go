@@ -0,0 +1,38 @@
+package preference
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"geo-repl-demo/internal/auth"
+)
+
+func TestFromRequestRequiresOwner(t *testing.T) {
+	codec := NewCodec(auth.NewSigner([]byte("test-key")), time.Hour)
+	ck, _, err := codec.Cookie("asia", "ayse", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, username string
+		want           bool
+	}{
+		{"owner", "ayse", true},
+		{"anonymous", "", false},
+		// Kopyalanan çerez başka bir kullanıcının okumalarını yönlendiremez
+		{"other user", "mehmet", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/region", nil)
+		r.AddCookie(ck)
+		p, ok := codec.FromRequest(r, tt.username)
+		if ok != tt.want {
+			t.Errorf("%s: FromRequest ok = %v, want %v", tt.name, ok, tt.want)
+		}
+		if ok && p.Region != "asia" {
+			t.Errorf("%s: region = %q, want asia", tt.name, p.Region)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package preference
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"geo-repl-demo/internal/audit"
+	"geo-repl-demo/internal/auth"
+	"geo-repl-demo/internal/region"
+)
+
+type Handler struct {
+	codec   *Codec
+	regions *region.Registry
+	audit   audit.Sink
+}
+
+func NewHandler(codec *Codec, regions *region.Registry, sink audit.Sink) *Handler {
+	return &Handler{codec: codec, regions: regions, audit: sink}
+}
+
+func RegisterRoutes(r *gin.Engine, h *Handler) {
+	api := r.Group("/api")
+	{
+		api.GET("/region-preference", h.get)
+		api.PUT("/region-preference", auth.Authenticated(), h.set)
+		api.DELETE("/region-preference", auth.Authenticated(), h.clear)
+	}
+}
+
+type setRequest struct {
+	Region string `json:"region" binding:"required"`
+}
+
+// get returns the caller's pinned region, if any.
+func (h *Handler) get(c *gin.Context) {
+	principal, _ := auth.FromContext(c)
+	p, ok := h.codec.FromRequest(c.Request, principal.Username)
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{"region": nil})
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// set pins the caller's reads to a region.
+func (h *Handler) set(c *gin.Context) {
+	principal, _ := auth.FromContext(c)
+	var req setRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reg, ok := h.regions.Lookup(req.Region)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown region: " + req.Region})
+		return
+	}
+
+	ck, p, err := h.codec.Cookie(reg.ID, principal.Username, c.Request.TLS != nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	http.SetCookie(c.Writer, ck)
+	h.audit.Record(c.Request.Context(), principal.AuditEvent(c.Request.Context(), "region.preference.set", "region_preference", reg.ID))
+	c.JSON(http.StatusOK, p)
+}
+
+// clear removes the pinned region.
+func (h *Handler) clear(c *gin.Context) {
+	principal, _ := auth.FromContext(c)
+	http.SetCookie(c.Writer, h.codec.Clear(c.Request.TLS != nil))
+	h.audit.Record(c.Request.Context(), principal.AuditEvent(c.Request.Context(), "region.preference.clear", "region_preference", ""))
+	c.JSON(http.StatusOK, gin.H{"status": "cleared"})
+}
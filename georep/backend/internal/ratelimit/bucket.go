@@ -0,0 +1,50 @@
+// Package ratelimit implements token-bucket request limits.
+package ratelimit
+
+import (
+	"math"
+	"time"
+
+	"geo-repl-demo/internal/config"
+)
+
+// bucket is a token bucket. It starts full.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(r config.Rate, now time.Time) *bucket {
+	return &bucket{tokens: float64(r.Burst), last: now}
+}
+
+// take removes one token. If the bucket is empty it returns false and how
+// long until a token is available.
+func (b *bucket) take(r config.Rate, now time.Time) (bool, time.Duration) {
+	b.refill(r, now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := (1 - b.tokens) / r.Rate
+	return false, time.Duration(math.Ceil(wait * float64(time.Second)))
+}
+
+// refill adds the tokens earned since the last call. A smaller burst after
+// a configuration change caps the bucket immediately.
+func (b *bucket) refill(r config.Rate, now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * r.Rate
+		b.last = now
+	}
+	if b.tokens > float64(r.Burst) {
+		b.tokens = float64(r.Burst)
+	}
+}
+
+// full reports whether the bucket would be full at now; such buckets can be
+// dropped, since a new bucket starts full as well.
+func (b *bucket) full(r config.Rate, now time.Time) bool {
+	b.refill(r, now)
+	return b.tokens >= float64(r.Burst)
+}
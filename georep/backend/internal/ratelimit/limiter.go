@@ -0,0 +1,171 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"geo-repl-demo/internal/auth"
+	"geo-repl-demo/internal/config"
+	"geo-repl-demo/internal/middleware"
+)
+
+// Request classes.
+const (
+	ClassRead  = "read"
+	ClassWrite = "write"
+)
+
+// sweepEvery is how many requests pass between removals of idle buckets.
+const sweepEvery = 4096
+
+// Limiter applies the rate limits of the topology file. Buckets live in
+// process memory, so every backend enforces its own budget.
+type Limiter struct {
+	mu      sync.Mutex
+	limits  config.RateLimits
+	buckets map[string]*entry
+	calls   int
+	now     func() time.Time
+}
+
+type entry struct {
+	b    *bucket
+	rate config.Rate
+}
+
+// Decision is the outcome of Allow.
+type Decision struct {
+	Allowed bool
+	// Scope is the bucket that refused the request: "read", "write" or
+	// "region_write".
+	Scope      string
+	RetryAfter time.Duration
+}
+
+// New returns a limiter enforcing limits.
+func New(limits config.RateLimits) *Limiter {
+	return &Limiter{limits: limits, buckets: map[string]*entry{}, now: time.Now}
+}
+
+// Update swaps the limits, e.g. after a topology reload. Existing buckets
+// keep their tokens and switch to the new rate.
+func (l *Limiter) Update(limits config.RateLimits) {
+	l.mu.Lock()
+	l.limits = limits
+	l.mu.Unlock()
+}
+
+// Allow charges one request of class by subject routed to region. Writes
+// are charged to the caller's bucket first and then to the region's shared
+// admission bucket, so a flooding caller is stopped before it can drain the
+// region budget of everyone else. The caller has one bucket per class
+// across all regions; the region only selects its rate, so switching
+// regions does not buy a fresh budget.
+func (l *Limiter) Allow(class, region, subject string) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+
+	budget := l.limits.For(region)
+	rate := budget.Read
+	if class == ClassWrite {
+		rate = budget.Write
+	}
+	if ok, wait := l.take(class+"|"+subject, rate, now); !ok {
+		return Decision{Scope: class, RetryAfter: wait}
+	}
+	if class == ClassWrite {
+		if ok, wait := l.take("region_write|"+region, budget.RegionWrite, now); !ok {
+			return Decision{Scope: "region_write", RetryAfter: wait}
+		}
+	}
+	return Decision{Allowed: true}
+}
+
+func (l *Limiter) take(key string, r config.Rate, now time.Time) (bool, time.Duration) {
+	if !r.Limited() {
+		return true, 0
+	}
+	e, ok := l.buckets[key]
+	if !ok {
+		e = &entry{b: newBucket(r, now)}
+		l.buckets[key] = e
+	}
+	e.rate = r
+	return e.b.take(r, now)
+}
+
+// sweep drops full buckets now and then so that one-off clients do not
+// accumulate. Callers hold l.mu.
+func (l *Limiter) sweep(now time.Time) {
+	l.calls++
+	if l.calls%sweepEvery != 0 {
+		return
+	}
+	for key, e := range l.buckets {
+		if e.b.full(e.rate, now) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Middleware limits requests by API key, user or client IP. It must run
+// after authentication and RegionMiddleware.
+func (l *Limiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+		class := ClassRead
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			class = ClassWrite
+		}
+
+		d := l.Allow(class, c.GetString("region"), Subject(c))
+		if d.Allowed {
+			c.Next()
+			return
+		}
+		retry := int(math.Ceil(d.RetryAfter.Seconds()))
+		if retry < 1 {
+			retry = 1
+		}
+		c.Header("Retry-After", strconv.Itoa(retry))
+		// Bölge kapasitesi dolduysa istemcinin suçu değil: 503
+		status, code := http.StatusTooManyRequests, "rate_limited"
+		if d.Scope == "region_write" {
+			status, code = http.StatusServiceUnavailable, "write_admission"
+		}
+		c.AbortWithStatusJSON(status, gin.H{
+			"error":       d.Scope + " rate limit exceeded",
+			"code":        code,
+			"scope":       d.Scope,
+			"retry_after": retry,
+		})
+	}
+}
+
+// Subject is the identity a request is limited by: its API key, its user,
+// or else its client address.
+func Subject(c *gin.Context) string {
+	if p, ok := auth.FromContext(c); ok {
+		if p.IsAPIKey() {
+			return "key:" + p.KeyID
+		}
+		if p.UserID != 0 {
+			return "user:" + strconv.FormatInt(p.UserID, 10)
+		}
+	}
+	addr := c.GetString(middleware.ClientAddrKey)
+	if addr == "" {
+		addr = c.ClientIP()
+	}
+	return "ip:" + addr
+}
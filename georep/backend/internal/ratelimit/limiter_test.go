@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"geo-repl-demo/internal/config"
+	"geo-repl-demo/internal/middleware"
+)
+
+func newTestLimiter(limits config.RateLimits) (*Limiter, *time.Time) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := New(limits)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestSwitchingRegionDoesNotRefill(t *testing.T) {
+	l, _ := newTestLimiter(config.RateLimits{
+		RateBudget: config.RateBudget{
+			Read:  config.Rate{Rate: 1, Burst: 2},
+			Write: config.Rate{Rate: 0.1, Burst: 2},
+		},
+	})
+	for _, class := range []string{ClassRead, ClassWrite} {
+		for i, region := range []string{"eu", "us"} {
+			if d := l.Allow(class, region, "user:1"); !d.Allowed {
+				t.Fatalf("%s #%d in %s refused", class, i+1, region)
+			}
+		}
+		for _, region := range []string{"eu", "us", "asia", "tr"} {
+			if d := l.Allow(class, region, "user:1"); d.Allowed || d.Scope != class {
+				t.Errorf("%s in %s after the burst: %+v, want refused by %s", class, region, d, class)
+			}
+		}
+		if d := l.Allow(class, "asia", "user:2"); !d.Allowed {
+			t.Errorf("%s of another caller refused", class)
+		}
+	}
+}
+
+func TestRegionSelectsRate(t *testing.T) {
+	l, now := newTestLimiter(config.RateLimits{
+		RateBudget: config.RateBudget{Write: config.Rate{Rate: 1, Burst: 5}},
+		Regions: map[string]config.RateBudget{
+			"tr": {Write: config.Rate{Rate: 0.1, Burst: 1}},
+		},
+	})
+	// tr'nin küçük burst'ü ortak kovayı hemen kırpar
+	if d := l.Allow(ClassWrite, "tr", "user:1"); !d.Allowed {
+		t.Fatal("first write refused")
+	}
+	if d := l.Allow(ClassWrite, "tr", "user:1"); d.Allowed {
+		t.Fatal("tr burst not applied")
+	}
+	if d := l.Allow(ClassWrite, "eu", "user:1"); d.Allowed {
+		t.Fatal("eu write refilled the bucket drained in tr")
+	}
+	*now = now.Add(2 * time.Second)
+	if d := l.Allow(ClassWrite, "eu", "user:1"); !d.Allowed {
+		t.Error("eu write refused after refilling at eu's rate")
+	}
+}
+
+func TestRegionWriteAdmissionIsPerRegion(t *testing.T) {
+	l, _ := newTestLimiter(config.RateLimits{
+		RateBudget: config.RateBudget{RegionWrite: config.Rate{Rate: 0.1, Burst: 1}},
+	})
+	if d := l.Allow(ClassWrite, "eu", "user:1"); !d.Allowed {
+		t.Fatal("first write refused")
+	}
+	if d := l.Allow(ClassWrite, "eu", "user:2"); d.Allowed || d.Scope != "region_write" {
+		t.Errorf("second eu write: %+v, want refused by region_write", d)
+	}
+	if d := l.Allow(ClassWrite, "us", "user:2"); !d.Allowed {
+		t.Error("us write refused by eu's admission bucket")
+	}
+}
+
+func TestSubjectUsesResolvedClientAddr(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	c.Request.RemoteAddr = "10.0.0.1:5000"
+	if got := Subject(c); got != "ip:10.0.0.1" {
+		t.Errorf("Subject = %q without a resolved address", got)
+	}
+	c.Set(middleware.ClientAddrKey, "198.51.100.1")
+	if got := Subject(c); got != "ip:198.51.100.1" {
+		t.Errorf("Subject = %q, want the resolved client address", got)
+	}
+}
@@ -0,0 +1,202 @@
+package region
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"geo-repl-demo/internal/config"
+)
+
+//go:embed iso3166.csv
+var isoCSV []byte
+
+//go:embed countries.yaml
+var defaultCountryMap []byte
+
+// Country is an ISO 3166-1 alpha-2 country with its GeoIP continent code.
+type Country struct {
+	Code      string `json:"code"`
+	Continent string `json:"continent"`
+	Name      string `json:"name"`
+}
+
+var (
+	countries    []Country
+	countryByISO = map[string]Country{}
+	continents   = map[string]bool{"EU": true, "AS": true, "AF": true, "NA": true, "SA": true, "OC": true, "AN": true}
+)
+
+func init() {
+	rows, err := csv.NewReader(bytes.NewReader(isoCSV)).ReadAll()
+	if err != nil {
+		panic(fmt.Sprintf("region: embedded iso3166.csv: %v", err))
+	}
+	for _, row := range rows[1:] {
+		c := Country{Code: row[0], Continent: row[1], Name: row[2]}
+		countries = append(countries, c)
+		countryByISO[c.Code] = c
+	}
+}
+
+// LookupCountry returns the ISO 3166-1 country with the given alpha-2 code.
+func LookupCountry(iso string) (Country, bool) {
+	c, ok := countryByISO[strings.ToUpper(strings.TrimSpace(iso))]
+	return c, ok
+}
+
+// CountryMap is the versioned country → region mapping data file.
+type CountryMap struct {
+	Version           string              `yaml:"version" json:"version"`
+	ContinentDefaults map[string]string   `yaml:"continent_defaults" json:"continent_defaults"`
+	Overrides         map[string][]string `yaml:"overrides" json:"overrides"`
+}
+
+// Issue is a problem found while validating a country map.
+type Issue struct {
+	Severity string `json:"severity"` // "error" or "warning"
+	Message  string `json:"message"`
+}
+
+// LoadCountryMap reads a country map file; an empty path selects the
+// mapping embedded in the binary.
+func LoadCountryMap(path string) (*CountryMap, error) {
+	if path == "" {
+		return ParseCountryMap(defaultCountryMap)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCountryMap(raw)
+}
+
+// ParseCountryMap decodes a YAML (or JSON) country map.
+func ParseCountryMap(raw []byte) (*CountryMap, error) {
+	var m CountryMap
+	if err := yaml.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("parse country map: %w", err)
+	}
+	return &m, nil
+}
+
+// ValidateCountryMap checks m against the regions defined by t and returns
+// every issue found, without building a registry.
+func ValidateCountryMap(t config.Topology, m *CountryMap) ([]Issue, error) {
+	tbl, err := build(t)
+	if err != nil {
+		return nil, err
+	}
+	return compile(m, tbl).issues, nil
+}
+
+// MappingEntry is the effective region of a single country.
+type MappingEntry struct {
+	Country
+	Region string `json:"region"`
+	Source string `json:"source"` // override, continent or default
+}
+
+// countryTable is the compiled form of a CountryMap against a region table.
+type countryTable struct {
+	version   string
+	overrides map[string]string
+	continent map[string]string
+	issues    []Issue
+}
+
+// compile validates m against the regions in t. Unknown or duplicate ISO
+// codes, unknown continents and references to undefined regions are
+// reported; entries with issues are skipped.
+func compile(m *CountryMap, t *table) *countryTable {
+	ct := &countryTable{
+		version:   m.Version,
+		overrides: map[string]string{},
+		continent: map[string]string{},
+	}
+	flag := func(severity, format string, args ...any) {
+		ct.issues = append(ct.issues, Issue{Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, cont := range sortedKeys(m.ContinentDefaults) {
+		code := strings.ToUpper(strings.TrimSpace(cont))
+		if !continents[code] {
+			flag("error", "unknown continent code %q", cont)
+			continue
+		}
+		reg, ok := t.lookup(m.ContinentDefaults[cont])
+		if !ok {
+			flag("error", "continent %s: unknown region %q", code, m.ContinentDefaults[cont])
+			continue
+		}
+		ct.continent[code] = reg.ID
+	}
+
+	for _, name := range sortedKeys(m.Overrides) {
+		reg, ok := t.lookup(name)
+		if !ok {
+			flag("error", "overrides: unknown region %q", name)
+			continue
+		}
+		for _, raw := range m.Overrides[name] {
+			code := strings.ToUpper(strings.TrimSpace(raw))
+			if _, known := countryByISO[code]; !known {
+				flag("warning", "region %s: unknown ISO code %q", reg.ID, raw)
+				continue
+			}
+			if prev, dup := ct.overrides[code]; dup {
+				flag("error", "duplicate ISO code %s (regions %s and %s)", code, prev, reg.ID)
+				continue
+			}
+			ct.overrides[code] = reg.ID
+		}
+	}
+
+	for code := range continents {
+		if _, ok := ct.continent[code]; !ok {
+			flag("warning", "continent %s has no default region", code)
+		}
+	}
+	sort.SliceStable(ct.issues, func(i, j int) bool { return ct.issues[i].Message < ct.issues[j].Message })
+	return ct
+}
+
+func (ct *countryTable) hasErrors() bool {
+	for _, is := range ct.issues {
+		if is.Severity == "error" {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve maps a country (and optionally its continent, as reported by
+// GeoIP) to a region ID and the rule that matched.
+func (ct *countryTable) resolve(iso, continent, def string) (string, string) {
+	iso = strings.ToUpper(iso)
+	if id, ok := ct.overrides[iso]; ok {
+		return id, "override"
+	}
+	if continent == "" {
+		continent = countryByISO[iso].Continent
+	}
+	if id, ok := ct.continent[strings.ToUpper(continent)]; ok {
+		return id, "continent"
+	}
+	return def, "default"
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
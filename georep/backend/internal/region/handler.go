@@ -0,0 +1,68 @@
+package region
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler exposes the region registry over HTTP.
+type Handler struct {
+	reg *Registry
+}
+
+func NewHandler(reg *Registry) *Handler {
+	return &Handler{reg: reg}
+}
+
+// RegisterRoutes mounts the region routes on a Gin router.
+func RegisterRoutes(r *gin.Engine, h *Handler) {
+	api := r.Group("/api")
+	{
+		api.GET("/regions", h.list)
+		api.GET("/regions/countries", h.countries)
+	}
+}
+
+func (h *Handler) list(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"default": h.reg.Default().ID,
+		"regions": h.reg.Regions(),
+	})
+}
+
+// countries lists the effective country → region mapping. ?region= narrows
+// the list to a single region, ?source= to one rule (override, continent,
+// default).
+func (h *Handler) countries(c *gin.Context) {
+	version, entries := h.reg.CountryMapping()
+
+	filterRegion := ""
+	if name := c.Query("region"); name != "" {
+		reg, ok := h.reg.Lookup(name)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown region"})
+			return
+		}
+		filterRegion = reg.ID
+	}
+	source := strings.ToLower(c.Query("source"))
+
+	out := entries[:0]
+	for _, e := range entries {
+		if filterRegion != "" && e.Region != filterRegion {
+			continue
+		}
+		if source != "" && e.Source != source {
+			continue
+		}
+		out = append(out, e)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"version":   version,
+		"countries": out,
+		"issues":    h.reg.CountryIssues(),
+	})
+}
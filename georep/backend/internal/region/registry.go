@@ -0,0 +1,300 @@
+package region
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"geo-repl-demo/internal/config"
+	"geo-repl-demo/internal/geo"
+)
+
+// Region is a routing region resolved against the topology.
+type Region struct {
+	ID      string   `json:"id"`
+	Label   string   `json:"label"`
+	Aliases []string `json:"aliases,omitempty"`
+	Node    string   `json:"node"`
+	Master  bool     `json:"master"`
+	// Timezone is the IANA zone for region-local times ("" = UTC).
+	Timezone string `json:"timezone,omitempty"`
+	// Locale is the language served when a reader's preferences match no
+	// translation.
+	Locale string `json:"locale"`
+}
+
+// Location returns the region's time zone, UTC when unset. Zones are
+// validated when the topology is loaded.
+func (r Region) Location() *time.Location {
+	loc, err := time.LoadLocation(r.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Registry is the single source of truth for region IDs, aliases, labels,
+// the default region and which node serves each region. The routing table
+// can be swapped at runtime with Replace; readers always see a consistent
+// snapshot.
+type Registry struct {
+	cur atomic.Pointer[table]
+}
+
+type table struct {
+	regions   []Region
+	byKey     map[string]int
+	nodes     map[string]config.Node
+	master    config.Node
+	def       string
+	locale    string
+	countries *countryTable
+}
+
+// New builds a registry from the topology and the country map (nil selects
+// the embedded default map). When the topology has no explicit regions
+// section, every node contributes one region named after its region.
+// Country map errors fail the build; warnings are kept for inspection.
+func New(t config.Topology, countries *CountryMap) (*Registry, error) {
+	tbl, err := build(t)
+	if err != nil {
+		return nil, err
+	}
+	if countries == nil {
+		if countries, err = LoadCountryMap(""); err != nil {
+			return nil, err
+		}
+	}
+	tbl.countries = compile(countries, tbl)
+	if tbl.countries.hasErrors() {
+		var msgs []string
+		for _, is := range tbl.countries.issues {
+			if is.Severity == "error" {
+				msgs = append(msgs, is.Message)
+			}
+		}
+		return nil, fmt.Errorf("country map: %s", strings.Join(msgs, "; "))
+	}
+	r := &Registry{}
+	r.cur.Store(tbl)
+	return r, nil
+}
+
+// Replace atomically switches r to the routing table of next.
+func (r *Registry) Replace(next *Registry) {
+	r.cur.Store(next.cur.Load())
+}
+
+func build(t config.Topology) (*table, error) {
+	r := &table{
+		byKey:  map[string]int{},
+		nodes:  map[string]config.Node{},
+		master: t.Master,
+		locale: t.DefaultLocale,
+	}
+	for _, n := range t.Nodes() {
+		r.nodes[n.Name] = n
+	}
+
+	specs := t.Regions
+	if len(specs) == 0 {
+		for _, n := range t.Nodes() {
+			specs = append(specs, config.Region{ID: n.Region, Label: n.Label, Node: n.Name})
+		}
+	}
+
+	for _, spec := range specs {
+		reg := Region{
+			ID:       normalize(spec.ID),
+			Label:    spec.Label,
+			Node:     spec.Node,
+			Timezone: spec.Timezone,
+			Locale:   spec.Locale,
+		}
+		if reg.Locale == "" {
+			reg.Locale = t.DefaultLocale
+		}
+		if reg.ID == "" {
+			return nil, fmt.Errorf("region id is required")
+		}
+		node, ok := r.nodes[reg.Node]
+		if !ok {
+			return nil, fmt.Errorf("region %q: unknown node %q", reg.ID, reg.Node)
+		}
+		reg.Master = node.Name == t.Master.Name
+		if reg.Label == "" {
+			reg.Label = strings.ToUpper(reg.ID)
+		}
+		for _, a := range spec.Aliases {
+			if a = normalize(a); a != "" && a != reg.ID {
+				reg.Aliases = append(reg.Aliases, a)
+			}
+		}
+
+		idx := len(r.regions)
+		for _, key := range append([]string{reg.ID}, reg.Aliases...) {
+			if prev, dup := r.byKey[key]; dup {
+				return nil, fmt.Errorf("region key %q used by both %q and %q", key, r.regions[prev].ID, reg.ID)
+			}
+			r.byKey[key] = idx
+		}
+		r.regions = append(r.regions, reg)
+	}
+
+	r.def = normalize(t.DefaultRegion)
+	if r.def == "" {
+		r.def = t.Master.Region
+	}
+	def, ok := r.lookup(r.def)
+	if !ok {
+		return nil, fmt.Errorf("default region %q is not defined", r.def)
+	}
+	r.def = def.ID
+
+	return r, nil
+}
+
+func (t *table) lookup(name string) (Region, bool) {
+	idx, ok := t.byKey[normalize(name)]
+	if !ok {
+		return Region{}, false
+	}
+	return t.regions[idx], true
+}
+
+func (t *table) resolve(name string) Region {
+	if reg, ok := t.lookup(name); ok {
+		return reg
+	}
+	reg, _ := t.lookup(t.def)
+	return reg
+}
+
+// Lookup resolves a region ID or alias (case-insensitive).
+func (r *Registry) Lookup(name string) (Region, bool) {
+	return r.cur.Load().lookup(name)
+}
+
+// Resolve is like Lookup but falls back to the default region.
+func (r *Registry) Resolve(name string) Region {
+	return r.cur.Load().resolve(name)
+}
+
+// Default returns the region used when nothing better is known.
+func (r *Registry) Default() Region {
+	t := r.cur.Load()
+	return t.resolve(t.def)
+}
+
+// DefaultLocale returns the topology-wide default locale, used for new
+// articles and regions without a locale of their own.
+func (r *Registry) DefaultLocale() string {
+	return r.cur.Load().locale
+}
+
+// Regions returns all regions sorted by ID.
+func (r *Registry) Regions() []Region {
+	t := r.cur.Load()
+	out := make([]Region, len(t.regions))
+	copy(out, t.regions)
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Master returns the master node.
+func (r *Registry) Master() config.Node {
+	return r.cur.Load().master
+}
+
+// Node returns the node with the given name.
+func (r *Registry) Node(name string) (config.Node, bool) {
+	n, ok := r.cur.Load().nodes[name]
+	return n, ok
+}
+
+// NodeFor returns the node serving the given region (or alias).
+// Unknown regions are served by the default region's node.
+func (r *Registry) NodeFor(name string) config.Node {
+	t := r.cur.Load()
+	return t.nodes[t.resolve(name).Node]
+}
+
+// RegionForCountry maps an ISO country code to its region. The continent
+// code (as reported by GeoIP) is optional; without it the embedded ISO table
+// is consulted.
+func (r *Registry) RegionForCountry(iso, continent string) Region {
+	t := r.cur.Load()
+	id, _ := t.countries.resolve(iso, continent, t.def)
+	return t.resolve(id)
+}
+
+// CountryMapping returns the map version and the effective region of every
+// known country.
+func (r *Registry) CountryMapping() (string, []MappingEntry) {
+	t := r.cur.Load()
+	out := make([]MappingEntry, 0, len(countries))
+	for _, c := range countries {
+		id, source := t.countries.resolve(c.Code, c.Continent, t.def)
+		out = append(out, MappingEntry{Country: c, Region: id, Source: source})
+	}
+	return t.countries.version, out
+}
+
+// CountryIssues returns the validation warnings of the active country map.
+func (r *Registry) CountryIssues() []Issue {
+	return r.cur.Load().countries.issues
+}
+
+// RegionForNode returns the primary region served by the node: the region
+// whose ID matches the node's own region, otherwise the first assigned one.
+func (r *Registry) RegionForNode(name string) (Region, bool) {
+	t := r.cur.Load()
+	var found Region
+	ok := false
+	for _, reg := range t.regions {
+		if reg.Node != name {
+			continue
+		}
+		if reg.ID == t.nodes[name].Region {
+			return reg, true
+		}
+		if !ok {
+			found, ok = reg, true
+		}
+	}
+	return found, ok
+}
+
+// Nearest returns the node closest to p by great-circle distance among the
+// nodes accepted by healthy (nil accepts all), and the distance in km.
+// Only nodes that serve at least one region are considered.
+func (r *Registry) Nearest(p geo.Point, healthy func(config.Node) bool) (config.Node, float64, bool) {
+	t := r.cur.Load()
+	var (
+		best  config.Node
+		bestD = math.Inf(1)
+		found bool
+	)
+	seen := map[string]bool{}
+	for _, reg := range t.regions {
+		if seen[reg.Node] {
+			continue
+		}
+		seen[reg.Node] = true
+		n := t.nodes[reg.Node]
+		if healthy != nil && !healthy(n) {
+			continue
+		}
+		if d := geo.DistanceKm(p, n.Point()); d < bestD {
+			best, bestD, found = n, d, true
+		}
+	}
+	return best, bestD, found
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
@@ -8,5 +8,5 @@ import (
 // Article replikalara yazılırken kullanılacak arabirim.
 // Böylece replication paketi article paketine bağımlı olmaz.
 type ReplicaWriter interface {
-	CopyToReplica(ctx context.Context, replicaIndex int, a model.Article) error
+	CopyToReplica(ctx context.Context, replica string, a model.Article) error
 }
@@ -2,9 +2,13 @@ package replication
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"sync"
 	"time"
 
+	"geo-repl-demo/internal/blob"
 	"geo-repl-demo/internal/db"
 	"geo-repl-demo/internal/model"
 
@@ -14,6 +18,14 @@ import (
 type Replicator struct {
 	master   *db.Master
 	replicas *db.ReplicaSet
+	blobs    *blob.Stores // nil ise yalnızca ek satırları kopyalanır
+
+	// Şeması garanti edilmiş replika havuzları (*pgxpool.Pool); DDL her
+	// yazmada değil, replika başına bir kez çalışır
+	schemas sync.Map
+	// Replikaya kadar kopyalanmış son yorumun master'daki (merged_at, id)
+	// değeri (*pgxpool.Pool → commentMark); bkz. syncComments
+	commentMarks sync.Map
 }
 
 // Constructor
@@ -21,50 +33,162 @@ func NewReplicator(master *db.Master, replicas *db.ReplicaSet) *Replicator {
 	return &Replicator{master: master, replicas: replicas}
 }
 
-// Yeni makale eklendiğinde çağrılır
-func (r *Replicator) Schedule(a model.Article) {
+// UseBlobs ek dosyalarının içeriğini node'ların blob store'larına kopyalamayı açar
+func (r *Replicator) UseBlobs(stores *blob.Stores) {
+	r.blobs = stores
+}
+
+// replicationDelay asenkron replikasyon gecikmesini taklit eder (eventual consistency)
+const replicationDelay = 2 * time.Second
+
+// fanOut write'ı her replikada ayrı bir goroutine içinde, gecikmeden sonra
+// çalıştırır. Hata what ile loglanır ve eksik kalanı FullSync tamamlar; done
+// verilmişse başarı da loglanır. Şema burada değil, replika bootstrap
+// edilirken / FullSync'te bir kez garanti edilir (bkz. ensureSchema)
+func (r *Replicator) fanOut(what, done string, timeout time.Duration, write func(ctx context.Context, name string, pool *pgxpool.Pool) error) {
 	if r.replicas == nil {
 		return
 	}
 
-	for i, pool := range r.replicas.Pools {
-		go func(idx int, pool *pgxpool.Pool) {
-			time.Sleep(2 * time.Second) // eventual consistency gecikmesi
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	for _, rep := range r.replicas.All() {
+		go func(name string, pool *pgxpool.Pool) {
+			time.Sleep(replicationDelay)
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
 			defer cancel()
 
-			// Her replikada tabloyu garanti altına al
-			_, _ = pool.Exec(ctx, `
-				CREATE TABLE IF NOT EXISTS articles (
-					id SERIAL PRIMARY KEY,
-					title TEXT NOT NULL,
-					summary TEXT NOT NULL,
-					content_long TEXT NOT NULL,
-					author TEXT NOT NULL,
-					region TEXT NOT NULL,
-					created_at TIMESTAMP DEFAULT NOW()
-				);
-			`)
-
-			_, err := pool.Exec(ctx, `
-				INSERT INTO articles (id, title, summary, content_long, author, region, created_at)
-				VALUES ($1,$2,$3,$4,$5,$6,$7)
-				ON CONFLICT (id) DO UPDATE
-				SET title=EXCLUDED.title,
-					summary=EXCLUDED.summary,
-					content_long=EXCLUDED.content_long,
-					author=EXCLUDED.author,
-					region=EXCLUDED.region,
-					created_at=EXCLUDED.created_at;
-			`, a.ID, a.Title, a.Summary, a.ContentLong, a.Author, a.Region, a.CreatedAt)
-
-			if err != nil {
-				log.Printf("❌ Replikasyon hatası (replica %d): %v", idx+1, err)
-			} else {
-				log.Printf("✅ Article %d kopyalandı → replica %d", a.ID, idx+1)
+			if err := write(ctx, name, pool); err != nil {
+				log.Printf("❌ %s replikasyon hatası (%s): %v", what, name, err)
+			} else if done != "" {
+				log.Printf("✅ %s kopyalandı → %s", done, name)
+			}
+		}(rep.Node.Name, rep.Pool)
+	}
+}
+
+// Yeni makale eklendiğinde çağrılır
+func (r *Replicator) Schedule(a model.Article) {
+	r.fanOut("Makale", fmt.Sprintf("Article %d", a.ID), 10*time.Second, func(ctx context.Context, _ string, pool *pgxpool.Pool) error {
+		return db.UpsertArticle(ctx, pool, a)
+	})
+}
+
+// Toplu içe aktarılan makaleler ve ilk revizyonları için replikasyon: her
+// replikada tek goroutine hepsini sırayla yazar (makale başına goroutine açılmaz).
+// Yazılamayanları FullSync taşır
+func (r *Replicator) ScheduleArticles(as []model.Article, revs []model.ArticleRevision) {
+	if len(as) == 0 {
+		return
+	}
+	r.fanOut("Toplu", fmt.Sprintf("%d makale", len(as)), 2*time.Minute, func(ctx context.Context, _ string, pool *pgxpool.Pool) error {
+		failed := 0
+		for _, a := range as {
+			if err := db.UpsertArticle(ctx, pool, a); err != nil {
+				failed++
+			}
+		}
+		for _, rev := range revs {
+			if err := db.CopyRevision(ctx, pool, rev); err != nil {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d kayıt yazılamadı, tam senkronizasyon tamamlayacak", failed)
+		}
+		return nil
+	})
+}
+
+// Yeni veya güncellenen kullanıcı için (şifre hash'i dahil) replikasyon
+func (r *Replicator) ScheduleUser(u model.User) {
+	r.fanOut("Kullanıcı", fmt.Sprintf("User %d", u.ID), 10*time.Second, func(ctx context.Context, _ string, pool *pgxpool.Pool) error {
+		return db.UpsertUser(ctx, pool, u)
+	})
+}
+
+// Yeni, döndürülen veya iptal edilen API anahtarı için replikasyon; her
+// bölge anahtarları master'a sormadan doğrulayabilsin diye hash'ler de kopyalanır
+func (r *Replicator) ScheduleAPIKey(k model.APIKey) {
+	r.fanOut("API anahtarı", "API key "+k.KeyID, 10*time.Second, func(ctx context.Context, _ string, pool *pgxpool.Pool) error {
+		return db.UpsertAPIKey(ctx, pool, k)
+	})
+}
+
+// Yeni makale revizyonu için replikasyon (revizyonlar değişmez, yalnızca eklenir)
+func (r *Replicator) ScheduleRevision(rev model.ArticleRevision) {
+	r.fanOut("Revizyon", "", 10*time.Second, func(ctx context.Context, _ string, pool *pgxpool.Pool) error {
+		return db.CopyRevision(ctx, pool, rev)
+	})
+}
+
+// Yeni veya yeniden adlandırılan kategori için replikasyon; makalelerin
+// kategori bağlantıları makaleyle birlikte gider
+func (r *Replicator) ScheduleCategory(c model.Category) {
+	r.fanOut("Kategori", "Category "+c.Slug, 10*time.Second, func(ctx context.Context, _ string, pool *pgxpool.Pool) error {
+		return db.UpsertCategory(ctx, pool, c)
+	})
+}
+
+// Master'da birleştirilen yorumların diğer bölgelere dağıtımı. Yorumlar
+// her replikada tek goroutine içinde sırayla (clock düzeninde) yazılır; böylece
+// yanıt, üst yorumundan önce hiçbir replikaya ulaşmaz
+func (r *Replicator) ScheduleComments(cs []model.Comment) {
+	if len(cs) == 0 {
+		return
+	}
+	r.fanOut("Yorum", "", 10*time.Second, func(ctx context.Context, _ string, pool *pgxpool.Pool) error {
+		for _, c := range cs {
+			// Sonrakiler bu yorumun yanıtı olabilir; kalanını FullSync taşır
+			if err := db.CopyComment(ctx, pool, c); err != nil {
+				return err
 			}
-		}(i, pool)
+		}
+		return nil
+	})
+}
+
+// Yeni ek dosyası için replikasyon: önce içerik master'ın store'undan
+// bölgenin store'una kopyalanır, satır ancak ondan sonra yazılır. Böylece
+// replikada görünen her ekin içeriği de o bölgede hazırdır
+func (r *Replicator) ScheduleAttachment(a model.Attachment) {
+	r.fanOut("Ek dosyası", fmt.Sprintf("Attachment %d", a.ID), 60*time.Second, func(ctx context.Context, name string, pool *pgxpool.Pool) error {
+		return r.copyAttachment(ctx, name, pool, a)
+	})
+}
+
+// copyAttachment bir ekin içeriğini ve satırını tek bir replikaya taşır ve
+// kopyayı master'a kaydeder (blob gecikmesi bu kayıtlardan hesaplanır)
+func (r *Replicator) copyAttachment(ctx context.Context, name string, pool *pgxpool.Pool, a model.Attachment) error {
+	if r.blobs != nil {
+		src, err := r.blobs.For(r.master.Node.Name)
+		if err != nil {
+			return err
+		}
+		dst, err := r.blobs.For(name)
+		if err != nil {
+			return err
+		}
+		// Yarım kalmış bir önceki kopya boyutundan anlaşılır ve yenilenir
+		info, err := dst.Stat(ctx, a.Key)
+		switch {
+		case errors.Is(err, blob.ErrNotFound) || err == nil && info.Size != a.Size:
+			if err := blob.Copy(ctx, dst, src, a.Key, a.SHA256); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		}
+	}
+	if err := db.CopyAttachment(ctx, pool, a); err != nil {
+		return err
 	}
+	return db.RecordBlobCopy(ctx, r.master.Pool, a.ID, name)
+}
+
+// Yeni audit kaydı için replikasyon (kayıtlar değişmez, yalnızca eklenir)
+func (r *Replicator) ScheduleAudit(e model.AuditEntry) {
+	r.fanOut("Audit", "", 10*time.Second, func(ctx context.Context, _ string, pool *pgxpool.Pool) error {
+		return db.CopyAudit(ctx, pool, e)
+	})
 }
 
 // Periyodik tam senkronizasyon (Master → tüm replikalar)
@@ -76,54 +200,474 @@ func (r *Replicator) FullSync() {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	rows, err := r.master.Pool.Query(ctx, `
-		SELECT id, title, summary, content_long, author, region, created_at
-		FROM articles
-	`)
+	snap, err := r.masterSnapshot(ctx)
 	if err != nil {
 		log.Printf("⚠️ Master verilerini okuma hatası: %v", err)
 		return
 	}
+
+	for _, rep := range r.replicas.All() {
+		// Açılışta ulaşılamayan replikanın şeması ilk erişilebildiği turda kurulur
+		if err := r.ensureSchema(ctx, rep.Pool); err != nil {
+			log.Printf("⚠️ Şema güncellenemedi (%s): %v", rep.Node.Name, err)
+			continue
+		}
+		if failed := r.syncPool(ctx, rep.Node.Name, rep.Pool, snap); failed > 0 {
+			// Replika veritabanı sıfırlanmış olabilir: şema sonraki turda yeniden kontrol edilir
+			r.schemas.Delete(rep.Pool)
+			r.commentMarks.Delete(rep.Pool)
+		}
+		log.Printf("✅ FullSync: %s güncellendi (%d makale, %d kullanıcı)", rep.Node.Name, len(snap.articles), len(snap.users))
+	}
+}
+
+// ensureSchema replikanın tablolarını (yeni kolonlar dahil) havuz başına bir
+// kez oluşturur / günceller. DSN'i değişen replika yeni havuzla yeniden kurulur
+func (r *Replicator) ensureSchema(ctx context.Context, pool *pgxpool.Pool) error {
+	if _, ok := r.schemas.Load(pool); ok {
+		return nil
+	}
+	if err := db.EnsureReplicaSchema(ctx, pool); err != nil {
+		return err
+	}
+	r.schemas.Store(pool, struct{}{})
+	return nil
+}
+
+// Topolojiye yeni eklenen replikayı okuma almadan önce hazırlar
+func (r *Replicator) Bootstrap(ctx context.Context, rep *db.Replica) error {
+	if err := rep.Pool.Ping(ctx); err != nil {
+		return err
+	}
+	if err := r.ensureSchema(ctx, rep.Pool); err != nil {
+		return fmt.Errorf("şema: %w", err)
+	}
+	snap, err := r.masterSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+	r.commentMarks.Delete(rep.Pool)
+	if failed := r.syncPool(ctx, rep.Node.Name, rep.Pool, snap); failed > 0 {
+		return fmt.Errorf("%d kayıt kopyalanamadı", failed)
+	}
+	// syncPool revizyonların yalnızca bir partisini kopyalar; yeni replika tümünü alır
+	for {
+		copied, failed := r.syncRevisions(ctx, rep.Node.Name, rep.Pool)
+		if failed > 0 {
+			return fmt.Errorf("%d revizyon kopyalanamadı", failed)
+		}
+		if copied < revisionBatch {
+			break
+		}
+	}
+	for {
+		advanced, failed := r.syncComments(ctx, rep.Node.Name, rep.Pool)
+		if failed > 0 {
+			return fmt.Errorf("%d yorum kopyalanamadı", failed)
+		}
+		if advanced < commentBatch {
+			break
+		}
+	}
+	log.Printf("🆕 Bootstrap: %s hazır (%d makale, %d kullanıcı)", rep.Node.Name, len(snap.articles), len(snap.users))
+	return nil
+}
+
+// snapshot master'daki replike edilen tabloların anlık kopyasıdır
+type snapshot struct {
+	articles    []model.Article
+	users       []model.User
+	apiKeys     []model.APIKey
+	categories  []model.Category
+	attachments []model.Attachment
+}
+
+func (r *Replicator) masterSnapshot(ctx context.Context) (snapshot, error) {
+	var snap snapshot
+	var err error
+	if snap.articles, err = r.masterArticles(ctx); err != nil {
+		return snap, err
+	}
+	if snap.users, err = r.masterUsers(ctx); err != nil {
+		return snap, err
+	}
+	if snap.apiKeys, err = r.masterAPIKeys(ctx); err != nil {
+		return snap, err
+	}
+	if snap.categories, err = r.masterCategories(ctx); err != nil {
+		return snap, err
+	}
+	if snap.attachments, err = r.masterAttachments(ctx); err != nil {
+		return snap, err
+	}
+	return snap, nil
+}
+
+func (r *Replicator) masterArticles(ctx context.Context) ([]model.Article, error) {
+	rows, err := r.master.Pool.Query(ctx, `SELECT `+db.ArticleColumns+` FROM articles`)
+	if err != nil {
+		return nil, err
+	}
 	defer rows.Close()
 
 	var articles []model.Article
 	for rows.Next() {
 		var a model.Article
-		if err := rows.Scan(&a.ID, &a.Title, &a.Summary, &a.ContentLong, &a.Author, &a.Region, &a.CreatedAt); err == nil {
+		if err := db.ScanArticle(rows, &a); err == nil {
 			articles = append(articles, a)
 		}
 	}
+	return articles, rows.Err()
+}
 
-	for i, pool := range r.replicas.Pools {
-		// Her replikada tabloyu garanti et
-		_, _ = pool.Exec(ctx, `
-			CREATE TABLE IF NOT EXISTS articles (
-				id SERIAL PRIMARY KEY,
-				title TEXT NOT NULL,
-				summary TEXT NOT NULL,
-				content_long TEXT NOT NULL,
-				author TEXT NOT NULL,
-				region TEXT NOT NULL,
-				created_at TIMESTAMP DEFAULT NOW()
-			);
-		`)
-
-		for _, a := range articles {
-			_, err := pool.Exec(ctx, `
-				INSERT INTO articles (id, title, summary, content_long, author, region, created_at)
-				VALUES ($1,$2,$3,$4,$5,$6,$7)
-				ON CONFLICT (id) DO UPDATE SET
-					title=EXCLUDED.title,
-					summary=EXCLUDED.summary,
-					content_long=EXCLUDED.content_long,
-					author=EXCLUDED.author,
-					region=EXCLUDED.region,
-					created_at=EXCLUDED.created_at
-			`, a.ID, a.Title, a.Summary, a.ContentLong, a.Author, a.Region, a.CreatedAt)
-			if err != nil {
-				log.Printf("⚠️ FullSync hata (replica %d): %v", i+1, err)
-			}
+func (r *Replicator) masterUsers(ctx context.Context) ([]model.User, error) {
+	rows, err := r.master.Pool.Query(ctx, `SELECT `+db.UserColumns+` FROM users`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []model.User
+	for rows.Next() {
+		var u model.User
+		if err := db.ScanUser(rows, &u); err == nil {
+			users = append(users, u)
+		}
+	}
+	return users, rows.Err()
+}
+
+func (r *Replicator) masterAPIKeys(ctx context.Context) ([]model.APIKey, error) {
+	rows, err := r.master.Pool.Query(ctx, `SELECT `+db.APIKeyColumns+` FROM api_keys`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []model.APIKey
+	for rows.Next() {
+		var k model.APIKey
+		if err := db.ScanAPIKey(rows, &k); err == nil {
+			keys = append(keys, k)
+		}
+	}
+	return keys, rows.Err()
+}
+
+func (r *Replicator) masterCategories(ctx context.Context) ([]model.Category, error) {
+	rows, err := r.master.Pool.Query(ctx, `SELECT `+db.CategoryColumns+` FROM categories`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cats []model.Category
+	for rows.Next() {
+		var c model.Category
+		if err := db.ScanCategory(rows, &c); err == nil {
+			cats = append(cats, c)
+		}
+	}
+	return cats, rows.Err()
+}
+
+// masterAttachments master'daki ek kayıtlarını id sırasıyla okur.
+func (r *Replicator) masterAttachments(ctx context.Context) ([]model.Attachment, error) {
+	rows, err := r.master.Pool.Query(ctx, `SELECT `+db.AttachmentColumns+` FROM attachments ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var as []model.Attachment
+	for rows.Next() {
+		var a model.Attachment
+		if err := db.ScanAttachment(rows, &a); err == nil {
+			as = append(as, a)
+		}
+	}
+	return as, rows.Err()
+}
+
+// Tek bir replikayı master içeriğiyle eşitler; başarısız satır sayısını döner
+// (şema çağıran tarafından garanti edilmiş olmalıdır)
+func (r *Replicator) syncPool(ctx context.Context, name string, pool *pgxpool.Pool, snap snapshot) int {
+	failed := 0
+	for _, a := range snap.articles {
+		err := db.UpsertArticle(ctx, pool, a)
+		if err != nil {
+			failed++
+			log.Printf("⚠️ FullSync hata (%s): %v", name, err)
+		}
+	}
+	_, revFailed := r.syncRevisions(ctx, name, pool)
+	failed += revFailed
+	for _, u := range snap.users {
+		if err := db.UpsertUser(ctx, pool, u); err != nil {
+			failed++
+			log.Printf("⚠️ FullSync kullanıcı hatası (%s): %v", name, err)
+		}
+	}
+	for _, k := range snap.apiKeys {
+		if err := db.UpsertAPIKey(ctx, pool, k); err != nil {
+			failed++
+			log.Printf("⚠️ FullSync API anahtarı hatası (%s): %v", name, err)
+		}
+	}
+	for _, c := range snap.categories {
+		if err := db.UpsertCategory(ctx, pool, c); err != nil {
+			failed++
+			log.Printf("⚠️ FullSync kategori hatası (%s): %v", name, err)
+		}
+	}
+	_, commentFailed := r.syncComments(ctx, name, pool)
+	failed += commentFailed
+	failed += r.syncAttachments(ctx, name, pool, snap.attachments)
+	failed += r.syncAudit(ctx, name, pool)
+	return failed
+}
+
+// blobBatch bounds how many blobs one sync round copies to a node; the rest
+// follow in the next rounds.
+const blobBatch = 50
+
+// syncAttachments copies attachments whose contents the master has not yet
+// recorded for the node; rows of copied ones are rewritten as usual.
+func (r *Replicator) syncAttachments(ctx context.Context, name string, pool *pgxpool.Pool, as []model.Attachment) int {
+	if len(as) == 0 {
+		return 0
+	}
+	rows, err := r.master.Pool.Query(ctx, `SELECT attachment_id FROM attachment_copies WHERE node=$1`, name)
+	if err != nil {
+		log.Printf("⚠️ Blob kopyaları okunamadı (%s): %v", name, err)
+		return 1
+	}
+	copied := map[int64]bool{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err == nil {
+			copied[id] = true
+		}
+	}
+	rows.Close()
+
+	failed, budget := 0, blobBatch
+	for _, a := range as {
+		var err error
+		switch {
+		case copied[a.ID]:
+			err = db.CopyAttachment(ctx, pool, a)
+		case budget > 0:
+			budget--
+			err = r.copyAttachment(ctx, name, pool, a)
+		default:
+			continue
+		}
+		if err != nil {
+			failed++
+			log.Printf("⚠️ FullSync ek dosyası hatası (%s): %v", name, err)
+		}
+	}
+	return failed
+}
+
+// firstGap returns the key after which the replica's copy of an append-only
+// table first differs from the master's, up to upTo. key is a column both
+// copies share, such as audit_log.id. Both hold the same keys when
+// complete, so counts up to a key agree exactly below the first gap; a
+// binary search finds it in O(log n) queries.
+func (r *Replicator) firstGap(ctx context.Context, pool *pgxpool.Pool, table, key string, upTo int64) (int64, error) {
+	count := func(p *pgxpool.Pool, k int64) (int64, error) {
+		var n int64
+		err := p.QueryRow(ctx, `SELECT COUNT(*) FROM `+table+` WHERE `+key+` <= $1`, k).Scan(&n)
+		return n, err
+	}
+	same := func(id int64) (bool, error) {
+		m, err := count(r.master.Pool, id)
+		if err != nil {
+			return false, err
+		}
+		rc, err := count(pool, id)
+		return m == rc, err
+	}
+
+	ok, err := same(upTo)
+	if err != nil || ok {
+		return upTo, err
+	}
+	lo, hi := int64(0), upTo // same(lo) true, same(hi) false
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		ok, err := same(mid)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
+
+// auditBatch bounds how many audit entries one sync round copies.
+const auditBatch = 1000
+
+// syncAudit copies audit entries the replica is missing. The log only grows,
+// so instead of a full snapshot it continues after the replica's highest ID.
+// Entries below it that ScheduleAudit failed to copy are found by comparing
+// row counts and copied in the same way.
+func (r *Replicator) syncAudit(ctx context.Context, name string, pool *pgxpool.Pool) int {
+	var replicaMax int64
+	if err := pool.QueryRow(ctx, `SELECT COALESCE(MAX(id), 0) FROM audit_log`).Scan(&replicaMax); err != nil {
+		log.Printf("⚠️ Audit senkronizasyonu okunamadı (%s): %v", name, err)
+		return 1
+	}
+	from, err := r.firstGap(ctx, pool, "audit_log", "id", replicaMax)
+	if err != nil {
+		log.Printf("⚠️ Audit senkronizasyonu okunamadı (%s): %v", name, err)
+		return 1
+	}
+
+	rows, err := r.master.Pool.Query(ctx, `
+		SELECT `+db.AuditColumns+` FROM audit_log
+		WHERE id > $1 ORDER BY id LIMIT $2`, from, auditBatch)
+	if err != nil {
+		log.Printf("⚠️ Audit kayıtları okunamadı (master): %v", err)
+		return 1
+	}
+	var entries []model.AuditEntry
+	for rows.Next() {
+		var e model.AuditEntry
+		if err := db.ScanAudit(rows, &e); err == nil {
+			entries = append(entries, e)
+		}
+	}
+	rows.Close()
+
+	failed := 0
+	for _, e := range entries {
+		if err := db.CopyAudit(ctx, pool, e); err != nil {
+			failed++
+			log.Printf("⚠️ FullSync audit hatası (%s): %v", name, err)
+		}
+	}
+	return failed
+}
+
+// revisionBatch bounds how many revisions one sync round copies.
+const revisionBatch = 1000
+
+// syncRevisions copies revisions the replica is missing and returns how many
+// it copied and how many failed. Revisions are only added, so like syncAudit
+// it continues after the replica's highest seq instead of recopying the
+// table; revisions ScheduleRevision failed to copy are found by firstGap.
+func (r *Replicator) syncRevisions(ctx context.Context, name string, pool *pgxpool.Pool) (int, int) {
+	var replicaMax int64
+	if err := pool.QueryRow(ctx, `SELECT COALESCE(MAX(seq), 0) FROM article_revisions`).Scan(&replicaMax); err != nil {
+		log.Printf("⚠️ Revizyon senkronizasyonu okunamadı (%s): %v", name, err)
+		return 0, 1
+	}
+	from, err := r.firstGap(ctx, pool, "article_revisions", "seq", replicaMax)
+	if err != nil {
+		log.Printf("⚠️ Revizyon senkronizasyonu okunamadı (%s): %v", name, err)
+		return 0, 1
+	}
+
+	rows, err := r.master.Pool.Query(ctx, `
+		SELECT `+db.RevisionColumns+` FROM article_revisions
+		WHERE seq > $1 ORDER BY seq LIMIT $2`, from, revisionBatch)
+	if err != nil {
+		log.Printf("⚠️ Revizyonlar okunamadı (master): %v", err)
+		return 0, 1
+	}
+	var revs []model.ArticleRevision
+	for rows.Next() {
+		var rev model.ArticleRevision
+		if err := db.ScanRevision(rows, &rev); err == nil {
+			revs = append(revs, rev)
+		}
+	}
+	rows.Close()
+
+	copied, failed := 0, 0
+	for _, rev := range revs {
+		if err := db.CopyRevision(ctx, pool, rev); err != nil {
+			failed++
+			log.Printf("⚠️ FullSync revizyon hatası (%s): %v", name, err)
+			continue
+		}
+		copied++
+	}
+	return copied, failed
+}
+
+// commentMark is the master's (merged_at, id) of the last comment a replica
+// is known to have. The replica's own merged_at values can't serve as the
+// mark: the origin node stamps its copies with its local clock.
+type commentMark struct {
+	at time.Time
+	id string
+}
+
+// commentBatch bounds how many comments one sync round copies.
+const commentBatch = 1000
+
+// commentSettle is how old a merged comment must be before the mark passes
+// it. merged_at is the start of the merging transaction, so a slower merge
+// can commit an older merged_at after a newer one is already visible.
+const commentSettle = time.Minute
+
+// syncComments copies merged comments after the replica's mark in
+// (merged_at, id) order and returns how far the mark advanced and how many
+// copies failed. Comments merged within commentSettle are copied again in
+// later rounds until the mark passes them; copying is idempotent. The mark
+// lives in memory, so after a restart each replica is walked once from the
+// start.
+func (r *Replicator) syncComments(ctx context.Context, name string, pool *pgxpool.Pool) (int, int) {
+	var mark commentMark
+	if v, ok := r.commentMarks.Load(pool); ok {
+		mark = v.(commentMark)
+	}
+
+	var settled time.Time
+	if err := r.master.Pool.QueryRow(ctx, `SELECT NOW()`).Scan(&settled); err != nil {
+		log.Printf("⚠️ Yorumlar okunamadı (master): %v", err)
+		return 0, 1
+	}
+	settled = settled.Add(-commentSettle)
+
+	rows, err := r.master.Pool.Query(ctx, `
+		SELECT `+db.CommentColumns+` FROM comments
+		WHERE merged_at IS NOT NULL AND (merged_at, id) > ($1, $2)
+		ORDER BY merged_at, id LIMIT $3`, mark.at, mark.id, commentBatch)
+	if err != nil {
+		log.Printf("⚠️ Yorumlar okunamadı (master): %v", err)
+		return 0, 1
+	}
+	var cs []model.Comment
+	for rows.Next() {
+		var c model.Comment
+		if err := db.ScanComment(rows, &c); err == nil {
+			cs = append(cs, c)
+		}
+	}
+	rows.Close()
+
+	advanced := 0
+	for _, c := range cs {
+		// Sonrakiler bu yorumun yanıtı olabilir: ilk hatada durulur
+		if err := db.CopyComment(ctx, pool, c); err != nil {
+			log.Printf("⚠️ FullSync yorum hatası (%s): %v", name, err)
+			r.commentMarks.Store(pool, mark)
+			return advanced, 1
+		}
+		if c.MergedAt.Before(settled) {
+			mark = commentMark{at: *c.MergedAt, id: c.ID}
+			advanced++
 		}
-		log.Printf("✅ FullSync: replica %d güncellendi (%d makale)", i+1, len(articles))
 	}
+	r.commentMarks.Store(pool, mark)
+	return advanced, 0
 }
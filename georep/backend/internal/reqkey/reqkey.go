@@ -0,0 +1,8 @@
+// Package reqkey names gin context keys that are set by the request
+// middleware and read by packages the middleware itself depends on, such
+// as audit.
+package reqkey
+
+// ClientAddr is the context key holding the resolved client address, even
+// when the region was overridden.
+const ClientAddr = "client_addr"
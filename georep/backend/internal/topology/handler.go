@@ -0,0 +1,60 @@
+package topology
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"geo-repl-demo/internal/audit"
+	"geo-repl-demo/internal/auth"
+)
+
+// Handler exposes the active topology and a manual reload trigger.
+type Handler struct {
+	reloader *Reloader
+	audit    audit.Sink
+}
+
+func NewHandler(reloader *Reloader, sink audit.Sink) *Handler {
+	return &Handler{reloader: reloader, audit: sink}
+}
+
+// RegisterRoutes mounts the topology routes on a Gin router (admin only).
+func RegisterRoutes(r *gin.Engine, h *Handler) {
+	api := r.Group("/api")
+	{
+		api.GET("/topology", auth.Require(auth.PermAdmin), h.get)
+		api.POST("/topology/reload", auth.Require(auth.PermAdmin), h.reload)
+	}
+}
+
+func (h *Handler) get(c *gin.Context) {
+	topo, loadedAt := h.reloader.Current()
+	c.JSON(http.StatusOK, gin.H{
+		"topology":  topo,
+		"loaded_at": loadedAt.Format(time.RFC3339),
+	})
+}
+
+func (h *Handler) reload(c *gin.Context) {
+	ctx := c.Request.Context()
+	before, _ := h.reloader.Current()
+	err := h.reloader.Reload(ctx)
+	after, _ := h.reloader.Current()
+
+	p, _ := auth.FromContext(c)
+	e := p.AuditEvent(ctx, "topology.reload", "topology", "")
+	e.BeforeHash, e.AfterHash = audit.Hash(before), audit.Hash(after)
+	if err != nil {
+		e.Outcome = audit.Failed
+		e.Detail = map[string]string{"error": err.Error()}
+	}
+	h.audit.Record(ctx, e)
+
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	h.get(c)
+}
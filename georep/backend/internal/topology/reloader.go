@@ -0,0 +1,185 @@
+package topology
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"geo-repl-demo/internal/config"
+	"geo-repl-demo/internal/db"
+	"geo-repl-demo/internal/region"
+)
+
+// Reloader re-reads the topology file and applies it to the running
+// process: new replicas are connected and bootstrapped, the region routing
+// table is swapped atomically, and removed replicas are drained.
+type Reloader struct {
+	path       string
+	regions    *region.Registry
+	replicas   *db.ReplicaSet
+	bootstrap  db.BootstrapFunc
+	drainGrace time.Duration
+	listeners  []func(config.Topology)
+
+	mu       sync.Mutex
+	current  config.Topology
+	checksum []byte
+	loadedAt time.Time
+}
+
+// NewReloader creates a reloader for the topology that is currently active.
+func NewReloader(path string, current config.Topology, regions *region.Registry, replicas *db.ReplicaSet, bootstrap db.BootstrapFunc) *Reloader {
+	r := &Reloader{
+		path:       path,
+		regions:    regions,
+		replicas:   replicas,
+		bootstrap:  bootstrap,
+		drainGrace: 30 * time.Second,
+		current:    current,
+		loadedAt:   time.Now(),
+	}
+	if _, sum, err := r.read(); err == nil {
+		r.checksum = sum
+	}
+	return r
+}
+
+// OnReload registers fn to be called with every topology that has been
+// applied successfully. Register listeners before starting Watch.
+func (r *Reloader) OnReload(fn func(config.Topology)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.listeners = append(r.listeners, fn)
+}
+
+// Current returns the active topology and when it was loaded.
+func (r *Reloader) Current() (config.Topology, time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.current, r.loadedAt
+}
+
+// Reload reads the topology file and applies it. An invalid file leaves the
+// running topology untouched.
+func (r *Reloader) Reload(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, sum, err := r.read()
+	if err != nil {
+		return err
+	}
+	return r.apply(ctx, next, sum)
+}
+
+// read parses the topology file and returns it together with a checksum
+// over the topology and the country map it references.
+func (r *Reloader) read() (config.Topology, []byte, error) {
+	raw, err := os.ReadFile(r.path)
+	if err != nil {
+		return config.Topology{}, nil, err
+	}
+	h := sha256.New()
+	h.Write(raw)
+
+	next, err := config.ParseTopology(raw)
+	if err != nil {
+		return config.Topology{}, h.Sum(nil), err
+	}
+	next.ResolvePaths(filepath.Dir(r.path))
+	if next.CountryMap != "" {
+		if cm, err := os.ReadFile(next.CountryMap); err == nil {
+			h.Write(cm)
+		}
+	}
+	return next, h.Sum(nil), nil
+}
+
+func (r *Reloader) apply(ctx context.Context, next config.Topology, sum []byte) error {
+	if next.Master.DSN != r.current.Master.DSN {
+		return fmt.Errorf("master değişikliği hot-reload ile desteklenmiyor, yeniden başlatma gerekli")
+	}
+	countries, err := region.LoadCountryMap(next.CountryMap)
+	if err != nil {
+		return err
+	}
+	nextRegions, err := region.New(next, countries)
+	if err != nil {
+		return err
+	}
+
+	// 1) Yeni replikalar bağlanır ve bootstrap edilir (hazır olana kadar okuma almaz)
+	removed, applyErr := r.replicas.Apply(ctx, next.Replicas, r.bootstrap)
+
+	// 2) Yönlendirme tablosu atomik olarak değiştirilir
+	r.regions.Replace(nextRegions)
+
+	// 3) Kaldırılan replikalar, üzerlerindeki istekler bitince kapatılır
+	db.Drain(removed, r.drainGrace)
+
+	// Bootstrap edilemeyen replikanın bölgesi şimdilik master'dan okur. Topoloji
+	// uygulanmış sayılmaz: sonraki tur (ya da SIGHUP) bootstrap'i yeniden dener
+	if applyErr != nil {
+		return fmt.Errorf("%w: %w", errIncomplete, applyErr)
+	}
+
+	r.current = next
+	r.checksum = sum
+	r.loadedAt = time.Now()
+	for _, fn := range r.listeners {
+		fn(next)
+	}
+	log.Printf("🗺️ Topoloji yeniden yüklendi: %d replika, %d bölge (%d kaldırıldı)",
+		len(next.Replicas), len(nextRegions.Regions()), len(removed))
+
+	return nil
+}
+
+// errIncomplete marks a topology that was applied except for replicas that
+// could not be bootstrapped; the same file is applied again until they are.
+var errIncomplete = errors.New("topoloji eksik uygulandı")
+
+// Watch polls the topology file and reloads it whenever its content or the
+// country map's changes. The file is reopened by path on every poll, so a
+// file replaced by rename is picked up as long as its directory, not the
+// file itself, is mounted into the container.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		next, sum, err := r.read()
+		if sum == nil {
+			continue
+		}
+
+		r.mu.Lock()
+		if !bytes.Equal(sum, r.checksum) {
+			if err == nil {
+				err = r.apply(ctx, next, sum)
+			}
+			if err != nil {
+				log.Printf("⚠️ Topoloji yeniden yüklenemedi: %v", err)
+				// Aynı hatalı dosyayı her turda tekrar denemeyelim; bootstrap
+				// hatası ise dosyadan değil node'dan gelir ve yeniden denenir
+				if !errors.Is(err, errIncomplete) {
+					r.checksum = sum
+				}
+			}
+		}
+		r.mu.Unlock()
+	}
+}
@@ -0,0 +1,129 @@
+package topology
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"geo-repl-demo/internal/config"
+	"geo-repl-demo/internal/db"
+	"geo-repl-demo/internal/region"
+)
+
+// Havuzlar tembel bağlanır: bu DSN'lere hiç bağlanılmaz
+const baseTopology = `
+master: {name: master, region: eu, lat: 50.11, lon: 8.68, dsn: "postgres://u@127.0.0.1:1/m"}
+replicas:
+  - {name: replica1, region: us, lat: 39.04, lon: -77.49, dsn: "postgres://u@127.0.0.1:1/r1"}
+  - {name: replica2, region: asia, lat: 1.35, lon: 103.82, dsn: "postgres://u@127.0.0.1:1/r2"}
+  - {name: replica3, region: tr, lat: 41.01, lon: 28.98, dsn: "postgres://u@127.0.0.1:1/r3"}
+  - {name: replica4, region: sa, lat: -23.55, lon: -46.63, dsn: "postgres://u@127.0.0.1:1/r4"}
+  - {name: replica5, region: africa, lat: -33.92, lon: 18.42, dsn: "postgres://u@127.0.0.1:1/r5"}
+`
+
+const addedReplica = `  - {name: replica6, region: au, lat: -33.87, lon: 151.21, dsn: "postgres://u@127.0.0.1:1/r6"}
+`
+
+func TestWatchRetriesFailedBootstrap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "topology.yaml")
+	if err := os.WriteFile(path, []byte(baseTopology), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	current, err := config.LoadTopology(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	regions, err := region.New(current, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicas, err := db.NewReplicas(current.Replicas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replicas.Close()
+
+	var attempts atomic.Int32
+	bootstrap := func(context.Context, *db.Replica) error {
+		if attempts.Add(1) < 3 {
+			return errors.New("replica unreachable")
+		}
+		return nil
+	}
+	r := NewReloader(path, current, regions, replicas, bootstrap)
+	applied := make(chan config.Topology, 4)
+	r.OnReload(func(t config.Topology) { applied <- t })
+
+	if err := os.WriteFile(path, []byte(baseTopology+addedReplica), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Watch(ctx, 10*time.Millisecond)
+
+	select {
+	case got := <-applied:
+		if len(got.Replicas) != 6 {
+			t.Errorf("applied %d replicas, want 6", len(got.Replicas))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("topology not applied after %d bootstrap attempts", attempts.Load())
+	}
+	cancel()
+
+	if n := attempts.Load(); n != 3 {
+		t.Errorf("bootstrap attempts = %d, want 3", n)
+	}
+	if topo, _ := r.Current(); len(topo.Replicas) != 6 {
+		t.Errorf("current topology has %d replicas, want 6", len(topo.Replicas))
+	}
+	if len(applied) != 0 {
+		t.Errorf("listeners notified %d extra times", len(applied))
+	}
+}
+
+func TestReloadIncompleteKeepsCurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "topology.yaml")
+	if err := os.WriteFile(path, []byte(baseTopology), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	current, err := config.LoadTopology(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	regions, err := region.New(current, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replicas, err := db.NewReplicas(current.Replicas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replicas.Close()
+
+	r := NewReloader(path, current, regions, replicas, func(context.Context, *db.Replica) error {
+		return errors.New("replica unreachable")
+	})
+	notified := false
+	r.OnReload(func(config.Topology) { notified = true })
+	if err := os.WriteFile(path, []byte(baseTopology+addedReplica), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Reload(context.Background()); !errors.Is(err, errIncomplete) {
+		t.Fatalf("Reload err = %v, want errIncomplete", err)
+	}
+	if topo, _ := r.Current(); len(topo.Replicas) != 5 {
+		t.Errorf("current topology has %d replicas, want the previous 5", len(topo.Replicas))
+	}
+	if notified {
+		t.Error("listeners notified of an incomplete topology")
+	}
+	if len(replicas.All()) != 5 {
+		t.Errorf("replica set has %d replicas, want 5", len(replicas.All()))
+	}
+}
@@ -5,9 +5,199 @@ CREATE TABLE IF NOT EXISTS articles (
     content_long TEXT,
     author TEXT NOT NULL,
     region TEXT NOT NULL,
-    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    allowed_countries TEXT[] NOT NULL DEFAULT '{}',
+    denied_countries TEXT[] NOT NULL DEFAULT '{}',
+    author_id BIGINT,
+    revision INT NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP,
+    status TEXT NOT NULL DEFAULT 'published',
+    publish_at TIMESTAMPTZ,
+    region_publish_at JSONB NOT NULL DEFAULT '{}',
+    locale TEXT NOT NULL DEFAULT 'tr',
+    translations JSONB NOT NULL DEFAULT '{}',
+    content_html TEXT NOT NULL DEFAULT '',
+    toc JSONB NOT NULL DEFAULT '[]'
 );
 
+-- Makale revizyon geçmişi: her sürüm (ve silme kaydı) ayrı satır, yalnızca eklenir
+CREATE TABLE IF NOT EXISTS article_revisions (
+    article_id BIGINT NOT NULL,
+    revision INT NOT NULL,
+    title TEXT NOT NULL,
+    summary TEXT NOT NULL,
+    content_long TEXT NOT NULL,
+    author TEXT NOT NULL,
+    author_id BIGINT NOT NULL DEFAULT 0,
+    region TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    allowed_countries TEXT[] NOT NULL DEFAULT '{}',
+    denied_countries TEXT[] NOT NULL DEFAULT '{}',
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    editor TEXT NOT NULL DEFAULT '',
+    editor_id BIGINT NOT NULL DEFAULT 0,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    status TEXT NOT NULL DEFAULT 'published',
+    publish_at TIMESTAMPTZ,
+    region_publish_at JSONB NOT NULL DEFAULT '{}',
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    categories TEXT[] NOT NULL DEFAULT '{}',
+    locale TEXT NOT NULL DEFAULT 'tr',
+    translations JSONB NOT NULL DEFAULT '{}',
+    content_html TEXT NOT NULL DEFAULT '',
+    toc JSONB NOT NULL DEFAULT '[]',
+    seq BIGSERIAL,
+    PRIMARY KEY (article_id, revision)
+);
+CREATE INDEX IF NOT EXISTS article_revisions_recorded_idx ON article_revisions (recorded_at);
+CREATE INDEX IF NOT EXISTS article_revisions_seq_idx ON article_revisions (seq);
+CREATE INDEX IF NOT EXISTS articles_scheduled_idx ON articles (publish_at) WHERE status = 'scheduled';
+
+-- Saklama süresi dolup kalıcı silinen makaleler ve silmeyi onaylayan replikalar
+CREATE TABLE IF NOT EXISTS article_purges (
+    article_id BIGINT PRIMARY KEY,
+    purged_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    confirmed_at TIMESTAMPTZ,
+    confirmed_replicas TEXT[] NOT NULL DEFAULT '{}'
+);
+
+-- Etiketler serbesttir (ilk kullanımda oluşur), kategoriler editörlerce tanımlanır;
+-- makale bağlantıları makaleyle birlikte replike edilir
+CREATE TABLE IF NOT EXISTS tags (
+    slug TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS categories (
+    slug TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS article_tags (
+    article_id BIGINT NOT NULL,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (article_id, tag)
+);
+CREATE INDEX IF NOT EXISTS article_tags_tag_idx ON article_tags (tag);
+
+CREATE TABLE IF NOT EXISTS article_categories (
+    article_id BIGINT NOT NULL,
+    category TEXT NOT NULL,
+    PRIMARY KEY (article_id, category)
+);
+CREATE INDEX IF NOT EXISTS article_categories_category_idx ON article_categories (category);
+
+-- Yorumlar okuyucunun bölge node'una yazılır, master'da birleştirilip diğer bölgelere dağıtılır
+CREATE TABLE IF NOT EXISTS comments (
+    id TEXT PRIMARY KEY,
+    article_id BIGINT NOT NULL,
+    parent_id TEXT,
+    author_id BIGINT NOT NULL,
+    author TEXT NOT NULL,
+    body TEXT NOT NULL,
+    origin TEXT NOT NULL,
+    region TEXT NOT NULL DEFAULT '',
+    clock BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    merged_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS comments_article_idx ON comments (article_id, clock, id);
+CREATE INDEX IF NOT EXISTS comments_unmerged_idx ON comments (clock, id) WHERE merged_at IS NULL;
+
+-- Ek dosyaları: içerik blob store'da, satır yalnızca üstveri
+CREATE TABLE IF NOT EXISTS attachments (
+    id BIGSERIAL PRIMARY KEY,
+    article_id BIGINT NOT NULL,
+    blob_key TEXT NOT NULL UNIQUE,
+    filename TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 TEXT NOT NULL,
+    uploaded_by BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS attachments_article_idx ON attachments (article_id, id);
+
+-- Hangi blob hangi node'un store'una kopyalandı (blob gecikmesi için)
+CREATE TABLE IF NOT EXISTS attachment_copies (
+    attachment_id BIGINT NOT NULL,
+    node TEXT NOT NULL,
+    copied_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (attachment_id, node)
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    username TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    role TEXT NOT NULL DEFAULT 'reader',
+    home_region TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    display_name TEXT NOT NULL DEFAULT '',
+    oidc_subject TEXT UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    key_id TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    owner_id BIGINT NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    region TEXT NOT NULL,
+    quota_per_hour INT NOT NULL DEFAULT 0,
+    secret_hash TEXT NOT NULL,
+    previous_hash TEXT NOT NULL DEFAULT '',
+    previous_expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMP
+);
+
+-- Denetim kaydı: yalnızca eklenir, güncelleme/silme tetikleyicilerle reddedilir
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    actor TEXT NOT NULL,
+    actor_id BIGINT NOT NULL DEFAULT 0,
+    role TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL,
+    entity TEXT NOT NULL DEFAULT '',
+    target TEXT NOT NULL DEFAULT '',
+    client_ip TEXT NOT NULL DEFAULT '',
+    region TEXT NOT NULL DEFAULT '',
+    outcome TEXT NOT NULL,
+    before_hash TEXT NOT NULL DEFAULT '',
+    after_hash TEXT NOT NULL DEFAULT '',
+    detail JSONB NOT NULL DEFAULT '{}'
+);
+CREATE INDEX IF NOT EXISTS audit_log_entity_idx ON audit_log (entity, target);
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log (actor);
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER audit_log_no_update
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+CREATE OR REPLACE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
+
+INSERT INTO categories (slug, name, description)
+VALUES
+('yazilim', 'Yazılım', 'Yazılım geliştirme ve mühendislik'),
+('yapay-zeka', 'Yapay Zeka', 'Yapay zeka ve uzman sistemler'),
+('altyapi', 'Altyapı', 'Bulut, ağ ve veritabanları')
+ON CONFLICT (slug) DO NOTHING;
+
 INSERT INTO articles (title, summary, content_long, author, region)
 VALUES
 -- 1. Yazılım Mühendisliği
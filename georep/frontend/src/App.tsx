@@ -1,7 +1,13 @@
 import { useEffect, useState } from "react";
 import ReaderPage from "./pages/ReaderPage";
 import WriterPage from "./pages/WriterPage";
-import { Session } from "./types";
+import { apiPost, setAuthToken } from "./api";
+import { Role, Session } from "./types";
+
+type AuthResponse = {
+  user: { username: string; role: Role; home_region: string };
+  access_token: string;
+};
 
 export default function App() {
   const [autoRegion, setAutoRegion] = useState("eu");
@@ -10,8 +16,11 @@ export default function App() {
   const [selectedRegion, setSelectedRegion] = useState("eu");
   const [refreshTrigger, setRefreshTrigger] = useState(0);
   const [showPasswordInput, setShowPasswordInput] = useState(false);
+  const [writerUsername, setWriterUsername] = useState("");
   const [writerPassword, setWriterPassword] = useState("");
+  const [writer, setWriter] = useState<AuthResponse | null>(null);
   const [error, setError] = useState("");
+  const [ssoEnabled, setSsoEnabled] = useState(false);
 
   const API_BASE = "http://localhost:8080/api";
 
@@ -21,6 +30,35 @@ export default function App() {
       .then((d) => {
         if (d.region && d.region !== "unknown") setAutoRegion(d.region);
       });
+    fetch(`${API_BASE}/oidc/config`)
+      .then((r) => r.json())
+      .then((d) => setSsoEnabled(!!d.enabled))
+      .catch(() => setSsoEnabled(false));
+  }, []);
+
+  // SSO dönüşü: /api/oidc/callback token'ları URL fragment'ında gönderir
+  useEffect(() => {
+    if (!window.location.hash) return;
+    const params = new URLSearchParams(window.location.hash.slice(1));
+    window.history.replaceState(null, "", window.location.pathname + window.location.search);
+    if (params.get("error")) {
+      setError(`SSO girişi başarısız: ${params.get("error")}`);
+      return;
+    }
+    const token = params.get("access_token");
+    if (!token) return;
+    const region = params.get("region") || "eu";
+    setAuthToken(token);
+    setWriter({
+      user: {
+        username: params.get("username") || "",
+        role: (params.get("role") as Role) || "reader",
+        home_region: region,
+      },
+      access_token: token,
+    });
+    setManualRegion(region);
+    setMode("writer");
   }, []);
 
   const handleArticleAdded = () => setRefreshTrigger((p) => p + 1);
@@ -30,15 +68,36 @@ export default function App() {
     setError("");
   };
 
-  const handleWriterConfirm = () => {
-    if (writerPassword === "4321") {
+  // /login veya /register → JWT; yazma istekleri bu token ile yapılır.
+  // Kayıt okuyucu hesabı açar, yazar rolünü admin verir.
+  const handleWriterAuth = async (action: "login" | "register") => {
+    try {
+      const body =
+        action === "register"
+          ? { username: writerUsername, password: writerPassword, region: selectedRegion }
+          : { username: writerUsername, password: writerPassword };
+      const res = await apiPost<AuthResponse>(`/${action}`, body);
+      setAuthToken(res.access_token);
+      setWriter(res);
       setManualRegion(selectedRegion);
-      setMode("writer");
-    } else {
-      setError("Yanlış parola! Lütfen tekrar deneyin.");
+      // Okuyucu hesabı yazamaz; okuyucu ekranına geçer
+      setMode(res.user.role === "reader" ? "reader" : "writer");
+      setError("");
+    } catch {
+      setError(
+        action === "login"
+          ? "Kullanıcı adı veya parola hatalı!"
+          : "Kayıt başarısız (kullanıcı adı alınmış veya parola 8 karakterden kısa)."
+      );
     }
   };
 
+  const logout = () => {
+    setAuthToken(null);
+    setWriter(null);
+    setMode("select");
+  };
+
   if (mode === "select") {
     const regions = ["eu", "us", "asia", "sa", "africa"];
     return (
@@ -83,16 +142,35 @@ export default function App() {
         {/* 🔐 Parola alanı sadece Yazar tıklanınca görünür */}
         {showPasswordInput && (
           <div style={passwordBox}>
+            <input
+              placeholder="Kullanıcı adı"
+              value={writerUsername}
+              onChange={(e) => setWriterUsername(e.target.value)}
+              style={passwordInput}
+            />
             <input
               type="password"
-              placeholder="Yazar parolası (örnek: 1234)"
+              placeholder="Parola"
               value={writerPassword}
               onChange={(e) => setWriterPassword(e.target.value)}
               style={passwordInput}
             />
-            <button onClick={handleWriterConfirm} style={button("#3b82f6")}>
-              Giriş
-            </button>
+            <div>
+              <button onClick={() => handleWriterAuth("login")} style={button("#3b82f6")}>
+                Giriş
+              </button>
+              <button onClick={() => handleWriterAuth("register")} style={button("#64748b")}>
+                Kayıt Ol
+              </button>
+            </div>
+            {ssoEnabled && (
+              <button
+                onClick={() => (window.location.href = `${API_BASE}/oidc/login`)}
+                style={button("#0f172a")}
+              >
+                🔑 SSO ile Giriş
+              </button>
+            )}
           </div>
         )}
 
@@ -103,10 +181,10 @@ export default function App() {
 
   const currentRegion = manualRegion || autoRegion;
   const session: Session = {
-    username: "demo",
-    role: mode === "writer" ? "writer" : "reader",
+    username: writer?.user.username || "demo",
+    role: writer?.user.role || "reader",
     region: currentRegion as any,
-    token: "demo-token",
+    token: writer?.access_token || "",
   };
 
   if (mode === "reader")
@@ -122,7 +200,7 @@ export default function App() {
     return (
       <WriterPage
         session={session}
-        onLogout={() => setMode("select")}
+        onLogout={logout}
         onArticleAdded={handleArticleAdded}
       />
     );
@@ -1,6 +1,6 @@
 import React, { useEffect, useState } from "react";
-import { apiGet } from "../api";
-import { Article, ReplicationStatus, Session } from "../types";
+import { apiGet, apiPost, assetUrl } from "../api";
+import { Article, ArticleFacets, Attachment, Comment, ReplicationStatus, Session } from "../types";
 
 type Props = {
   session: Session;
@@ -11,20 +11,98 @@ export default function ReaderPage({ session, onLogout }: Props) {
   const [articles, setArticles] = useState<Article[]>([]);
   const [status, setStatus] = useState<ReplicationStatus[]>([]);
   const [selectedArticle, setSelectedArticle] = useState<Article | null>(null);
+  const [facets, setFacets] = useState<ArticleFacets>({ tags: [], categories: [] });
+  const [tag, setTag] = useState<string | null>(null);
+  const [category, setCategory] = useState<string | null>(null);
+  const [comments, setComments] = useState<Comment[]>([]);
+  const [commentText, setCommentText] = useState("");
+  const [replyTo, setReplyTo] = useState<Comment | null>(null);
+  const [attachments, setAttachments] = useState<Attachment[]>([]);
+  // Boşsa dil tarayıcının Accept-Language başlığıyla seçilir
+  const [lang, setLang] = useState("");
 
   // ⚡ GECİKME VERİSİ
   const [latencyText, setLatencyText] = useState<string | null>(null);
   const [loadingLatency, setLoadingLatency] = useState(false);
 
+  // Seçili etiket / kategori hem listeyi hem facet sayılarını süzer
+  const filterQuery = () => {
+    const q = new URLSearchParams({ region: session.region });
+    if (tag) q.append("tag", tag);
+    if (category) q.append("category", category);
+    if (lang) q.append("lang", lang);
+    return q.toString();
+  };
+
   const loadArticles = async () => {
     try {
-      const data = await apiGet<Article[]>(`/articles?region=${session.region}`);
+      const data = await apiGet<Article[]>(`/articles?${filterQuery()}`);
       setArticles(data || []);
     } catch {
       setArticles([]);
     }
   };
 
+  const loadFacets = async () => {
+    try {
+      setFacets(await apiGet<ArticleFacets>(`/articles/facets?${filterQuery()}`));
+    } catch {
+      setFacets({ tags: [], categories: [] });
+    }
+  };
+
+  const loadComments = async (id: number) => {
+    try {
+      const data = await apiGet<Comment[]>(`/articles/${id}/comments?region=${session.region}`);
+      setComments(data || []);
+    } catch {
+      setComments([]);
+    }
+  };
+
+  const loadAttachments = async (id: number) => {
+    try {
+      const data = await apiGet<Attachment[]>(`/articles/${id}/attachments?region=${session.region}`);
+      setAttachments(data || []);
+    } catch {
+      setAttachments([]);
+    }
+  };
+
+  // Açık makaleyi seçilen dilde yeniden oku; liste de aynı dile geçer
+  const switchLang = async (id: number, l: string) => {
+    setLang(l);
+    try {
+      const q = new URLSearchParams({ region: session.region, lang: l });
+      setSelectedArticle(await apiGet<Article>(`/articles/${id}?${q.toString()}`));
+    } catch {
+      alert("Çeviri yüklenemedi.");
+    }
+  };
+
+  // İçerik isteği de okuyucunun bölgesinden gider (ekin satırı o bölgenin node'undan okunur)
+  const fileUrl = (a: Attachment) => `${assetUrl(a.url)}&region=${session.region}`;
+
+  const sendComment = async (e: React.FormEvent) => {
+    e.preventDefault();
+    if (!selectedArticle) return;
+    try {
+      await apiPost<Comment>(`/articles/${selectedArticle.id}/comments?region=${session.region}`, {
+        body: commentText,
+        parent_id: replyTo?.id,
+      });
+      setCommentText("");
+      setReplyTo(null);
+      await loadComments(selectedArticle.id);
+    } catch {
+      alert("Yorum gönderilemedi.");
+    }
+  };
+
+  // Yanıtın girinti derinliği (liste nedensel sırada: üst yorum her zaman önce gelir)
+  const depthOf = (c: Comment, depths: Record<string, number>) =>
+    (depths[c.id] = c.parent_id ? (depths[c.parent_id] ?? 0) + 1 : 0);
+
   const loadStatus = async () => {
     try {
       const s = await apiGet<ReplicationStatus[]>("/replication-status");
@@ -51,17 +129,33 @@ export default function ReaderPage({ session, onLogout }: Props) {
   };
 
   useEffect(() => {
-    loadArticles();
     loadStatus();
     loadLatency();
+  }, [session.region]);
+
+  useEffect(() => {
+    setComments([]);
+    setReplyTo(null);
+    setAttachments([]);
+    if (!selectedArticle) return;
+    loadComments(selectedArticle.id);
+    loadAttachments(selectedArticle.id);
+    const interval = setInterval(() => loadComments(selectedArticle.id), 3000);
+    return () => clearInterval(interval);
+  }, [selectedArticle?.id, session.region]);
+
+  useEffect(() => {
+    loadArticles();
+    loadFacets();
 
     const interval = setInterval(() => {
       loadArticles();
+      loadFacets();
       loadStatus();
     }, 5000);
 
     return () => clearInterval(interval);
-  }, [session.region]);
+  }, [session.region, tag, category, lang]);
 
   return (
     <section className="card">
@@ -102,6 +196,30 @@ export default function ReaderPage({ session, onLogout }: Props) {
         </button>
       </div>
 
+      {/* 🏷️ Kategori ve etiket süzgeçleri (bölge replikasındaki sayılarla) */}
+      {(facets.categories.length > 0 || facets.tags.length > 0) && (
+        <div style={{ display: "flex", flexWrap: "wrap", gap: "6px", margin: "1rem 0" }}>
+          {facets.categories.map((f) => (
+            <button
+              key={`c-${f.slug}`}
+              onClick={() => setCategory(category === f.slug ? null : f.slug)}
+              style={chip(category === f.slug, "#1e40af")}
+            >
+              📁 {f.name || f.slug} ({f.count})
+            </button>
+          ))}
+          {facets.tags.map((f) => (
+            <button
+              key={`t-${f.slug}`}
+              onClick={() => setTag(tag === f.slug ? null : f.slug)}
+              style={chip(tag === f.slug, "#2f855a")}
+            >
+              #{f.slug} ({f.count})
+            </button>
+          ))}
+        </div>
+      )}
+
       <div className="stories">
         {articles.length > 0 ? (
           articles.map((a) => (
@@ -124,6 +242,7 @@ export default function ReaderPage({ session, onLogout }: Props) {
               </p>
               <p style={{ fontSize: "13px", color: "#718096", marginTop: "6px" }}>
                 ✍️ {a.author}
+                {(a.tags || []).map((t) => ` #${t}`).join("")}
               </p>
             </div>
           ))
@@ -143,12 +262,106 @@ export default function ReaderPage({ session, onLogout }: Props) {
             <p style={{ fontSize: "14px", color: "#4a5568", marginTop: "8px" }}>
               {selectedArticle.author}
             </p>
+            {/* 🌐 Makalenin dilleri (seçili olan sunulan dil) */}
+            {(selectedArticle.locales || []).length > 1 && (
+              <div style={{ display: "flex", gap: "6px", marginTop: "6px" }}>
+                {(selectedArticle.locales || []).map((l) => (
+                  <button
+                    key={l}
+                    onClick={() => switchLang(selectedArticle.id, l)}
+                    style={chip(selectedArticle.locale === l, "#6b46c1")}
+                  >
+                    🌐 {l}
+                  </button>
+                ))}
+              </div>
+            )}
             <hr style={{ margin: "12px 0" }} />
-            <p style={{ textAlign: "justify", lineHeight: "1.6" }}>
-              {selectedArticle.content_long ||
-                selectedArticle.content ||
-                "Bu makale için detaylı içerik bulunamadı."}
-            </p>
+            {/* 📑 İçindekiler (başlık bağlantıları HTML'deki id'lere gider) */}
+            {(selectedArticle.toc || []).length > 1 && (
+              <nav style={{ fontSize: "14px", marginBottom: "12px" }}>
+                <strong>İçindekiler</strong>
+                {(selectedArticle.toc || []).map((h) => (
+                  <div key={h.id} style={{ marginLeft: `${(h.level - 1) * 14}px` }}>
+                    <a href={`#${h.id}`}>{h.text}</a>
+                  </div>
+                ))}
+              </nav>
+            )}
+            {/* HTML backend'de allow-list ile temizlenmiştir; eski makalelerde düz metne düşülür */}
+            {selectedArticle.content_html ? (
+              <div
+                style={{ lineHeight: "1.6" }}
+                dangerouslySetInnerHTML={{ __html: selectedArticle.content_html }}
+              />
+            ) : (
+              <p style={{ textAlign: "justify", lineHeight: "1.6" }}>
+                {selectedArticle.content_long ||
+                  selectedArticle.content ||
+                  "Bu makale için detaylı içerik bulunamadı."}
+              </p>
+            )}
+            {attachments.length > 0 && (
+              <>
+                <h3>📎 Ekler</h3>
+                {attachments.map((a) => (
+                  <div key={a.id} style={{ marginBottom: "8px" }}>
+                    {a.content_type.startsWith("image/") && (
+                      <img src={fileUrl(a)} alt={a.filename} style={{ maxWidth: "100%", borderRadius: "6px" }} />
+                    )}
+                    <p style={{ fontSize: "13px", color: "#718096", margin: "2px 0" }}>
+                      <a href={fileUrl(a)} target="_blank" rel="noreferrer">{a.filename}</a>
+                      {` • ${Math.ceil(a.size / 1024)} KB • store: ${a.store}`}
+                    </p>
+                  </div>
+                ))}
+              </>
+            )}
+            <hr style={{ margin: "12px 0" }} />
+            <h3>💬 Yorumlar ({comments.length})</h3>
+            {(() => {
+              const depths: Record<string, number> = {};
+              return comments.map((c) => (
+                <div
+                  key={c.id}
+                  style={{
+                    marginLeft: `${depthOf(c, depths) * 20}px`,
+                    borderLeft: "2px solid #e2e8f0",
+                    padding: "4px 10px",
+                    marginBottom: "6px",
+                  }}
+                >
+                  <p style={{ fontSize: "13px", color: "#718096", margin: 0 }}>
+                    {c.author} • {new Date(c.created_at).toLocaleString()} • {c.origin}
+                    {!c.merged_at && " • ⏳ birleştirilmedi"}
+                  </p>
+                  <p style={{ margin: "4px 0" }}>{c.body}</p>
+                  <button
+                    onClick={() => setReplyTo(c)}
+                    style={{ fontSize: "12px", background: "none", border: "none", color: "#3b82f6", cursor: "pointer", padding: 0 }}
+                  >
+                    Yanıtla
+                  </button>
+                </div>
+              ));
+            })()}
+            <form onSubmit={sendComment} style={{ display: "flex", flexDirection: "column", gap: "6px", marginTop: "10px" }}>
+              {replyTo && (
+                <p className="hint" style={{ margin: 0 }}>
+                  {replyTo.author} kullanıcısına yanıt •{" "}
+                  <a href="#" onClick={(e) => { e.preventDefault(); setReplyTo(null); }}>iptal</a>
+                </p>
+              )}
+              <textarea
+                placeholder="Yorumun (bölge node'una yazılır)"
+                value={commentText}
+                onChange={(e) => setCommentText(e.target.value)}
+                required
+                rows={2}
+                style={{ padding: "8px", borderRadius: "6px", border: "1px solid #cbd5e0" }}
+              />
+              <button type="submit" style={{ alignSelf: "flex-start" }}>Gönder</button>
+            </form>
             <button onClick={() => setSelectedArticle(null)} style={closeBtn}>
               Kapat
             </button>
@@ -159,6 +372,17 @@ export default function ReaderPage({ session, onLogout }: Props) {
   );
 }
 
+// Süzgeç düğmesi (seçiliyse dolu)
+const chip = (active: boolean, color: string) => ({
+  border: `1px solid ${color}`,
+  background: active ? color : "white",
+  color: active ? "white" : color,
+  borderRadius: "999px",
+  padding: "4px 10px",
+  fontSize: "13px",
+  cursor: "pointer",
+});
+
 // Modal stilleri
 const modalOverlay = {
   position: "fixed",
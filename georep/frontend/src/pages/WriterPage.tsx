@@ -1,6 +1,6 @@
 import React, { useEffect, useState } from "react";
-import { apiGet, apiPost } from "../api";
-import { Article, ReplicationStatus, Session } from "../types";
+import { apiDelete, apiGet, apiPost, apiUpload } from "../api";
+import { Article, ArticleStatus, Attachment, Category, ReplicationStatus, Session, TrashedArticle } from "../types";
 
 type Props = {
   session: Session;
@@ -10,19 +10,59 @@ type Props = {
 
 export default function WriterPage({ session, onLogout, onArticleAdded }: Props) {
   const [title, setTitle] = useState("");
-  const [author, setAuthor] = useState(session.username || "");
   const [summary, setSummary] = useState("");
   const [contentLong, setContentLong] = useState("");
   const [loading, setLoading] = useState(false);
   const [articles, setArticles] = useState<Article[]>([]);
   const [repStatus, setRepStatus] = useState<ReplicationStatus[]>([]);
   const [expandedId, setExpandedId] = useState<number | null>(null);
+  const [trash, setTrash] = useState<TrashedArticle[]>([]);
+  const [status, setStatus] = useState<ArticleStatus>("published");
+  const [publishTime, setPublishTime] = useState("");
+  const [localPerRegion, setLocalPerRegion] = useState(true);
+  const [pending, setPending] = useState<Article[]>([]);
+  const [tagsText, setTagsText] = useState("");
+  const [categories, setCategories] = useState<Category[]>([]);
+  const [selectedCategories, setSelectedCategories] = useState<string[]>([]);
 
   const loadArticles = async () => {
     const data = await apiGet<Article[]>("/articles?region=eu");
     setArticles(data || []);
   };
 
+  const loadPending = async () => {
+    try {
+      const data = await apiGet<Article[]>("/articles/unpublished");
+      setPending(data || []);
+    } catch {
+      setPending([]);
+    }
+  };
+
+  const loadTrash = async () => {
+    try {
+      const data = await apiGet<TrashedArticle[]>("/articles/trash");
+      setTrash(data || []);
+    } catch {
+      setTrash([]);
+    }
+  };
+
+  const loadCategories = async () => {
+    try {
+      const data = await apiGet<Category[]>("/categories");
+      setCategories(data || []);
+    } catch {
+      setCategories([]);
+    }
+  };
+
+  const toggleCategory = (slug: string) => {
+    setSelectedCategories((prev) =>
+      prev.includes(slug) ? prev.filter((c) => c !== slug) : [...prev, slug]
+    );
+  };
+
   const loadRepStatus = async () => {
     const s = await apiGet<ReplicationStatus[]>("/replication-status");
     setRepStatus((s || []).filter((r) => r.replica.toLowerCase() !== "eu"));
@@ -30,6 +70,9 @@ export default function WriterPage({ session, onLogout, onArticleAdded }: Props)
 
   useEffect(() => {
     loadArticles();
+    loadPending();
+    loadTrash();
+    loadCategories();
     loadRepStatus();
     const interval = setInterval(loadRepStatus, 3000);
     return () => clearInterval(interval);
@@ -39,19 +82,39 @@ export default function WriterPage({ session, onLogout, onArticleAdded }: Props)
     e.preventDefault();
     setLoading(true);
     try {
+      // Zamanlanmış yayın: tek bir an ya da her bölgenin kendi yerel saati
+      const schedule =
+        status !== "scheduled"
+          ? {}
+          : localPerRegion
+          ? { publish_local_time: publishTime }
+          : { publish_at: new Date(publishTime).toISOString() };
       const created = await apiPost<Article>("/articles", {
         title,
         summary,
         content: summary,
         content_long: contentLong,
-        author,
+        status,
+        ...schedule,
+        // Etiketler virgülle ayrılır; backend slug'a çevirir
+        tags: tagsText.split(",").map((t) => t.trim()).filter(Boolean),
+        categories: selectedCategories,
       });
-      setArticles((prev) => [created, ...prev]);
+      if (created.status === "published") {
+        setArticles((prev) => [created, ...prev]);
+      } else {
+        await loadPending();
+      }
       setTitle("");
       setSummary("");
       setContentLong("");
-      setAuthor(session.username || "");
+      setStatus("published");
+      setPublishTime("");
+      setTagsText("");
+      setSelectedCategories([]);
       if (onArticleAdded) onArticleAdded();
+    } catch {
+      alert("Kaydetme başarısız oldu.");
     } finally {
       setLoading(false);
     }
@@ -60,18 +123,45 @@ export default function WriterPage({ session, onLogout, onArticleAdded }: Props)
   const handleDelete = async (id: number) => {
     if (!confirm("Bu haberi silmek istediğine emin misin?")) return;
     try {
-      await fetch(
-        `${
-          import.meta.env.VITE_API_BASE || "http://localhost:8080/api"
-        }/articles/${id}`,
-        { method: "DELETE" }
-      );
-      await loadArticles();
+      await apiDelete(`/articles/${id}`);
+      await Promise.all([loadArticles(), loadTrash()]);
     } catch {
       alert("Silme işlemi başarısız oldu.");
     }
   };
 
+  const handlePublishNow = async (id: number) => {
+    try {
+      await apiPost<Article>(`/articles/${id}/publish`, {});
+      await Promise.all([loadArticles(), loadPending()]);
+    } catch {
+      alert("Yayına alma başarısız oldu.");
+    }
+  };
+
+  const handleRestore = async (id: number) => {
+    try {
+      await apiPost<Article>(`/articles/${id}/restore`, {});
+      await Promise.all([loadArticles(), loadTrash()]);
+    } catch {
+      alert("Geri alma başarısız oldu (saklama süresi dolmuş olabilir).");
+    }
+  };
+
+  // Ek dosyası master'ın blob store'una yüklenir, bölgelere arka planda kopyalanır
+  const handleAttach = async (id: number, e: React.ChangeEvent<HTMLInputElement>) => {
+    const file = e.target.files?.[0];
+    e.target.value = "";
+    if (!file) return;
+    try {
+      const a = await apiUpload<Attachment>(`/articles/${id}/attachments`, file);
+      alert(`📎 ${a.filename} yüklendi (${Math.ceil(a.size / 1024)} KB).`);
+      await loadRepStatus();
+    } catch {
+      alert("Dosya yüklenemedi (boyut sınırını aşmış olabilir).");
+    }
+  };
+
   const toggleExpand = (id: number) => {
     setExpandedId((prev) => (prev === id ? null : id));
   };
@@ -148,6 +238,8 @@ export default function WriterPage({ session, onLogout, onArticleAdded }: Props)
                 }}
               >
                 {colorMap.icon} {s.replica}: {s.status}
+                {!!s.blob_pending &&
+                  ` • 📎 ${s.blob_pending} dosya bekliyor (${Math.round(s.blob_lag_seconds || 0)} sn)`}
               </div>
             );
           })}
@@ -178,22 +270,10 @@ export default function WriterPage({ session, onLogout, onArticleAdded }: Props)
                 border: "1px solid #cbd5e0",
               }}
             />
-            <input
-              placeholder="Yazar adı"
-              value={author}
-              onChange={(e) => setAuthor(e.target.value)}
-              required
-              style={{
-                padding: "10px",
-                borderRadius: "6px",
-                border: "1px solid #cbd5e0",
-              }}
-            />
             <textarea
-              placeholder="Kısa özet (önizleme için)"
+              placeholder="Kısa özet (boş bırakılırsa içerikten üretilir)"
               value={summary}
               onChange={(e) => setSummary(e.target.value)}
-              required
               rows={2}
               style={{
                 padding: "10px",
@@ -203,7 +283,7 @@ export default function WriterPage({ session, onLogout, onArticleAdded }: Props)
               }}
             />
             <textarea
-              placeholder="Uzun içerik (makalenin tamamı)"
+              placeholder="Uzun içerik (Markdown: # başlık, **kalın**, [bağlantı](https://…), - liste)"
               value={contentLong}
               onChange={(e) => setContentLong(e.target.value)}
               required
@@ -215,6 +295,60 @@ export default function WriterPage({ session, onLogout, onArticleAdded }: Props)
                 resize: "vertical",
               }}
             />
+            <input
+              placeholder="Etiketler (virgülle ayır: postgres, replikasyon)"
+              value={tagsText}
+              onChange={(e) => setTagsText(e.target.value)}
+              style={{
+                padding: "10px",
+                borderRadius: "6px",
+                border: "1px solid #cbd5e0",
+              }}
+            />
+            {categories.length > 0 && (
+              <div style={{ display: "flex", gap: "10px", flexWrap: "wrap" }}>
+                {categories.map((c) => (
+                  <label key={c.slug} className="hint" style={{ display: "flex", gap: "4px", alignItems: "center" }}>
+                    <input
+                      type="checkbox"
+                      checked={selectedCategories.includes(c.slug)}
+                      onChange={() => toggleCategory(c.slug)}
+                    />
+                    {c.name}
+                  </label>
+                ))}
+              </div>
+            )}
+            <div style={{ display: "flex", gap: "10px", alignItems: "center", flexWrap: "wrap" }}>
+              <select
+                value={status}
+                onChange={(e) => setStatus(e.target.value as ArticleStatus)}
+                style={{ padding: "8px", borderRadius: "6px", border: "1px solid #cbd5e0" }}
+              >
+                <option value="published">Hemen yayınla</option>
+                <option value="draft">Taslak</option>
+                <option value="scheduled">Zamanla</option>
+              </select>
+              {status === "scheduled" && (
+                <>
+                  <input
+                    type="datetime-local"
+                    value={publishTime}
+                    onChange={(e) => setPublishTime(e.target.value)}
+                    required
+                    style={{ padding: "8px", borderRadius: "6px", border: "1px solid #cbd5e0" }}
+                  />
+                  <label className="hint" style={{ display: "flex", gap: "4px", alignItems: "center" }}>
+                    <input
+                      type="checkbox"
+                      checked={localPerRegion}
+                      onChange={(e) => setLocalPerRegion(e.target.checked)}
+                    />
+                    Her bölgede yerel saatle
+                  </label>
+                </>
+              )}
+            </div>
             <button
               type="submit"
               disabled={loading}
@@ -228,7 +362,13 @@ export default function WriterPage({ session, onLogout, onArticleAdded }: Props)
                 cursor: "pointer",
               }}
             >
-              {loading ? "Yayınlanıyor..." : "Yayınla"}
+              {loading
+                ? "Kaydediliyor..."
+                : status === "draft"
+                ? "Taslak Olarak Kaydet"
+                : status === "scheduled"
+                ? "Zamanla"
+                : "Yayınla"}
             </button>
           </form>
         </div>
@@ -254,8 +394,14 @@ export default function WriterPage({ session, onLogout, onArticleAdded }: Props)
                 <strong>{a.author}</strong> — {new Date(a.created_at).toLocaleString()}
               </p>
               <p style={{ color: "#374151" }}>
-                {expandedId === a.id ? a.content_long : a.summary}
+                {expandedId !== a.id && a.summary}
               </p>
+              {expandedId === a.id &&
+                (a.content_html ? (
+                  <div style={{ color: "#374151" }} dangerouslySetInnerHTML={{ __html: a.content_html }} />
+                ) : (
+                  <p style={{ color: "#374151" }}>{a.content_long}</p>
+                ))}
               <div style={{ marginTop: "10px", display: "flex", gap: "10px" }}>
                 <button
                   onClick={() => toggleExpand(a.id)}
@@ -285,6 +431,19 @@ export default function WriterPage({ session, onLogout, onArticleAdded }: Props)
                 >
                   Sil
                 </button>
+                <label
+                  style={{
+                    backgroundColor: "#e2e8f0",
+                    color: "#2d3748",
+                    padding: "6px 12px",
+                    borderRadius: "6px",
+                    fontSize: "14px",
+                    cursor: "pointer",
+                  }}
+                >
+                  📎 Dosya ekle
+                  <input type="file" onChange={(e) => handleAttach(a.id, e)} style={{ display: "none" }} />
+                </label>
               </div>
             </div>
           ))
@@ -292,6 +451,109 @@ export default function WriterPage({ session, onLogout, onArticleAdded }: Props)
           <p className="hint">Henüz makale yok.</p>
         )}
       </div>
+
+      {pending.length > 0 && (
+        <>
+          <hr style={{ margin: "2rem 0 1rem 0" }} />
+          <h3>Taslaklar ve Zamanlananlar</h3>
+          <div className="stories">
+            {pending.map((a) => (
+              <div
+                key={a.id}
+                style={{
+                  backgroundColor: "#fffbeb",
+                  border: "1px solid #fde68a",
+                  borderRadius: "8px",
+                  padding: "12px 16px",
+                  marginBottom: "12px",
+                  display: "flex",
+                  justifyContent: "space-between",
+                  alignItems: "center",
+                  gap: "10px",
+                }}
+              >
+                <div>
+                  <h4 style={{ margin: "0 0 4px 0" }}>{a.title}</h4>
+                  <p className="hint" style={{ margin: 0 }}>
+                    {a.status === "draft"
+                      ? "Taslak"
+                      : Object.keys(a.region_publish_at || {}).length > 0
+                      ? "Zamanlandı: " +
+                        Object.entries(a.region_publish_at || {})
+                          .map(([r, t]) => `${r.toUpperCase()} ${new Date(t).toLocaleString()}`)
+                          .join(", ")
+                      : `Zamanlandı: ${a.publish_at ? new Date(a.publish_at).toLocaleString() : "-"}`}
+                  </p>
+                </div>
+                <button
+                  onClick={() => handlePublishNow(a.id)}
+                  style={{
+                    backgroundColor: "#3b82f6",
+                    color: "white",
+                    border: "none",
+                    padding: "6px 12px",
+                    borderRadius: "6px",
+                    fontSize: "14px",
+                    cursor: "pointer",
+                  }}
+                >
+                  Şimdi Yayınla
+                </button>
+              </div>
+            ))}
+          </div>
+        </>
+      )}
+
+      {trash.length > 0 && (
+        <>
+          <hr style={{ margin: "2rem 0 1rem 0" }} />
+          <h3>Çöp Kutusu</h3>
+          <p className="hint">
+            Silinen yazılar saklama süresi boyunca geri alınabilir, sonra tüm bölgelerden kalıcı olarak silinir.
+          </p>
+          <div className="stories">
+            {trash.map((a) => (
+              <div
+                key={a.id}
+                style={{
+                  backgroundColor: "#f9fafb",
+                  border: "1px dashed #cbd5e1",
+                  borderRadius: "8px",
+                  padding: "12px 16px",
+                  marginBottom: "12px",
+                  display: "flex",
+                  justifyContent: "space-between",
+                  alignItems: "center",
+                  gap: "10px",
+                }}
+              >
+                <div>
+                  <h4 style={{ margin: "0 0 4px 0", color: "#6b7280" }}>{a.title}</h4>
+                  <p className="hint" style={{ margin: 0 }}>
+                    Silindi: {new Date(a.deleted_at).toLocaleString()} — Kalıcı silinme:{" "}
+                    {new Date(a.purge_at).toLocaleString()}
+                  </p>
+                </div>
+                <button
+                  onClick={() => handleRestore(a.id)}
+                  style={{
+                    backgroundColor: "#10b981",
+                    color: "white",
+                    border: "none",
+                    padding: "6px 12px",
+                    borderRadius: "6px",
+                    fontSize: "14px",
+                    cursor: "pointer",
+                  }}
+                >
+                  Geri Al
+                </button>
+              </div>
+            ))}
+          </div>
+        </>
+      )}
     </section>
   );
 }
@@ -1,6 +1,6 @@
 export type Region = "eu" | "us" | "asia" | "sa" | "tr" | "africa";
 
-export type Role = "reader" | "writer";
+export type Role = "reader" | "writer" | "editor" | "admin";
 
 export type Session = {
   username: string;
@@ -16,12 +16,87 @@ export type Article = {
   author: string;
   region: string;
   created_at: string;
+  deleted_at?: string;
+  status?: ArticleStatus;
+  publish_at?: string;
+  region_publish_at?: Record<string, string>;
+  tags?: string[];
+  categories?: string[];
+  // Sunulan dil ve makalenin mevcut tüm dilleri (ilki yazıldığı dil)
+  locale?: string;
+  locales?: string[];
+  // content_long Markdown'dır; backend yazarken temizlenmiş HTML'e ve içindekilere çevirir
+  summary?: string;
+  content_long?: string;
+  content_html?: string;
+  toc?: Heading[];
+};
+
+export type Heading = {
+  level: number;
+  id: string;
+  text: string;
+};
+
+export type ArticleStatus = "draft" | "scheduled" | "published";
+
+// Çöpteki makale; purge_at geçince kalıcı olarak silinir
+export type TrashedArticle = Article & {
+  deleted_at: string;
+  purge_at: string;
+};
+
+export type Category = {
+  slug: string;
+  name: string;
+  description: string;
+};
+
+// Bölge replikasındaki etiket / kategori sayıları
+export type Facet = {
+  slug: string;
+  name?: string;
+  count: number;
+};
+
+export type ArticleFacets = {
+  tags: Facet[];
+  categories: Facet[];
+};
+
+// Bölge node'una yazılan yorum; merged_at boşsa henüz master'da birleştirilmedi
+export type Comment = {
+  id: string;
+  article_id: number;
+  parent_id?: string;
+  author: string;
+  body: string;
+  origin: string;
+  clock: number;
+  created_at: string;
+  merged_at?: string;
 };
 
 export type ReplicationStatus = {
   replica: string;
   status: string;
   last_at?: string;
+  // Ek dosyası içerikleri satırlardan ayrı kopyalanır
+  blob_pending?: number;
+  blob_lag_seconds?: number;
+};
+
+export type Attachment = {
+  id: number;
+  article_id: number;
+  filename: string;
+  content_type: string;
+  size: number;
+  sha256: string;
+  uploaded_by: number;
+  created_at: string;
+  url: string; // okuyucunun en yakın blob store'u
+  store: string;
 };
 
 